@@ -0,0 +1,376 @@
+// Package abi decodes contract event logs into named events with typed
+// arguments. ABIs come from JSON files, from the chain (GetContractABI) or,
+// for contracts without a known ABI, from a built-in table of common events.
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	eabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+)
+
+// ErrUnknownEvent means neither the contract ABI nor the built-in table knows the log's topic0
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Arg is one decoded event argument
+type Arg struct {
+	Name    string
+	Type    string
+	Indexed bool
+	// Value is *big.Int for integers, a base58 string for addresses, []byte or
+	// a byte array for bytes, or the Go value go-ethereum decodes the type into
+	Value any
+}
+
+// String formats the value the way it is published: integers in decimal,
+// addresses in base58 and bytes in hex
+func (a Arg) String() string {
+	switch v := a.Value.(type) {
+	case *big.Int:
+		return v.String()
+	case string:
+		return v
+	case []byte:
+		return hex.EncodeToString(v)
+	case [32]byte:
+		return hex.EncodeToString(v[:])
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Event is a decoded log
+type Event struct {
+	Contract string
+	Name     string
+	// Signature is the canonical signature, e.g. Transfer(address,address,uint256)
+	Signature string
+	Args      []Arg
+	// Builtin is set when the event was decoded with the built-in table instead of the contract's ABI
+	Builtin bool
+}
+
+// Arg returns the argument with the given name
+func (e *Event) Arg(name string) (Arg, bool) {
+	for _, a := range e.Args {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Arg{}, false
+}
+
+// Fields returns every argument formatted with Arg.String, keyed by name
+func (e *Event) Fields() map[string]string {
+	out := make(map[string]string, len(e.Args))
+	for _, a := range e.Args {
+		out[a.Name] = a.String()
+	}
+	return out
+}
+
+// Fetcher loads a contract's ABI from the chain, e.g. GrpcClient.GetContractABI
+type Fetcher func(contract string) (*core.SmartContract_ABI, error)
+
+// Retry intervals: missingRetry for contracts without a usable ABI (none on
+// chain, unparsable or without events), failedRetry after a node error
+const (
+	missingRetry = time.Hour
+	failedRetry  = time.Minute
+)
+
+// Registry maps contracts to their ABIs. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	contracts map[string]*eabi.ABI
+	// missing remembers contracts without a usable on-chain ABI until the time they may be fetched again
+	missing map[string]time.Time
+	// fetching holds a channel per contract being fetched, closed when the fetch is done
+	fetching map[string]chan struct{}
+	fetch    Fetcher
+}
+
+// NewRegistry returns a registry that only knows the built-in events.
+// fetch may be nil to never query the chain.
+func NewRegistry(fetch Fetcher) *Registry {
+	return &Registry{
+		contracts: make(map[string]*eabi.ABI),
+		missing:   make(map[string]time.Time),
+		fetching:  make(map[string]chan struct{}),
+		fetch:     fetch,
+	}
+}
+
+// Register sets the ABI of a contract, replacing any earlier one
+func (r *Registry) Register(contract string, a *eabi.ABI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contracts[contract] = a
+	delete(r.missing, contract)
+}
+
+// LoadFile registers the ABI in a JSON file for contract. Both the Solidity
+// compiler format (an array of entries) and the TronGrid/TronScan format
+// ({"entrys": [...]}) are accepted.
+func (r *Registry) LoadFile(contract, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read abi %s: %w", path, err)
+	}
+	a, err := ParseJSON(data)
+	if err != nil {
+		return fmt.Errorf("parse abi %s: %w", path, err)
+	}
+	r.Register(contract, a)
+	return nil
+}
+
+// LoadDir registers every <contract>.json file in dir and returns how many were loaded
+func (r *Registry) LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		contract := strings.TrimSuffix(filepath.Base(f), ".json")
+		if _, err := address.Base58ToAddress(contract); err != nil {
+			return 0, fmt.Errorf("abi file %s: name must be the contract address: %w", f, err)
+		}
+		if err := r.LoadFile(contract, f); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+// Decode decodes a log emitted by contract. The contract's ABI is tried first
+// (fetching it from the chain once if a Fetcher is set), then the built-in table.
+func (r *Registry) Decode(contract string, topics [][]byte, data []byte) (*Event, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("anonymous log: %w", ErrUnknownEvent)
+	}
+	if a := r.lookup(contract); a != nil {
+		if ev, err := a.EventByID(common.BytesToHash(topics[0])); err == nil && indexedCount(ev.Inputs) == len(topics)-1 {
+			out, err := decode(ev, topics, data)
+			if err != nil {
+				return nil, err
+			}
+			out.Contract = contract
+			return out, nil
+		}
+	}
+	for _, ev := range builtin[common.BytesToHash(topics[0])] {
+		if indexedCount(ev.Inputs) != len(topics)-1 {
+			continue
+		}
+		out, err := decode(ev, topics, data)
+		if err != nil {
+			continue
+		}
+		out.Contract = contract
+		out.Builtin = true
+		return out, nil
+	}
+	return nil, fmt.Errorf("topic %x: %w", topics[0], ErrUnknownEvent)
+}
+
+// Prefetch fetches the ABIs of contracts not known yet, so that decoding their
+// logs later does not wait on the node. The monitor calls it from its
+// concurrent fetch stage.
+func (r *Registry) Prefetch(contracts ...string) {
+	for _, c := range contracts {
+		r.lookup(c)
+	}
+}
+
+// lookup returns the contract's ABI, fetching it when it is not known yet.
+// Concurrent lookups of the same contract share one fetch.
+func (r *Registry) lookup(contract string) *eabi.ABI {
+	r.mu.Lock()
+	for {
+		a, ok := r.contracts[contract]
+		retryAt, missing := r.missing[contract]
+		if ok || r.fetch == nil || (missing && time.Now().Before(retryAt)) {
+			r.mu.Unlock()
+			return a
+		}
+		wait, busy := r.fetching[contract]
+		if !busy {
+			break
+		}
+		r.mu.Unlock()
+		<-wait
+		r.mu.Lock()
+	}
+	done := make(chan struct{})
+	r.fetching[contract] = done
+	r.mu.Unlock()
+
+	var a *eabi.ABI
+	entries, fetchErr := r.fetch(contract)
+	var parseErr error
+	if fetchErr == nil {
+		a, parseErr = fromProto(entries)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fetching, contract)
+	close(done)
+	switch {
+	case fetchErr != nil:
+		// 节点错误稍后重试
+		r.missing[contract] = time.Now().Add(failedRetry)
+		return nil
+	case parseErr != nil || len(a.Events) == 0:
+		// 无法解析或不含事件的 ABI 重查也一样，很久之后才再查
+		r.missing[contract] = time.Now().Add(missingRetry)
+		return nil
+	}
+	r.contracts[contract] = a
+	return a
+}
+
+// decode unpacks the indexed arguments from topics and the rest from data.
+// Unnamed inputs are named by position (arg0, arg1, ...) so they do not
+// overwrite each other.
+func decode(ev *eabi.Event, topics [][]byte, data []byte) (*Event, error) {
+	inputs := make(eabi.Arguments, len(ev.Inputs))
+	for i, in := range ev.Inputs {
+		if in.Name == "" {
+			in.Name = fmt.Sprintf("arg%d", i)
+		}
+		inputs[i] = in
+	}
+	values := make(map[string]any, len(inputs))
+	if err := inputs.NonIndexed().UnpackIntoMap(values, data); err != nil {
+		return nil, fmt.Errorf("decode %s data: %w", ev.Sig, err)
+	}
+	var indexed eabi.Arguments
+	for _, in := range inputs {
+		if in.Indexed {
+			indexed = append(indexed, in)
+		}
+	}
+	hashes := make([]common.Hash, len(topics)-1)
+	for i, t := range topics[1:] {
+		hashes[i] = common.BytesToHash(t)
+	}
+	if err := eabi.ParseTopicsIntoMap(values, indexed, hashes); err != nil {
+		return nil, fmt.Errorf("decode %s topics: %w", ev.Sig, err)
+	}
+
+	out := &Event{Name: ev.RawName, Signature: ev.Sig}
+	for _, in := range inputs {
+		v := values[in.Name]
+		if addr, ok := v.(common.Address); ok {
+			v = base58(addr.Bytes())
+		}
+		out.Args = append(out.Args, Arg{Name: in.Name, Type: in.Type.String(), Indexed: in.Indexed, Value: v})
+	}
+	return out, nil
+}
+
+func indexedCount(args eabi.Arguments) int {
+	n := 0
+	for _, a := range args {
+		if a.Indexed {
+			n++
+		}
+	}
+	return n
+}
+
+// base58 converts a 20-byte EVM address to a TRON address
+func base58(b []byte) string {
+	return address.Address(append([]byte{address.TronBytePrefix}, b...)).String()
+}
+
+// jsonEntry is one ABI entry in the Solidity JSON format
+type jsonEntry struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name,omitempty"`
+	Anonymous bool        `json:"anonymous,omitempty"`
+	Inputs    []jsonParam `json:"inputs,omitempty"`
+}
+
+type jsonParam struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Indexed    bool        `json:"indexed,omitempty"`
+	Components []jsonParam `json:"components,omitempty"`
+}
+
+// ParseJSON parses an ABI in the Solidity or TronGrid JSON format.
+// Only events and errors are kept; TRON's trcToken type is read as uint256.
+func ParseJSON(data []byte) (*eabi.ABI, error) {
+	var entries []jsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Entrys []jsonEntry `json:"entrys"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil || wrapped.Entrys == nil {
+			return nil, err
+		}
+		entries = wrapped.Entrys
+	}
+	return build(entries)
+}
+
+// fromProto converts an ABI returned by the node
+func fromProto(a *core.SmartContract_ABI) (*eabi.ABI, error) {
+	var entries []jsonEntry
+	for _, e := range a.GetEntrys() {
+		je := jsonEntry{Type: e.GetType().String(), Name: e.GetName(), Anonymous: e.GetAnonymous()}
+		for _, p := range e.GetInputs() {
+			je.Inputs = append(je.Inputs, jsonParam{Name: p.GetName(), Type: p.GetType(), Indexed: p.GetIndexed()})
+		}
+		entries = append(entries, je)
+	}
+	return build(entries)
+}
+
+func build(entries []jsonEntry) (*eabi.ABI, error) {
+	var keep []jsonEntry
+	for _, e := range entries {
+		e.Type = strings.ToLower(e.Type)
+		if e.Type != "event" && e.Type != "error" {
+			continue
+		}
+		for i := range e.Inputs {
+			e.Inputs[i] = normalize(e.Inputs[i])
+		}
+		keep = append(keep, e)
+	}
+	data, err := json.Marshal(keep)
+	if err != nil {
+		return nil, err
+	}
+	a, err := eabi.JSON(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func normalize(p jsonParam) jsonParam {
+	if strings.HasPrefix(p.Type, "trcToken") {
+		p.Type = "uint256" + strings.TrimPrefix(p.Type, "trcToken")
+	}
+	for i := range p.Components {
+		p.Components[i] = normalize(p.Components[i])
+	}
+	return p
+}
@@ -0,0 +1,116 @@
+package abi
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+)
+
+const testContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+// pairABI has one event with two unnamed inputs
+func pairABI(typ string) *core.SmartContract_ABI {
+	return &core.SmartContract_ABI{Entrys: []*core.SmartContract_ABI_Entry{{
+		Type: core.SmartContract_ABI_Entry_Event,
+		Name: "Pair",
+		Inputs: []*core.SmartContract_ABI_Entry_Param{
+			{Type: typ, Indexed: true},
+			{Type: typ},
+			{Type: typ},
+		},
+	}}}
+}
+
+func word(v int64) []byte { return common.BigToHash(big.NewInt(v)).Bytes() }
+
+func TestDecodeUnnamedInputs(t *testing.T) {
+	r := NewRegistry(func(string) (*core.SmartContract_ABI, error) { return pairABI("uint256"), nil })
+	topics := [][]byte{crypto.Keccak256([]byte("Pair(uint256,uint256,uint256)")), word(1)}
+	data := append(word(2), word(3)...)
+
+	ev, err := r.Decode(testContract, topics, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Builtin || ev.Name != "Pair" {
+		t.Errorf("event = %+v", ev)
+	}
+	want := map[string]string{"arg0": "1", "arg1": "2", "arg2": "3"}
+	got := ev.Fields()
+	if len(got) != len(want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestLookupRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		fetch Fetcher
+		retry time.Duration
+	}{
+		{"node error", func(string) (*core.SmartContract_ABI, error) { return nil, errors.New("unavailable") }, failedRetry},
+		{"unparsable", func(string) (*core.SmartContract_ABI, error) { return pairABI("notatype"), nil }, missingRetry},
+		{"no events", func(string) (*core.SmartContract_ABI, error) { return &core.SmartContract_ABI{}, nil }, missingRetry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			r := NewRegistry(func(c string) (*core.SmartContract_ABI, error) {
+				calls++
+				return tt.fetch(c)
+			})
+			start := time.Now()
+			if a := r.lookup(testContract); a != nil {
+				t.Fatalf("lookup = %v", a)
+			}
+			retryAt := r.missing[testContract]
+			if d := retryAt.Sub(start); d < tt.retry || d > tt.retry+time.Second {
+				t.Errorf("retry after %v, want %v", d, tt.retry)
+			}
+			r.lookup(testContract)
+			if calls != 1 {
+				t.Errorf("fetched %d times before the retry time", calls)
+			}
+		})
+	}
+}
+
+func TestPrefetchSharesFetch(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	r := NewRegistry(func(string) (*core.SmartContract_ABI, error) {
+		calls.Add(1)
+		<-release
+		return pairABI("uint256"), nil
+	})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Prefetch(testContract)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	if r.lookup(testContract) == nil {
+		t.Error("prefetched ABI not registered")
+	}
+}
@@ -0,0 +1,71 @@
+package abi
+
+import (
+	"encoding/json"
+
+	eabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// builtinJSON lists common events used when a contract's ABI is unknown.
+// TRC20 and TRC721 Transfer/Approval share topic0 and are told apart by the
+// number of indexed arguments (topics).
+const builtinJSON = `[
+	{"type":"event","name":"Transfer","inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Transfer","inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"Approval","inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"spender","type":"address","indexed":true},
+		{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Approval","inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"approved","type":"address","indexed":true},
+		{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"ApprovalForAll","inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"operator","type":"address","indexed":true},
+		{"name":"approved","type":"bool"}]},
+	{"type":"event","name":"Swap","inputs":[
+		{"name":"sender","type":"address","indexed":true},
+		{"name":"amount0In","type":"uint256"},
+		{"name":"amount1In","type":"uint256"},
+		{"name":"amount0Out","type":"uint256"},
+		{"name":"amount1Out","type":"uint256"},
+		{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"Sync","inputs":[
+		{"name":"reserve0","type":"uint112"},
+		{"name":"reserve1","type":"uint112"}]},
+	{"type":"event","name":"Deposit","inputs":[
+		{"name":"dst","type":"address","indexed":true},
+		{"name":"wad","type":"uint256"}]},
+	{"type":"event","name":"Withdrawal","inputs":[
+		{"name":"src","type":"address","indexed":true},
+		{"name":"wad","type":"uint256"}]}
+]`
+
+// builtin maps topic0 to the built-in events with that signature
+var builtin = loadBuiltin()
+
+func loadBuiltin() map[common.Hash][]*eabi.Event {
+	var entries []jsonEntry
+	if err := json.Unmarshal([]byte(builtinJSON), &entries); err != nil {
+		panic("abi: invalid built-in table: " + err.Error())
+	}
+	out := make(map[common.Hash][]*eabi.Event)
+	// abi.JSON keys events by name, so each entry is parsed on its own to keep overloads
+	for _, e := range entries {
+		a, err := build([]jsonEntry{e})
+		if err != nil {
+			panic("abi: invalid built-in event " + e.Name + ": " + err.Error())
+		}
+		ev := a.Events[e.Name]
+		out[ev.ID] = append(out[ev.ID], &ev)
+	}
+	return out
+}
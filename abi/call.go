@@ -0,0 +1,146 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	eabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Call is decoded TriggerSmartContract call data
+type Call struct {
+	Name string
+	// Signature is the canonical signature, e.g. transfer(address,uint256)
+	Signature string
+	Args      []Arg
+}
+
+// Arg returns the argument with the given name
+func (c *Call) Arg(name string) (Arg, bool) {
+	for _, a := range c.Args {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Arg{}, false
+}
+
+// builtinMethods are the token methods decoded without a contract ABI
+const builtinMethods = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+	{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+	{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}]}
+]`
+
+var methods = loadMethods()
+
+func loadMethods() map[[4]byte]eabi.Method {
+	a, err := eabi.JSON(strings.NewReader(builtinMethods))
+	if err != nil {
+		panic("abi: invalid built-in methods: " + err.Error())
+	}
+	out := make(map[[4]byte]eabi.Method, len(a.Methods))
+	for _, m := range a.Methods {
+		out[[4]byte(m.ID)] = m
+	}
+	return out
+}
+
+// DecodeCall decodes call data of the built-in token methods (transfer, transferFrom, approve)
+func DecodeCall(data []byte) (*Call, error) {
+	if len(data) < 4 {
+		return nil, errors.New("call data shorter than a selector")
+	}
+	m, ok := methods[[4]byte(data[:4])]
+	if !ok {
+		return nil, fmt.Errorf("selector %x: %w", data[:4], ErrUnknownEvent)
+	}
+	values, err := m.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", m.Sig, err)
+	}
+	c := &Call{Name: m.RawName, Signature: m.Sig}
+	for i, in := range m.Inputs {
+		v := values[i]
+		if addr, ok := v.(common.Address); ok {
+			v = base58(addr.Bytes())
+		}
+		c.Args = append(c.Args, Arg{Name: in.Name, Type: in.Type.String(), Value: v})
+	}
+	return c, nil
+}
+
+// Solidity's built-in revert payloads
+var (
+	selectorError = [4]byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	selectorPanic = [4]byte{0x4e, 0x48, 0x7b, 0x71} // Panic(uint256)
+)
+
+// panicReasons describes the Solidity panic codes
+var panicReasons = map[uint64]string{
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to uninitialized function",
+}
+
+// RevertReason decodes the revert payload returned by a failed contract call:
+// require/revert messages (Error(string)), panics (Panic(uint256)) and custom
+// errors declared in the contract's ABI. It returns "" when nothing is decodable.
+func (r *Registry) RevertReason(contract string, data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	args := data[4:]
+	switch [4]byte(data[:4]) {
+	case selectorError:
+		t, _ := eabi.NewType("string", "", nil)
+		v, err := eabi.Arguments{{Type: t}}.Unpack(args)
+		if err != nil {
+			return ""
+		}
+		return v[0].(string)
+	case selectorPanic:
+		t, _ := eabi.NewType("uint256", "", nil)
+		v, err := eabi.Arguments{{Type: t}}.Unpack(args)
+		if err != nil {
+			return ""
+		}
+		code := v[0].(*big.Int)
+		if reason, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+			return fmt.Sprintf("panic 0x%x: %s", code, reason)
+		}
+		return fmt.Sprintf("panic 0x%x", code)
+	}
+
+	a := r.lookup(contract)
+	if a == nil {
+		return ""
+	}
+	for _, e := range a.Errors {
+		if [4]byte(e.ID[:4]) != [4]byte(data[:4]) {
+			continue
+		}
+		v, err := e.Inputs.Unpack(args)
+		if err != nil {
+			return e.Sig
+		}
+		parts := make([]string, len(v))
+		for i, x := range v {
+			if addr, ok := x.(common.Address); ok {
+				x = base58(addr.Bytes())
+			}
+			parts[i] = Arg{Value: x}.String()
+		}
+		return fmt.Sprintf("%s(%s)", e.Name, strings.Join(parts, ", "))
+	}
+	return ""
+}
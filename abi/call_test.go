@@ -0,0 +1,124 @@
+package abi
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	eabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// tokenErrors declares the custom errors of a TRC20 token following OpenZeppelin 5
+const tokenErrors = `[
+	{"type":"error","name":"ERC20InsufficientBalance","inputs":[{"name":"sender","type":"address"},{"name":"balance","type":"uint256"},{"name":"needed","type":"uint256"}]},
+	{"type":"error","name":"EnforcedPause","inputs":[]}
+]`
+
+func TestRevertReason(t *testing.T) {
+	a, err := eabi.JSON(strings.NewReader(tokenErrors))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(func(string) (*core.SmartContract_ABI, error) { return &core.SmartContract_ABI{}, nil })
+	r.Register(testContract, &a)
+
+	sender := "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"
+	insufficient := a.Errors["ERC20InsufficientBalance"]
+	packed, err := insufficient.Inputs.Pack(addressOf(t, sender), big.NewInt(5), big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insufficientData := append(insufficient.ID[:4:4], packed...)
+
+	// require(balance >= value, "ERC20: transfer amount exceeds balance")
+	exceeds := unhex(t, `08c379a0
+		0000000000000000000000000000000000000000000000000000000000000020
+		0000000000000000000000000000000000000000000000000000000000000026
+		45524332303a207472616e7366657220616d6f756e7420657863656564732062
+		616c616e63650000000000000000000000000000000000000000000000000000`)
+
+	tests := []struct {
+		name     string
+		contract string
+		data     []byte
+		want     string
+	}{
+		{"error string", testContract, exceeds, "ERC20: transfer amount exceeds balance"},
+		{"empty error string", testContract, unhex(t, "08c379a0"+strings.Repeat("0", 62)+"20"+strings.Repeat("0", 64)), ""},
+		{"panic overflow", testContract, unhex(t, "4e487b71"+strings.Repeat("0", 62)+"11"), "panic 0x11: arithmetic overflow or underflow"},
+		{"panic division", testContract, unhex(t, "4e487b71"+strings.Repeat("0", 62)+"12"), "panic 0x12: division or modulo by zero"},
+		{"unknown panic code", testContract, unhex(t, "4e487b71"+strings.Repeat("0", 62)+"99"), "panic 0x99"},
+		// 超出 uint64 的代码不能按低 64 位查表
+		{"huge panic code", testContract, unhex(t, "4e487b71"+"01"+strings.Repeat("0", 60)+"11"), "panic 0x1" + strings.Repeat("0", 60) + "11"},
+		{"custom error", testContract, insufficientData, "ERC20InsufficientBalance(" + sender + ", 5, 7)"},
+		{"custom error without arguments", testContract, a.Errors["EnforcedPause"].ID.Bytes()[:4], "EnforcedPause()"},
+		{"custom error of another contract", sender, insufficientData, ""},
+		// 截断或损坏的返回数据
+		{"nil", testContract, nil, ""},
+		{"shorter than a selector", testContract, []byte{0x08, 0xc3, 0x79}, ""},
+		{"error selector only", testContract, exceeds[:4], ""},
+		{"truncated error string", testContract, exceeds[:4+64+10], ""},
+		{"error string offset out of range", testContract, unhex(t, "08c379a0"+strings.Repeat("f", 64)), ""},
+		{"error string length out of range", testContract, unhex(t, "08c379a0"+strings.Repeat("0", 62)+"20"+strings.Repeat("f", 64)), ""},
+		{"truncated panic", testContract, unhex(t, "4e487b71"+strings.Repeat("0", 30)+"11"), ""},
+		{"truncated custom error", testContract, insufficientData[:4+40], "ERC20InsufficientBalance(address,uint256,uint256)"},
+		{"unknown selector", testContract, unhex(t, "deadbeef"+strings.Repeat("0", 64)), ""},
+	}
+	for _, tt := range tests {
+		if got := r.RevertReason(tt.contract, tt.data); got != tt.want {
+			t.Errorf("%s: RevertReason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func addressOf(t *testing.T, a string) common.Address {
+	t.Helper()
+	b, err := address.Base58ToAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return common.BytesToAddress(b)
+}
+
+func TestDecodeCall(t *testing.T) {
+	// transfer(TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF, 1000000)
+	data := unhex(t, `a9059cbb
+		00000000000000000000000088403ac26730e33164eeac403291b48f300b782f
+		00000000000000000000000000000000000000000000000000000000000f4240`)
+	c, err := DecodeCall(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "transfer" || c.Signature != "transfer(address,uint256)" {
+		t.Errorf("call = %+v", c)
+	}
+	if to, ok := c.Arg("to"); !ok || to.String() != "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF" {
+		t.Errorf("to = %v", to)
+	}
+	if v, ok := c.Arg("value"); !ok || v.String() != "1000000" {
+		t.Errorf("value = %v", v)
+	}
+
+	for name, data := range map[string][]byte{
+		"short":            data[:3],
+		"unknown selector": unhex(t, "deadbeef"),
+		"truncated":        data[:4+40],
+	} {
+		if c, err := DecodeCall(data); err == nil {
+			t.Errorf("%s: DecodeCall = %+v, want error", name, c)
+		}
+	}
+}
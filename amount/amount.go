@@ -0,0 +1,265 @@
+package amount
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// TRXDecimals is the precision of TRX (1 TRX = 1e6 sun)
+const TRXDecimals = 6
+
+// Amount is an exact token amount: an integer number of base units plus the
+// asset's decimals. It never goes through floating point, so 18-decimal
+// tokens keep full precision. The zero value is 0 with unset decimals.
+type Amount struct {
+	raw      *big.Int
+	decimals int32
+	// known is set once the decimals are: every constructor sets it, only the
+	// zero value lacks it. Unmarshaling into an amount without known decimals
+	// takes them from the fraction digits; 0 decimals is a real precision.
+	known bool
+}
+
+// New returns an amount of raw base units (e.g. sun) with the given decimals.
+// It panics on negative decimals.
+func New(raw *big.Int, decimals int32) Amount {
+	if raw == nil {
+		raw = new(big.Int)
+	}
+	return Amount{raw: new(big.Int).Set(raw), decimals: checkDecimals(decimals), known: true}
+}
+
+// FromInt64 returns an amount of raw base units with the given decimals.
+// It panics on negative decimals.
+func FromInt64(raw int64, decimals int32) Amount {
+	return Amount{raw: big.NewInt(raw), decimals: checkDecimals(decimals), known: true}
+}
+
+func checkDecimals(d int32) int32 {
+	if d < 0 {
+		panic(fmt.Sprintf("amount: negative decimals %d", d))
+	}
+	return d
+}
+
+// Parse parses a human readable decimal such as "12.5" or "-0.000001" into
+// an amount with the given decimals. More fraction digits than decimals is an error.
+func Parse(s string, decimals int32) (Amount, error) {
+	if decimals < 0 {
+		return Amount{}, fmt.Errorf("amount: negative decimals %d", decimals)
+	}
+	str := strings.TrimSpace(s)
+	neg := strings.HasPrefix(str, "-")
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "-"), "+")
+
+	intPart, fracPart, _ := strings.Cut(str, ".")
+	if intPart == "" && fracPart == "" {
+		return Amount{}, fmt.Errorf("amount: invalid number %q", s)
+	}
+	if int32(len(fracPart)) > decimals {
+		// 多余的小数位只允许是 0，否则会丢精度
+		if strings.Trim(fracPart[decimals:], "0") != "" {
+			return Amount{}, fmt.Errorf("amount: %q has more than %d decimals", s, decimals)
+		}
+		fracPart = fracPart[:decimals]
+	}
+	digits := intPart + fracPart + strings.Repeat("0", int(decimals)-len(fracPart))
+	if digits == "" {
+		digits = "0"
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Amount{}, fmt.Errorf("amount: invalid number %q", s)
+		}
+	}
+	raw, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		raw.Neg(raw)
+	}
+	return Amount{raw: raw, decimals: decimals, known: true}, nil
+}
+
+// MustParse is like Parse but panics on error; for constants only
+func MustParse(s string, decimals int32) Amount {
+	a, err := Parse(s, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a Amount) int() *big.Int {
+	if a.raw == nil {
+		return new(big.Int)
+	}
+	return a.raw
+}
+
+// Raw returns a copy of the amount in base units
+func (a Amount) Raw() *big.Int {
+	return new(big.Int).Set(a.int())
+}
+
+// Decimals returns the number of decimals the amount is bound to
+func (a Amount) Decimals() int32 {
+	return a.decimals
+}
+
+// String formats the amount exactly with all of its decimals, e.g. "12.500000"
+func (a Amount) String() string {
+	raw := a.int()
+	s := new(big.Int).Abs(raw).String()
+	if a.decimals > 0 {
+		if pad := int(a.decimals) + 1 - len(s); pad > 0 {
+			s = strings.Repeat("0", pad) + s
+		}
+		cut := len(s) - int(a.decimals)
+		s = s[:cut] + "." + s[cut:]
+	}
+	if raw.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Trimmed formats the amount without trailing fraction zeros, e.g. "12.5"
+func (a Amount) Trimmed() string {
+	s := a.String()
+	if a.decimals > 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Rescale converts the amount to other decimals. Reducing decimals fails
+// when it would drop non-zero digits; negative decimals are an error.
+func (a Amount) Rescale(decimals int32) (Amount, error) {
+	switch {
+	case decimals < 0:
+		return Amount{}, fmt.Errorf("amount: negative decimals %d", decimals)
+	case decimals == a.decimals:
+		return New(a.int(), decimals), nil
+	case decimals > a.decimals:
+		return Amount{raw: new(big.Int).Mul(a.int(), pow10(decimals-a.decimals)), decimals: decimals, known: true}, nil
+	default:
+		q, r := new(big.Int).QuoRem(a.int(), pow10(a.decimals-decimals), new(big.Int))
+		if r.Sign() != 0 {
+			return Amount{}, fmt.Errorf("amount: %s cannot be represented with %d decimals", a, decimals)
+		}
+		return Amount{raw: q, decimals: decimals, known: true}, nil
+	}
+}
+
+// align returns both raw values scaled to the larger of the two decimals
+func align(a, b Amount) (*big.Int, *big.Int, int32) {
+	d := a.decimals
+	if b.decimals > d {
+		d = b.decimals
+	}
+	x, _ := a.Rescale(d)
+	y, _ := b.Rescale(d)
+	return x.raw, y.raw, d
+}
+
+// Add returns a+b; amounts with different decimals are aligned exactly
+func (a Amount) Add(b Amount) Amount {
+	x, y, d := align(a, b)
+	return Amount{raw: x.Add(x, y), decimals: d, known: true}
+}
+
+// Sub returns a-b; amounts with different decimals are aligned exactly
+func (a Amount) Sub(b Amount) Amount {
+	x, y, d := align(a, b)
+	return Amount{raw: x.Sub(x, y), decimals: d, known: true}
+}
+
+// MulInt returns a*n
+func (a Amount) MulInt(n int64) Amount {
+	return Amount{raw: new(big.Int).Mul(a.int(), big.NewInt(n)), decimals: a.decimals, known: true}
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return Amount{raw: new(big.Int).Neg(a.int()), decimals: a.decimals, known: true}
+}
+
+// Cmp compares a and b exactly: -1 if a < b, 0 if equal, +1 if a > b
+func (a Amount) Cmp(b Amount) int {
+	x, y, _ := align(a, b)
+	return x.Cmp(y)
+}
+
+// Sign returns -1, 0 or +1
+func (a Amount) Sign() int {
+	return a.int().Sign()
+}
+
+// IsZero reports whether the amount is 0
+func (a Amount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// MarshalJSON encodes the amount as a decimal string with all of its decimals,
+// which keeps both the value and the precision, e.g. "12.500000".
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts a decimal string or number. If the receiver already
+// has decimals (any constructor, including FromInt64(0, 0) for a 0-decimal
+// asset) they are used; a zero Amount takes them from the fraction digits.
+// null leaves the amount unchanged.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	s := strings.Trim(string(data), `"`)
+	return a.setString(s)
+}
+
+// Value implements driver.Valuer; amounts are stored as exact decimal strings (NUMERIC/DECIMAL columns)
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan implements sql.Scanner for string, []byte and int64 columns. Strings are
+// decimals like "12.5" and follow the UnmarshalJSON rules for decimals; integer
+// columns hold base units (e.g. sun) and take the receiver's decimals.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return a.setString(v)
+	case []byte:
+		return a.setString(string(v))
+	case int64:
+		*a = FromInt64(v, a.decimals)
+		return nil
+	case nil:
+		*a = Amount{decimals: a.decimals, known: a.known}
+		return nil
+	default:
+		return fmt.Errorf("amount: cannot scan %T", src)
+	}
+}
+
+func (a *Amount) setString(s string) error {
+	decimals := a.decimals
+	if !a.known {
+		if _, frac, ok := strings.Cut(s, "."); ok {
+			decimals = int32(len(frac))
+		}
+	}
+	v, err := Parse(s, decimals)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,275 @@
+package amount
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		decimals int32
+		raw      string
+		wantErr  bool
+	}{
+		{"12.5", 6, "12500000", false},
+		{"0.000001", 6, "1", false},
+		{"-0.000001", 6, "-1", false},
+		{"+3", 2, "300", false},
+		{".5", 1, "5", false},
+		{"7.", 0, "7", false},
+		{" 1.10 ", 1, "11", false},
+		{"1.2300", 2, "123", false},
+		{"123456789012345678.123456789012345678", 18, "123456789012345678123456789012345678", false},
+		{"1.0000001", 6, "", true},
+		{"", 6, "", true},
+		{".", 6, "", true},
+		{"1e6", 0, "", true},
+		{"1,5", 1, "", true},
+		{"--1", 0, "", true},
+		{"1", -1, "", true},
+	}
+	for _, tt := range tests {
+		a, err := Parse(tt.in, tt.decimals)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q, %d) = %s, want error", tt.in, tt.decimals, a)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", tt.in, tt.decimals, err)
+			continue
+		}
+		if a.Raw().String() != tt.raw || a.Decimals() != tt.decimals {
+			t.Errorf("Parse(%q, %d) = %s/%d, want %s/%d", tt.in, tt.decimals, a.Raw(), a.Decimals(), tt.raw, tt.decimals)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		raw      int64
+		decimals int32
+		want     string
+		trimmed  string
+	}{
+		{0, 0, "0", "0"},
+		{0, 6, "0.000000", "0"},
+		{1, 6, "0.000001", "0.000001"},
+		{-1, 6, "-0.000001", "-0.000001"},
+		{12500000, 6, "12.500000", "12.5"},
+		{100, 2, "1.00", "1"},
+		{42, 0, "42", "42"},
+	}
+	for _, tt := range tests {
+		a := FromInt64(tt.raw, tt.decimals)
+		if got := a.String(); got != tt.want {
+			t.Errorf("FromInt64(%d, %d).String() = %q, want %q", tt.raw, tt.decimals, got, tt.want)
+		}
+		if got := a.Trimmed(); got != tt.trimmed {
+			t.Errorf("FromInt64(%d, %d).Trimmed() = %q, want %q", tt.raw, tt.decimals, got, tt.trimmed)
+		}
+	}
+	if got := (Amount{}).String(); got != "0" {
+		t.Errorf("zero value String() = %q", got)
+	}
+
+	// 18 位精度不经过浮点
+	raw, _ := new(big.Int).SetString("1000000000000000001", 10)
+	if got := New(raw, 18).String(); got != "1.000000000000000001" {
+		t.Errorf("18 decimals = %q", got)
+	}
+}
+
+func TestRescale(t *testing.T) {
+	tests := []struct {
+		in      string
+		from    int32
+		to      int32
+		want    string
+		wantErr bool
+	}{
+		{"1.5", 1, 6, "1.500000", false},
+		{"1.500000", 6, 1, "1.5", false},
+		{"1.500000", 6, 0, "", true},
+		{"-2.000", 3, 0, "-2", false},
+		{"3", 0, 0, "3", false},
+		{"3", 0, -1, "", true},
+		{"1.5", 1, -2, "", true},
+	}
+	for _, tt := range tests {
+		a := MustParse(tt.in, tt.from)
+		got, err := a.Rescale(tt.to)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s.Rescale(%d) = %s, want error", tt.in, tt.to, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s.Rescale(%d): %v", tt.in, tt.to, err)
+			continue
+		}
+		if got.String() != tt.want || got.Decimals() != tt.to {
+			t.Errorf("%s.Rescale(%d) = %s, want %s", tt.in, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("1.5", 1)
+	b := MustParse("0.25", 6)
+	if got := a.Add(b).String(); got != "1.750000" {
+		t.Errorf("Add = %s", got)
+	}
+	if got := a.Sub(b).String(); got != "1.250000" {
+		t.Errorf("Sub = %s", got)
+	}
+	if got := a.MulInt(3).String(); got != "4.5" {
+		t.Errorf("MulInt = %s", got)
+	}
+	if got := a.Neg().String(); got != "-1.5" {
+		t.Errorf("Neg = %s", got)
+	}
+
+	tests := []struct {
+		a, b Amount
+		want int
+	}{
+		{MustParse("1", 0), MustParse("1.000000", 6), 0},
+		{MustParse("0.999999", 6), MustParse("1", 0), -1},
+		{MustParse("1.000001", 6), MustParse("1.00", 2), 1},
+		{MustParse("-1", 0), Amount{}, -1},
+		{Amount{}, FromInt64(0, 18), 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Cmp(tt.b); got != tt.want {
+			t.Errorf("%s.Cmp(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if !FromInt64(0, 6).IsZero() || FromInt64(-1, 6).Sign() != -1 {
+		t.Error("IsZero/Sign")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	for _, a := range []Amount{
+		MustParse("12.5", 6),
+		MustParse("-0.000000000000000001", 18),
+		FromInt64(42, 0),
+		FromInt64(0, 2),
+	} {
+		data, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Amount
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		if back.Cmp(a) != 0 || back.Decimals() != a.Decimals() {
+			t.Errorf("round trip %s: got %s/%d, want %s/%d", data, back, back.Decimals(), a, a.Decimals())
+		}
+	}
+
+	var v struct {
+		A Amount  `json:"a"`
+		P *Amount `json:"p"`
+	}
+	v.A = FromInt64(7, 2)
+	if err := json.Unmarshal([]byte(`{"a": null, "p": null}`), &v); err != nil {
+		t.Fatalf("null: %v", err)
+	}
+	if v.A.String() != "0.07" || v.P != nil {
+		t.Errorf("null changed the amounts: %s %v", v.A, v.P)
+	}
+	if err := json.Unmarshal([]byte(`{"a": 1.25}`), &v); err != nil || v.A.String() != "1.25" {
+		t.Errorf("number: %s %v", v.A, err)
+	}
+	if err := json.Unmarshal([]byte(`{"a": "abc"}`), &v); err == nil {
+		t.Error("invalid string accepted")
+	}
+}
+
+func TestJSONZeroDecimals(t *testing.T) {
+	// 0 位小数的资产（如 TRC10）不能被当成"未知"而从小数部分推断
+	a := FromInt64(0, 0)
+	if err := json.Unmarshal([]byte(`"5"`), &a); err != nil || a.String() != "5" || a.Decimals() != 0 {
+		t.Errorf(`"5" into 0 decimals = %s/%d, %v`, a, a.Decimals(), err)
+	}
+	if err := json.Unmarshal([]byte(`"1.5"`), &a); err == nil {
+		t.Errorf(`"1.5" into 0 decimals = %s, want error`, a)
+	}
+	if err := json.Unmarshal([]byte(`"2.000"`), &a); err != nil || a.String() != "2" {
+		t.Errorf(`"2.000" into 0 decimals = %s, %v`, a, err)
+	}
+
+	var inferred Amount
+	if err := json.Unmarshal([]byte(`"1.50"`), &inferred); err != nil || inferred.Decimals() != 2 {
+		t.Errorf(`"1.50" into zero Amount = %s/%d, %v`, inferred, inferred.Decimals(), err)
+	}
+}
+
+func TestNegativeDecimals(t *testing.T) {
+	if _, err := Parse("1", -1); err == nil {
+		t.Error("Parse with -1 decimals accepted")
+	}
+	for name, f := range map[string]func(){
+		"New":       func() { New(big.NewInt(1), -1) },
+		"FromInt64": func() { FromInt64(1, -3) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s with negative decimals did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestSQL(t *testing.T) {
+	a := MustParse("12.5", 6)
+	v, err := a.Value()
+	if err != nil || v != "12.500000" {
+		t.Fatalf("Value() = %v, %v", v, err)
+	}
+
+	tests := []struct {
+		src      any
+		decimals int32 // -1: 零值 Amount，小数位未知
+		want     string
+	}{
+		{"12.500000", -1, "12.500000"},
+		{"12", 0, "12"},
+		{[]byte("1.5"), 6, "1.500000"},
+		// 整数列是最小单位
+		{int64(1500000), 6, "1.500000"},
+		{int64(3), 0, "3"},
+		{nil, 6, "0.000000"},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if tt.decimals >= 0 {
+			got = FromInt64(0, tt.decimals)
+		}
+		if err := got.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Scan(%v) with %d decimals = %s, want %s", tt.src, tt.decimals, got, tt.want)
+		}
+	}
+	var bad Amount
+	if err := bad.Scan(1.5); err == nil {
+		t.Error("Scan(float64) accepted")
+	}
+	whole := FromInt64(0, 0)
+	if err := whole.Scan("12.5"); err == nil {
+		t.Errorf(`Scan("12.5") with 0 decimals = %s, want error`, whole)
+	}
+}
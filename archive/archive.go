@@ -0,0 +1,314 @@
+// Package archive keeps blocks, transactions, receipts and decoded events in a
+// local SQLite file so history can be queried without TronGrid or a node.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/yourname/tron-demo/monitor"
+	"google.golang.org/protobuf/proto"
+	_ "modernc.org/sqlite"
+)
+
+// schema is applied on every Open; all statements are idempotent.
+// Timestamps are unix milliseconds, fees are in sun.
+const schema = `
+CREATE TABLE IF NOT EXISTS blocks (
+	number      INTEGER PRIMARY KEY,
+	hash        TEXT    NOT NULL,
+	parent_hash TEXT    NOT NULL,
+	timestamp   INTEGER NOT NULL,
+	tx_count    INTEGER NOT NULL,
+	header      BLOB    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS blocks_timestamp ON blocks (timestamp);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id           TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	position     INTEGER NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	type         TEXT    NOT NULL,
+	owner        TEXT    NOT NULL,
+	recipient    TEXT    NOT NULL,
+	success      INTEGER NOT NULL,
+	result       TEXT    NOT NULL,
+	fee          INTEGER NOT NULL,
+	energy_used  INTEGER NOT NULL,
+	raw          BLOB    NOT NULL,
+	info         BLOB
+);
+CREATE INDEX IF NOT EXISTS transactions_block ON transactions (block_number, position);
+CREATE INDEX IF NOT EXISTS transactions_timestamp ON transactions (timestamp);
+CREATE INDEX IF NOT EXISTS transactions_recipient ON transactions (recipient, timestamp);
+
+CREATE TABLE IF NOT EXISTS events (
+	tx_id        TEXT    NOT NULL,
+	log_index    INTEGER NOT NULL,
+	block_number INTEGER NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	contract     TEXT    NOT NULL,
+	name         TEXT    NOT NULL,
+	signature    TEXT    NOT NULL,
+	topic0       TEXT    NOT NULL,
+	args         TEXT    NOT NULL,
+	topics       TEXT    NOT NULL,
+	data         TEXT    NOT NULL,
+	PRIMARY KEY (tx_id, log_index)
+);
+CREATE INDEX IF NOT EXISTS events_block ON events (block_number);
+CREATE INDEX IF NOT EXISTS events_contract ON events (contract, timestamp);
+CREATE INDEX IF NOT EXISTS events_name ON events (name, timestamp);
+CREATE INDEX IF NOT EXISTS events_timestamp ON events (timestamp);
+
+CREATE TABLE IF NOT EXISTS addresses (
+	address      TEXT    NOT NULL,
+	tx_id        TEXT    NOT NULL,
+	role         TEXT    NOT NULL,
+	log_index    INTEGER NOT NULL,
+	block_number INTEGER NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	PRIMARY KEY (address, tx_id, role, log_index)
+);
+CREATE INDEX IF NOT EXISTS addresses_timestamp ON addresses (address, timestamp);
+CREATE INDEX IF NOT EXISTS addresses_block ON addresses (block_number);
+`
+
+// Roles of an address in the addresses index
+const (
+	RoleOwner    = "owner"
+	RoleTo       = "to"
+	RoleContract = "contract"
+	RoleEvent    = "event"
+	RoleInternal = "internal"
+	RoleStaking  = "staking"
+)
+
+// Options tune what the archive keeps
+type Options struct {
+	// Contracts limits archived transactions to those calling, emitting logs
+	// from or being called by one of these contracts; empty keeps every
+	// transaction. Block headers are always kept.
+	Contracts []string
+}
+
+// Store is an archive file
+type Store struct {
+	db   *sql.DB
+	opts Options
+}
+
+// Open opens or creates the archive at path
+func Open(path string, opts Options) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{schema, publishedSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("archive %s: %w", path, err)
+		}
+	}
+	return &Store{db: db, opts: opts}, nil
+}
+
+// Close closes the archive file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Handler returns a monitor handler that archives every processed block and
+// removes blocks abandoned by a reorganisation
+func (s *Store) Handler() monitor.Handler {
+	return monitor.Handler{
+		Name:    "archive",
+		OnBlock: s.Write,
+		OnRollback: func(ctx context.Context, b *monitor.Block) error {
+			return s.Delete(ctx, b.Number)
+		},
+	}
+}
+
+// Write stores a block, replacing what was archived for the same block number
+func (s *Store) Write(ctx context.Context, b *monitor.Block) error {
+	header, err := proto.Marshal(b.Raw.GetBlockHeader())
+	if err != nil {
+		return fmt.Errorf("block %d header: %w", b.Number, err)
+	}
+	dbtx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbtx.Rollback()
+
+	if err := deleteBlock(ctx, dbtx, b.Number); err != nil {
+		return err
+	}
+	if _, err := dbtx.ExecContext(ctx,
+		`INSERT INTO blocks (number, hash, parent_hash, timestamp, tx_count, header) VALUES (?, ?, ?, ?, ?, ?)`,
+		b.Number, b.Hash, b.ParentHash, b.Timestamp.UnixMilli(), len(b.Transactions), header); err != nil {
+		return fmt.Errorf("block %d: %w", b.Number, err)
+	}
+	for i, tx := range b.Transactions {
+		if !s.keep(tx) {
+			continue
+		}
+		if err := writeTransaction(ctx, dbtx, i, tx); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.ID, err)
+		}
+	}
+	return dbtx.Commit()
+}
+
+// Delete removes an archived block and everything recorded for it
+func (s *Store) Delete(ctx context.Context, number int64) error {
+	dbtx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbtx.Rollback()
+	if err := deleteBlock(ctx, dbtx, number); err != nil {
+		return err
+	}
+	return dbtx.Commit()
+}
+
+func deleteBlock(ctx context.Context, dbtx *sql.Tx, number int64) error {
+	for _, table := range []string{"addresses", "events", "transactions"} {
+		if _, err := dbtx.ExecContext(ctx, `DELETE FROM `+table+` WHERE block_number = ?`, number); err != nil {
+			return fmt.Errorf("delete block %d from %s: %w", number, table, err)
+		}
+	}
+	if _, err := dbtx.ExecContext(ctx, `DELETE FROM blocks WHERE number = ?`, number); err != nil {
+		return fmt.Errorf("delete block %d: %w", number, err)
+	}
+	return nil
+}
+
+// keep reports whether a transaction passes Options.Contracts
+func (s *Store) keep(tx *monitor.Transaction) bool {
+	c := s.opts.Contracts
+	if len(c) == 0 || slices.Contains(c, tx.To) {
+		return true
+	}
+	return slices.ContainsFunc(tx.Logs, func(l *monitor.Log) bool { return slices.Contains(c, l.Contract) }) ||
+		slices.ContainsFunc(tx.Internal, func(it *monitor.InternalTx) bool { return slices.Contains(c, it.Caller) })
+}
+
+// writeTransaction stores a transaction with its receipt, logs and address index
+func writeTransaction(ctx context.Context, dbtx *sql.Tx, position int, tx *monitor.Transaction) error {
+	raw, err := proto.Marshal(tx.Raw)
+	if err != nil {
+		return err
+	}
+	var info []byte
+	if tx.Info != nil {
+		if info, err = proto.Marshal(tx.Info); err != nil {
+			return err
+		}
+	}
+	result := "SUCCESS"
+	if tx.Failure != nil {
+		result = tx.Failure.Result
+	}
+	ts := tx.Block.Timestamp.UnixMilli()
+	if _, err := dbtx.ExecContext(ctx,
+		`INSERT INTO transactions (id, block_number, position, timestamp, type, owner, recipient, success, result, fee, energy_used, raw, info)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tx.ID, tx.Block.Number, position, ts, tx.Type, tx.Owner, tx.To, tx.Success, result,
+		tx.Info.GetFee(), tx.Info.GetReceipt().GetEnergyUsageTotal(), raw, info); err != nil {
+		return err
+	}
+
+	index := func(address, role string, logIndex int) error {
+		if address == "" {
+			return nil
+		}
+		_, err := dbtx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO addresses (address, tx_id, role, log_index, block_number, timestamp) VALUES (?, ?, ?, ?, ?, ?)`,
+			address, tx.ID, role, logIndex, tx.Block.Number, ts)
+		return err
+	}
+	if err := index(tx.Owner, RoleOwner, -1); err != nil {
+		return err
+	}
+	if err := index(tx.To, RoleTo, -1); err != nil {
+		return err
+	}
+	if s := tx.Staking; s != nil {
+		if err := index(s.Receiver, RoleStaking, -1); err != nil {
+			return err
+		}
+		for _, v := range s.Votes {
+			if err := index(v.Witness, RoleStaking, -1); err != nil {
+				return err
+			}
+		}
+	}
+	for _, it := range tx.Internal {
+		if err := index(it.Caller, RoleInternal, -1); err != nil {
+			return err
+		}
+		if err := index(it.To, RoleInternal, -1); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range tx.Logs {
+		if err := writeLog(ctx, dbtx, l); err != nil {
+			return err
+		}
+		if err := index(l.Contract, RoleContract, l.Index); err != nil {
+			return err
+		}
+		if l.Event == nil {
+			continue
+		}
+		for _, a := range l.Event.Args {
+			if a.Type != "address" {
+				continue
+			}
+			if err := index(a.String(), RoleEvent, l.Index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeLog stores a log with its decoded name and arguments (empty when undecoded)
+func writeLog(ctx context.Context, dbtx *sql.Tx, l *monitor.Log) error {
+	var name, signature, topic0 string
+	args := map[string]string{}
+	if l.Event != nil {
+		name, signature = l.Event.Name, l.Event.Signature
+		args = l.Event.Fields()
+	}
+	topics := make([]string, len(l.Topics))
+	for i, t := range l.Topics {
+		topics[i] = hex.EncodeToString(t)
+	}
+	if len(topics) > 0 {
+		topic0 = topics[0]
+	}
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	topicsJSON, err := json.Marshal(topics)
+	if err != nil {
+		return err
+	}
+	_, err = dbtx.ExecContext(ctx,
+		`INSERT INTO events (tx_id, log_index, block_number, timestamp, contract, name, signature, topic0, args, topics, data)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.Tx.ID, l.Index, l.Tx.Block.Number, l.Tx.Block.Timestamp.UnixMilli(), l.Contract, name, signature, topic0,
+		string(argsJSON), string(topicsJSON), hex.EncodeToString(l.Data))
+	return err
+}
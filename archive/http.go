@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Register adds the query API to mux:
+//
+//	GET /archive/transactions  archived transactions
+//	GET /archive/events        archived contract events
+//	GET /archive/range         first and last archived block
+//
+// Transactions and events take the Query fields as parameters: address,
+// contract, type, event, since, until (RFC3339, YYYY-MM-DD or unix ms),
+// from_block, to_block, limit and offset.
+func Register(mux *http.ServeMux, s *Store) {
+	mux.HandleFunc("GET /archive/transactions", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		txs, err := s.Transactions(r.Context(), q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, txs)
+	})
+	mux.HandleFunc("GET /archive/events", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events, err := s.Events(r.Context(), q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, events)
+	})
+	mux.HandleFunc("GET /archive/range", func(w http.ResponseWriter, r *http.Request) {
+		first, last, err := s.Range(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]int64{"first_block": first, "last_block": last})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// parseQuery reads a Query from URL parameters
+func parseQuery(v url.Values) (Query, error) {
+	q := Query{
+		Address:  v.Get("address"),
+		Contract: v.Get("contract"),
+		Type:     v.Get("type"),
+		Event:    v.Get("event"),
+	}
+	var err error
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if s := v.Get(p.name); s != "" {
+			if *p.dst, err = parseTime(s); err != nil {
+				return q, fmt.Errorf("%s: %w", p.name, err)
+			}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"from_block", &q.FromBlock}, {"to_block", &q.ToBlock}} {
+		if s := v.Get(p.name); s != "" {
+			if *p.dst, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return q, fmt.Errorf("%s: %w", p.name, err)
+			}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &q.Limit}, {"offset", &q.Offset}} {
+		if s := v.Get(p.name); s != "" {
+			if *p.dst, err = strconv.Atoi(s); err != nil {
+				return q, fmt.Errorf("%s: %w", p.name, err)
+			}
+		}
+	}
+	return q, nil
+}
+
+// parseTime accepts RFC3339, a date (YYYY-MM-DD, UTC) or unix milliseconds
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC3339, YYYY-MM-DD or unix ms", s)
+	}
+	return time.UnixMilli(ms), nil
+}
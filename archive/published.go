@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/monitor"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// publishedSchema records the events the watchers published (deposits,
+// transfers, logs...), so a replay can be compared with what was credited
+const publishedSchema = `
+CREATE TABLE IF NOT EXISTS published (
+	source       TEXT    NOT NULL,
+	key          TEXT    NOT NULL,
+	type         TEXT    NOT NULL,
+	tx_id        TEXT    NOT NULL,
+	block_number INTEGER NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	contract     TEXT    NOT NULL,
+	sender       TEXT    NOT NULL,
+	recipient    TEXT    NOT NULL,
+	value        TEXT,
+	confirmed    INTEGER NOT NULL,
+	data         TEXT    NOT NULL,
+	PRIMARY KEY (source, key)
+);
+CREATE INDEX IF NOT EXISTS published_block ON published (source, block_number);
+`
+
+// Sink returns a sink recording published events in the archive. A rollback
+// event removes the event it revokes. Closing the sink does not close the store.
+func (s *Store) Sink() sink.EventSink {
+	return publishedSink{s}
+}
+
+type publishedSink struct{ s *Store }
+
+func (p publishedSink) Publish(ctx context.Context, ev sink.Event) error {
+	switch ev.Type {
+	case monitor.EventTypeRollback:
+		_, err := p.s.db.ExecContext(ctx, `DELETE FROM published WHERE source = ? AND key = ?`, ev.Source, ev.Data["rollback_key"])
+		return err
+	case monitor.EventTypeBlockRollback:
+		return nil
+	}
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	var value sql.NullString
+	if ev.Value != nil {
+		value = sql.NullString{String: ev.Value.String(), Valid: true}
+	}
+	_, err = p.s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO published (source, key, type, tx_id, block_number, timestamp, contract, sender, recipient, value, confirmed, data)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ev.Source, ev.Key, ev.Type, ev.TxID, ev.BlockNumber, ev.BlockTimestamp, ev.Contract, ev.From, ev.To, value, ev.Confirmed, string(data))
+	if err != nil {
+		return fmt.Errorf("record event %s: %w", ev.Key, err)
+	}
+	return nil
+}
+
+func (publishedSink) Close() error { return nil }
+
+// Published returns the recorded events of source in blocks [from, to], in
+// block and key order. Only events a replay can reproduce are returned: those
+// of archived transactions, and events without a transaction from archived
+// blocks. With archive.contracts set, other transactions were never archived.
+func (s *Store) Published(ctx context.Context, source string, from, to int64) ([]sink.Event, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT p.key, p.type, p.tx_id, p.block_number, p.timestamp, p.contract, p.sender, p.recipient, p.value, p.confirmed, p.data
+		 FROM published p
+		 WHERE p.source = ? AND p.block_number BETWEEN ? AND ?
+		   AND (EXISTS (SELECT 1 FROM transactions t WHERE t.id = p.tx_id)
+		        OR p.tx_id = '' AND EXISTS (SELECT 1 FROM blocks b WHERE b.number = p.block_number))
+		 ORDER BY p.block_number, p.key`,
+		source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []sink.Event
+	for rows.Next() {
+		ev := sink.Event{Source: source}
+		var value sql.NullString
+		var data string
+		if err := rows.Scan(&ev.Key, &ev.Type, &ev.TxID, &ev.BlockNumber, &ev.BlockTimestamp, &ev.Contract, &ev.From, &ev.To, &value, &ev.Confirmed, &data); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			// 精度以记录时的小数位为准，比较时按数值比较
+			v, err := amount.Parse(value.String, decimals(value.String))
+			if err != nil {
+				return nil, fmt.Errorf("event %s value: %w", ev.Key, err)
+			}
+			ev.Value = &v
+		}
+		if err := json.Unmarshal([]byte(data), &ev.Data); err != nil {
+			return nil, fmt.Errorf("event %s data: %w", ev.Key, err)
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+// decimals counts the fraction digits of a decimal string
+func decimals(s string) int32 {
+	for i := range s {
+		if s[i] == '.' {
+			return int32(len(s) - i - 1)
+		}
+	}
+	return 0
+}
+
+// Numbers returns the archived block numbers in [from, to], ascending
+func (s *Store) Numbers(ctx context.Context, from, to int64) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT number FROM blocks WHERE number BETWEEN ? AND ? ORDER BY number`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var n int64
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
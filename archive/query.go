@@ -0,0 +1,272 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/amount"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotArchived is returned for blocks that are not in the archive
+var ErrNotArchived = errors.New("block not archived")
+
+// Query selects archived transactions or events. Empty fields do not filter.
+type Query struct {
+	// Address matches transactions and events involving the address (owner,
+	// recipient, emitting contract, event argument, internal transaction or
+	// staking receiver)
+	Address string
+	// Contract matches the called contract of a transaction (or any contract it
+	// emitted logs from) and the emitting contract of an event
+	Contract string
+	// Type matches the system contract type of transactions, e.g. TriggerSmartContract
+	Type string
+	// Event matches events by name (Transfer), signature or topic0 hex
+	Event string
+	// Since and Until bound the block time (Until exclusive)
+	Since, Until time.Time
+	// FromBlock and ToBlock bound the block number (both inclusive)
+	FromBlock, ToBlock int64
+	// Limit caps the number of results, oldest first; 0 means 100
+	Limit  int
+	Offset int
+}
+
+// MaxLimit caps Query.Limit
+const MaxLimit = 10000
+
+func (q Query) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return 100
+	case q.Limit > MaxLimit:
+		return MaxLimit
+	}
+	return q.Limit
+}
+
+// where collects SQL conditions and their arguments
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string, args ...any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// common adds the time and block bounds on table alias t
+func (w *where) common(q Query, t string) {
+	if !q.Since.IsZero() {
+		w.add(t+".timestamp >= ?", q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		w.add(t+".timestamp < ?", q.Until.UnixMilli())
+	}
+	if q.FromBlock > 0 {
+		w.add(t+".block_number >= ?", q.FromBlock)
+	}
+	if q.ToBlock > 0 {
+		w.add(t+".block_number <= ?", q.ToBlock)
+	}
+}
+
+// TxRecord is an archived transaction
+type TxRecord struct {
+	ID          string    `json:"id"`
+	BlockNumber int64     `json:"block_number"`
+	Position    int       `json:"position"`
+	Timestamp   time.Time `json:"timestamp"`
+	Type        string    `json:"type"`
+	Owner       string    `json:"owner"`
+	To          string    `json:"to,omitempty"`
+	Success     bool      `json:"success"`
+	Result      string    `json:"result"`
+	// Fee is the TRX burned for energy and bandwidth
+	Fee        amount.Amount `json:"fee"`
+	EnergyUsed int64         `json:"energy_used"`
+}
+
+// Transactions returns the archived transactions matching q, oldest first
+func (s *Store) Transactions(ctx context.Context, q Query) ([]TxRecord, error) {
+	var w where
+	w.common(q, "t")
+	if q.Address != "" {
+		w.add("t.id IN (SELECT tx_id FROM addresses WHERE address = ?)", q.Address)
+	}
+	if q.Contract != "" {
+		w.add("(t.recipient = ? OR t.id IN (SELECT tx_id FROM events WHERE contract = ?))", q.Contract, q.Contract)
+	}
+	if q.Type != "" {
+		w.add("t.type = ?", q.Type)
+	}
+	if q.Event != "" {
+		w.add("t.id IN (SELECT tx_id FROM events WHERE name = ? OR signature = ? OR topic0 = ?)", q.Event, q.Event, topicHex(q.Event))
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT t.id, t.block_number, t.position, t.timestamp, t.type, t.owner, t.recipient, t.success, t.result, t.fee, t.energy_used
+		 FROM transactions t`+w.String()+` ORDER BY t.block_number, t.position LIMIT ? OFFSET ?`,
+		append(w.args, q.limit(), q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TxRecord
+	for rows.Next() {
+		var r TxRecord
+		var ts, fee int64
+		if err := rows.Scan(&r.ID, &r.BlockNumber, &r.Position, &ts, &r.Type, &r.Owner, &r.To, &r.Success, &r.Result, &fee, &r.EnergyUsed); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.UnixMilli(ts).UTC()
+		r.Fee = amount.FromInt64(fee, amount.TRXDecimals)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// EventRecord is an archived contract log; Name, Signature and Args are empty
+// when the log could not be decoded
+type EventRecord struct {
+	TxID        string            `json:"tx_id"`
+	LogIndex    int               `json:"log_index"`
+	BlockNumber int64             `json:"block_number"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Contract    string            `json:"contract"`
+	Name        string            `json:"name,omitempty"`
+	Signature   string            `json:"signature,omitempty"`
+	Args        map[string]string `json:"args,omitempty"`
+	Topics      []string          `json:"topics"`
+	Data        string            `json:"data"`
+}
+
+// Events returns the archived events matching q, oldest first. Query.Type
+// filters on the system contract type of the emitting transaction.
+func (s *Store) Events(ctx context.Context, q Query) ([]EventRecord, error) {
+	var w where
+	w.common(q, "e")
+	if q.Address != "" {
+		w.add("EXISTS (SELECT 1 FROM addresses a WHERE a.address = ? AND a.tx_id = e.tx_id AND a.log_index = e.log_index)", q.Address)
+	}
+	if q.Contract != "" {
+		w.add("e.contract = ?", q.Contract)
+	}
+	if q.Type != "" {
+		w.add("e.tx_id IN (SELECT id FROM transactions WHERE type = ?)", q.Type)
+	}
+	if q.Event != "" {
+		w.add("(e.name = ? OR e.signature = ? OR e.topic0 = ?)", q.Event, q.Event, topicHex(q.Event))
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT e.tx_id, e.log_index, e.block_number, e.timestamp, e.contract, e.name, e.signature, e.args, e.topics, e.data
+		 FROM events e`+w.String()+` ORDER BY e.block_number, e.tx_id, e.log_index LIMIT ? OFFSET ?`,
+		append(w.args, q.limit(), q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []EventRecord
+	for rows.Next() {
+		var r EventRecord
+		var ts int64
+		var args, topics string
+		if err := rows.Scan(&r.TxID, &r.LogIndex, &r.BlockNumber, &ts, &r.Contract, &r.Name, &r.Signature, &args, &topics, &r.Data); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.UnixMilli(ts).UTC()
+		if err := json.Unmarshal([]byte(args), &r.Args); err != nil {
+			return nil, fmt.Errorf("event %s#%d args: %w", r.TxID, r.LogIndex, err)
+		}
+		if len(r.Args) == 0 {
+			r.Args = nil
+		}
+		if err := json.Unmarshal([]byte(topics), &r.Topics); err != nil {
+			return nil, fmt.Errorf("event %s#%d topics: %w", r.TxID, r.LogIndex, err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// topicHex normalises a topic0 given with or without 0x
+func topicHex(s string) string {
+	return strings.ToLower(strings.TrimPrefix(s, "0x"))
+}
+
+// Range returns the lowest and highest archived block numbers; both are 0 for an empty archive
+func (s *Store) Range(ctx context.Context) (first, last int64, err error) {
+	var lo, hi sql.NullInt64
+	err = s.db.QueryRowContext(ctx, `SELECT MIN(number), MAX(number) FROM blocks`).Scan(&lo, &hi)
+	return lo.Int64, hi.Int64, err
+}
+
+// Block returns an archived block as the node returned it, with the receipts
+// of its archived transactions. Transactions left out by Options.Contracts are
+// missing from both.
+func (s *Store) Block(ctx context.Context, number int64) (*api.BlockExtention, []*core.TransactionInfo, error) {
+	var hash string
+	var header []byte
+	err := s.db.QueryRowContext(ctx, `SELECT hash, header FROM blocks WHERE number = ?`, number).Scan(&hash, &header)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("block %d: %w", number, ErrNotArchived)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	block := &api.BlockExtention{BlockHeader: &core.BlockHeader{}}
+	if err := proto.Unmarshal(header, block.BlockHeader); err != nil {
+		return nil, nil, fmt.Errorf("block %d header: %w", number, err)
+	}
+	if block.Blockid, err = hex.DecodeString(hash); err != nil {
+		return nil, nil, fmt.Errorf("block %d hash: %w", number, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, raw, info FROM transactions WHERE block_number = ? ORDER BY position`, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var infos []*core.TransactionInfo
+	for rows.Next() {
+		var id string
+		var raw, info []byte
+		if err := rows.Scan(&id, &raw, &info); err != nil {
+			return nil, nil, err
+		}
+		ext := &api.TransactionExtention{Transaction: &core.Transaction{}}
+		if err := proto.Unmarshal(raw, ext.Transaction); err != nil {
+			return nil, nil, fmt.Errorf("transaction %s: %w", id, err)
+		}
+		if ext.Txid, err = hex.DecodeString(id); err != nil {
+			return nil, nil, fmt.Errorf("transaction %s: %w", id, err)
+		}
+		block.Transactions = append(block.Transactions, ext)
+		if info != nil {
+			ti := &core.TransactionInfo{}
+			if err := proto.Unmarshal(info, ti); err != nil {
+				return nil, nil, fmt.Errorf("transaction %s receipt: %w", id, err)
+			}
+			infos = append(infos, ti)
+		}
+	}
+	return block, infos, rows.Err()
+}
@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/tron-demo/sink"
+	"github.com/yourname/tron-demo/source"
+)
+
+const testContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "archive.db"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func exec(t *testing.T, s *Store, query string, args ...any) {
+	t.Helper()
+	if _, err := s.db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addBlock(t *testing.T, s *Store, num int64) {
+	exec(t, s, `INSERT INTO blocks (number, hash, parent_hash, timestamp, tx_count, header) VALUES (?, ?, '', ?, 0, x'')`,
+		num, fmt.Sprintf("%064d", num), num*3000)
+}
+
+func addTransaction(t *testing.T, s *Store, id string, num int64, position int) {
+	exec(t, s, `INSERT INTO transactions (id, block_number, position, timestamp, type, owner, recipient, success, result, fee, energy_used, raw)
+		VALUES (?, ?, ?, ?, 'TriggerSmartContract', '', '', 1, 'SUCCESS', 0, 0, x'')`, id, num, position, num*3000)
+}
+
+// addTransfer archives a Transfer log of value from and to fixed addresses
+func addTransfer(t *testing.T, s *Store, txID string, logIndex int, num int64, value int64) {
+	topics, _ := json.Marshal([]string{
+		source.TransferTopic,
+		strings.Repeat("0", 24) + "1111111111111111111111111111111111111111",
+		strings.Repeat("0", 24) + "2222222222222222222222222222222222222222",
+	})
+	exec(t, s, `INSERT INTO events (tx_id, log_index, block_number, timestamp, contract, name, signature, topic0, args, topics, data)
+		VALUES (?, ?, ?, ?, ?, 'Transfer', 'Transfer(address,address,uint256)', ?, '{}', ?, ?)`,
+		txID, logIndex, num, num*3000, testContract, source.TransferTopic, string(topics), fmt.Sprintf("%064x", value))
+}
+
+// 同一区块内按交易位置排序，而不是按交易 id
+func TestSourceTransfersOrder(t *testing.T) {
+	s := testStore(t)
+	addBlock(t, s, 100)
+	addBlock(t, s, 101)
+	addTransaction(t, s, "ff01", 100, 0)
+	addTransaction(t, s, "aa02", 100, 1)
+	addTransaction(t, s, "cc03", 101, 0)
+	addTransfer(t, s, "aa02", 0, 100, 2)
+	addTransfer(t, s, "ff01", 1, 100, 1)
+	addTransfer(t, s, "ff01", 0, 100, 0)
+	addTransfer(t, s, "cc03", 0, 101, 3)
+
+	got, err := NewSource(s).Transfers(context.Background(), []string{testContract}, 100, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, tr := range got {
+		keys = append(keys, tr.Key())
+	}
+	want := []string{"ff01#0", "ff01#1", "aa02#0", "cc03#0"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Fatalf("order = %v, want %v", keys, want)
+	}
+	if got[0].From != "TBXSw8fM4jpQkGc6zZjsVABFpVN7UvXPdV" || got[3].Value.Int64() != 3 {
+		t.Errorf("transfer = %+v", got[0])
+	}
+
+	if _, err := NewSource(s).Transfers(context.Background(), []string{testContract}, 100, 102); !errors.Is(err, source.ErrBeyondHead) {
+		t.Errorf("Transfers past the archive = %v, want ErrBeyondHead", err)
+	}
+}
+
+// 未归档的交易（archive.contracts 过滤掉的）无法重放，不参与对比
+func TestPublishedOnlyArchived(t *testing.T) {
+	s := testStore(t)
+	addBlock(t, s, 100)
+	addTransaction(t, s, "aa01", 100, 0)
+	for _, ev := range []sink.Event{
+		{Source: "gridwatcher", Type: "DEPOSIT", Key: "aa01#0", TxID: "aa01", BlockNumber: 100},
+		{Source: "gridwatcher", Type: "DEPOSIT", Key: "bb02#0", TxID: "bb02", BlockNumber: 100},
+		{Source: "gridwatcher", Type: "BLOCK", Key: "block#100", BlockNumber: 100},
+		{Source: "gridwatcher", Type: "BLOCK", Key: "block#101", BlockNumber: 101},
+		{Source: "monitor", Type: "DEPOSIT", Key: "aa01#0", TxID: "aa01", BlockNumber: 100},
+	} {
+		if err := s.Sink().Publish(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.Published(context.Background(), "gridwatcher", 100, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, ev := range got {
+		keys = append(keys, ev.Key)
+	}
+	if want := "aa01#0 block#100"; strings.Join(keys, " ") != want {
+		t.Errorf("Published = %v, want %s", keys, want)
+	}
+}
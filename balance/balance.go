@@ -3,18 +3,30 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"net/http"
+	"os"
 	"strings"
-)
 
-const (
-	apiURL       = "https://api.trongrid.io/wallet/triggersmartcontract"
-	usdtContract = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" // USDT 合约地址（Base58）
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/config"
 )
 
+// triggerSmartContractPath 相对于 TronGrid base URL 的接口路径
+const triggerSmartContractPath = "/wallet/triggersmartcontract"
+
+var debug = flag.Bool("debug", false, "log address conversions, requests and responses")
+
+// debugf logs request details when -debug is set
+func debugf(format string, args ...any) {
+	if *debug {
+		log.Printf("[balance] "+format, args...)
+	}
+}
+
 type TriggerSmartContractRequest struct {
 	OwnerAddress     string `json:"owner_address"`
 	ContractAddress  string `json:"contract_address"`
@@ -39,7 +51,7 @@ func base58ToHex(address string) (string, error) {
 	}
 
 	if hexAddr, exists := knownConversions[address]; exists {
-		fmt.Printf("Debug: Using known conversion for %s -> %s\n", address, hexAddr)
+		debugf("Using known conversion for %s -> %s", address, hexAddr)
 		return hexAddr, nil
 	}
 
@@ -80,7 +92,7 @@ func base58ToHex(address string) (string, error) {
 		hexStr = "41" + hexStr
 	}
 
-	fmt.Printf("Debug: Base58 address %s -> Hex: %s\n", address, hexStr)
+	debugf("Base58 address %s -> hex %s", address, hexStr)
 	return hexStr, nil
 }
 
@@ -90,7 +102,7 @@ func padAddressParam(hexAddr string) string {
 	return fmt.Sprintf("%064s", addr)
 }
 
-func getTRC20Balance(userAddress string) (*big.Float, error) {
+func getTRC20Balance(cfg *config.Config, usdtContract, userAddress string) (*amount.Amount, error) {
 	hexAddr, err := base58ToHex(userAddress)
 	if err != nil {
 		return nil, err
@@ -104,10 +116,7 @@ func getTRC20Balance(userAddress string) (*big.Float, error) {
 
 	param := padAddressParam(hexAddr)
 
-	fmt.Printf("Debug: Request parameters:\n")
-	fmt.Printf("  OwnerAddress: %s\n", hexAddr)
-	fmt.Printf("  ContractAddress: %s\n", contractHexAddr)
-	fmt.Printf("  Parameter: %s\n", param)
+	debugf("Request parameters: owner=%s contract=%s parameter=%s", hexAddr, contractHexAddr, param)
 
 	reqBody := TriggerSmartContractRequest{
 		OwnerAddress:     hexAddr,
@@ -122,22 +131,32 @@ func getTRC20Balance(userAddress string) (*big.Float, error) {
 		return nil, err
 	}
 
-	fmt.Printf("Debug: Request JSON: %s\n", string(jsonData))
+	debugf("Request JSON: %s", jsonData)
 
-	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(jsonData))
+	apiURL := strings.TrimRight(cfg.TronGrid.BaseURL, "/") + triggerSmartContractPath
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.TronGrid.APIKey != "" {
+		req.Header.Set("TRON-PRO-API-KEY", cfg.TronGrid.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	fmt.Printf("Debug: Response status: %s\n", resp.Status)
+	debugf("Response status: %s", resp.Status)
 
 	var result TriggerSmartContractResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("Debug: Response result: %+v\n", result)
+	debugf("Response result: %+v", result)
 
 	if len(result.ConstantResult) == 0 {
 		return nil, fmt.Errorf("no balance returned")
@@ -145,23 +164,32 @@ func getTRC20Balance(userAddress string) (*big.Float, error) {
 
 	// 解析 hex → decimal
 	balanceHex := result.ConstantResult[0]
-	balanceInt := new(big.Int)
-	balanceInt.SetString(balanceHex, 16)
-
-	fmt.Printf("Debug: Balance hex: %s, decimal: %s\n", balanceHex, balanceInt.String())
+	balanceInt, ok := new(big.Int).SetString(balanceHex, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance hex: %q", balanceHex)
+	}
 
-	// USDT 精度为 6 位
-	balance := new(big.Float).SetInt(balanceInt)
-	decimal := new(big.Float).Quo(balance, big.NewFloat(1e6))
+	debugf("Balance hex: %s, decimal: %s", balanceHex, balanceInt)
 
-	return decimal, nil
+	// 精度取自网络配置中的代币定义（USDT 为 6 位）
+	balance := amount.New(balanceInt, cfg.Profile.Decimals(usdtContract))
+	return &balance, nil
 }
 
 func main() {
-	address := "TM1zzNDZD2DPASbKcgdVoTYhfmYgtfwx9R"
-	balance, err := getTRC20Balance(address)
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		log.Fatal("查询失败:", err)
+		log.Fatal("配置错误:", err)
+	}
+	if len(cfg.Watch.Tokens) == 0 {
+		log.Fatal("未配置代币合约")
+	}
+
+	for _, address := range cfg.Watch.Addresses {
+		balance, err := getTRC20Balance(cfg, cfg.Watch.Tokens[0], address)
+		if err != nil {
+			log.Fatal("查询失败:", err)
+		}
+		fmt.Printf("地址 %s 的 USDT 余额为: %s\n", address, balance)
 	}
-	fmt.Printf("地址 %s 的 USDT 余额为: %s\n", address, balance.Text('f', 6))
 }
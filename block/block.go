@@ -2,13 +2,18 @@ package block
 
 import (
 	"fmt"
-	"github.com/fbsobreira/gotron-sdk/pkg/client"
 	"log"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/yourname/tron-demo/metrics"
 )
 
 // 获取当前区块高度
 func GetCurrentBlockNum(c *client.GrpcClient) (int64, error) {
+	start := time.Now()
 	latest, err := c.GetBlockByLatestNum(1)
+	metrics.ObserveGRPC("GetBlockByLatestNum", start, err)
 	if err != nil {
 		log.Fatalf("获取最新块失败: %v", err)
 	}
@@ -19,7 +24,9 @@ func GetCurrentBlockNum(c *client.GrpcClient) (int64, error) {
 
 // 根据num获取区块信息
 func GetBlockByNum(c *client.GrpcClient, num int64) {
+	start := time.Now()
 	block, err := c.GetBlockByNum(num)
+	metrics.ObserveGRPC("GetBlockByNum", start, err)
 	if err != nil {
 		log.Fatalf("获取块失败: %v", err)
 	}
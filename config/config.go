@@ -0,0 +1,474 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/network"
+	"github.com/yourname/tron-demo/risk"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the runtime configuration shared by all commands.
+//
+// Values are resolved with the following precedence (highest first):
+// command line flags, environment variables, the YAML config file, built-in defaults.
+// Secrets (API keys, RPC URLs that embed keys) are never read from the YAML
+// file itself; only a path to a file holding the secret may be configured there.
+//
+// Endpoints and watched tokens left empty are filled from the selected network profile.
+type Config struct {
+	Network      string `yaml:"network"`
+	GRPCEndpoint string `yaml:"grpc_endpoint"`
+	// SolidityEndpoint is the solidity node gRPC endpoint (WalletSolidity service)
+	SolidityEndpoint string         `yaml:"solidity_endpoint"`
+	TronGrid         TronGridConfig `yaml:"trongrid"`
+	Ethereum         EthereumConfig `yaml:"ethereum"`
+	// Tokens declares TRC20 tokens of the selected network that are not built
+	// into its profile, so their amounts can be scaled and screened for dust
+	Tokens  []TokenConfig `yaml:"tokens"`
+	Watch   WatchConfig   `yaml:"watch"`
+	Retry   RetryConfig   `yaml:"retry"`
+	Risk    RiskConfig    `yaml:"risk"`
+	Source  SourceConfig  `yaml:"source"`
+	Monitor MonitorConfig `yaml:"monitor"`
+	Archive ArchiveConfig `yaml:"archive"`
+	Sinks   string        `yaml:"sinks"`
+
+	// ListenAddr is the address of the embedded HTTP server (/metrics, /healthz, /readyz, /status); empty disables it
+	ListenAddr string       `yaml:"listen_addr"`
+	Health     HealthConfig `yaml:"health"`
+	// StateFile persists watcher cursors across restarts; empty disables persistence
+	StateFile string `yaml:"state_file"`
+	// ShutdownTimeout bounds how long a watcher may take to stop after SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// Profile is the resolved network profile, set by Load
+	Profile *network.Profile `yaml:"-"`
+}
+
+// TronGridConfig configures the TronGrid HTTP API
+type TronGridConfig struct {
+	BaseURL    string        `yaml:"base_url"`
+	APIKey     string        `yaml:"-"`
+	APIKeyFile string        `yaml:"api_key_file"`
+	Timeout    time.Duration `yaml:"timeout"`
+	PageSize   int           `yaml:"page_size"`
+}
+
+// EthereumConfig configures the Ethereum JSON-RPC endpoint used by the eth demo
+type EthereumConfig struct {
+	RPCURL     string `yaml:"-"`
+	RPCURLFile string `yaml:"rpc_url_file"`
+	Token      string `yaml:"token"`
+	Decimals   int32  `yaml:"decimals"`
+	Address    string `yaml:"address"`
+}
+
+// TokenConfig declares a TRC20 token and its decimals
+type TokenConfig struct {
+	Symbol   string `yaml:"symbol"`
+	Contract string `yaml:"contract"`
+	Decimals int32  `yaml:"decimals"`
+}
+
+// WatchConfig lists what the watchers follow and how often they poll
+type WatchConfig struct {
+	Tokens         []string      `yaml:"tokens"`
+	Addresses      []string      `yaml:"addresses"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	LookbackWindow time.Duration `yaml:"lookback_window"`
+}
+
+// RetryConfig controls backoff and the circuit breaker around TronGrid requests
+type RetryConfig struct {
+	InitialBackoff   time.Duration `yaml:"initial_backoff"`
+	MaxBackoff       time.Duration `yaml:"max_backoff"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+// Transfer sources selectable with SourceConfig.Kind
+const (
+	SourceTronGrid = "trongrid"
+	SourceNode     = "node"
+	SourceFailover = "failover"
+)
+
+// SourceConfig selects where gridwatcher reads transfers from
+type SourceConfig struct {
+	// Kind is trongrid (events API, time windows), node (full node receipts)
+	// or failover (TronGrid first, full node when TronGrid is unhealthy)
+	Kind string `yaml:"kind"`
+	// FailoverAfter is the number of consecutive failures before switching sources
+	FailoverAfter int `yaml:"failover_after"`
+	// RetryPrimary is how long to stay on the fallback before trying TronGrid again
+	RetryPrimary time.Duration `yaml:"retry_primary"`
+	// BatchBlocks is the number of blocks requested per call in node/failover mode
+	BatchBlocks int64 `yaml:"batch_blocks"`
+}
+
+// Block finality modes selectable with MonitorConfig.Finality
+const (
+	FinalityHead     = "head"
+	FinalityDepth    = "depth"
+	FinalitySolidity = "solidity"
+)
+
+// MonitorConfig configures the block monitor (the root command)
+type MonitorConfig struct {
+	// StateFile persists the last processed block number and hash; empty disables persistence
+	StateFile string `yaml:"state_file"`
+	// Finality selects the newest block processed: head (blocks that may still be
+	// reverted, events are published unconfirmed), depth (Confirmations blocks
+	// behind the head) or solidity (the solidity node's latest block)
+	Finality string `yaml:"finality"`
+	// Confirmations is the depth used by finality depth; 0 uses the network's solidify depth
+	Confirmations int64 `yaml:"confirmations"`
+	// Workers is the number of blocks fetched in parallel while catching up
+	Workers int `yaml:"workers"`
+	// ForkWindow is the number of recent blocks kept to roll back a chain reorganisation
+	ForkWindow int `yaml:"fork_window"`
+	// ABIDir holds contract ABIs as <contract address>.json used to decode logs
+	ABIDir string `yaml:"abi_dir"`
+	// FetchABI fetches the ABI of other contracts from the chain (GetContractABI)
+	FetchABI bool `yaml:"fetch_abi"`
+}
+
+// ArchiveConfig configures the local block archive
+type ArchiveConfig struct {
+	// Path is the SQLite file blocks, transactions, receipts and events are
+	// archived to; empty disables the archive
+	Path string `yaml:"path"`
+	// Contracts limits archived transactions to those involving these
+	// contracts; empty archives every transaction
+	Contracts []string `yaml:"contracts"`
+}
+
+// RiskConfig controls the address poisoning / dust spam detector
+type RiskConfig struct {
+	// DustThreshold flags non-zero transfers below this many tokens, e.g. "1"; "0" disables it
+	DustThreshold string `yaml:"dust_threshold"`
+	// LookalikePrefix and LookalikeSuffix are how many leading/trailing address
+	// characters must match a known counterparty to be flagged as a lookalike
+	LookalikePrefix int `yaml:"lookalike_prefix"`
+	LookalikeSuffix int `yaml:"lookalike_suffix"`
+	// Counterparties seeds the known counterparties of the watched addresses
+	Counterparties []string `yaml:"counterparties"`
+	// IncludeFlagged credits and lists flagged transfers (tagged with the risk reason)
+	// instead of excluding them
+	IncludeFlagged bool `yaml:"include_flagged"`
+}
+
+// Options converts the configuration into detector options
+func (r RiskConfig) Options() (risk.Options, error) {
+	threshold, err := amount.Parse(r.DustThreshold, maxDecimals)
+	if err != nil {
+		return risk.Options{}, fmt.Errorf("risk.dust_threshold: %w", err)
+	}
+	return risk.Options{
+		DustThreshold: threshold,
+		PrefixLen:     r.LookalikePrefix,
+		SuffixLen:     r.LookalikeSuffix,
+	}, nil
+}
+
+// Detector builds a risk detector for the watched addresses, seeded with the configured counterparties
+func (c *Config) Detector() (*risk.Detector, error) {
+	opts, err := c.Risk.Options()
+	if err != nil {
+		return nil, err
+	}
+	d := risk.NewDetector(opts, c.Watch.Addresses)
+	for _, a := range c.Risk.Counterparties {
+		d.Remember(a)
+	}
+	return d, nil
+}
+
+// maxDecimals is enough precision for any TRC20/ERC20 threshold
+const maxDecimals = 18
+
+// HealthConfig controls the /healthz and /readyz probes
+type HealthConfig struct {
+	// MaxLag fails readiness when the cursor is further behind the chain than this
+	MaxLag time.Duration `yaml:"max_lag"`
+	// StallTimeout fails liveness when the watcher loop has not made any attempt for this long
+	StallTimeout time.Duration `yaml:"stall_timeout"`
+}
+
+// Default returns the built-in defaults (TRON mainnet)
+func Default() *Config {
+	return &Config{
+		Network: network.Mainnet,
+		TronGrid: TronGridConfig{
+			Timeout:  10 * time.Second,
+			PageSize: 200,
+		},
+		Ethereum: EthereumConfig{
+			Token:    "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+			Decimals: 6,
+		},
+		Watch: WatchConfig{
+			PollInterval:   5 * time.Second,
+			LookbackWindow: 1 * time.Minute,
+		},
+		Retry: RetryConfig{
+			InitialBackoff:   500 * time.Millisecond,
+			MaxBackoff:       30 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  1 * time.Minute,
+		},
+		Source: SourceConfig{
+			Kind:          SourceTronGrid,
+			FailoverAfter: 3,
+			RetryPrimary:  5 * time.Minute,
+			BatchBlocks:   20,
+		},
+		Monitor: MonitorConfig{
+			Finality:   FinalityDepth,
+			Workers:    8,
+			ForkWindow: 64,
+			FetchABI:   true,
+		},
+		Risk: RiskConfig{
+			DustThreshold:   "1",
+			LookalikePrefix: 4,
+			LookalikeSuffix: 4,
+		},
+		Health: HealthConfig{
+			MaxLag:       5 * time.Minute,
+			StallTimeout: 10 * time.Minute,
+		},
+		Sinks:           "stdout",
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// LoadFile merges the YAML file at path into c
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	defer f.Close()
+
+	// 未知字段直接报错，避免把 api_key 之类的密钥写进配置文件后被静默忽略
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyProfile resolves the network profile and fills unset endpoints and tokens from it
+func (c *Config) applyProfile() error {
+	p, err := network.Get(c.Network)
+	if err != nil {
+		return err
+	}
+	if len(c.Tokens) > 0 {
+		tokens := make([]network.Token, len(c.Tokens))
+		for i, t := range c.Tokens {
+			tokens[i] = network.Token{Symbol: t.Symbol, Contract: t.Contract, Decimals: t.Decimals}
+		}
+		p = p.WithTokens(tokens...)
+	}
+	c.Profile = p
+	if c.GRPCEndpoint == "" {
+		c.GRPCEndpoint = p.GRPCEndpoint
+	}
+	if c.SolidityEndpoint == "" {
+		c.SolidityEndpoint = p.SolidityEndpoint
+	}
+	if c.Monitor.Confirmations == 0 {
+		c.Monitor.Confirmations = p.SolidifyDepth
+	}
+	if c.TronGrid.BaseURL == "" {
+		c.TronGrid.BaseURL = p.TronGridURL
+	}
+	if len(c.Watch.Tokens) == 0 {
+		c.Watch.Tokens = p.DefaultTokens()
+	}
+	return nil
+}
+
+// resolveSecrets reads secrets from their files when they were not given directly
+func (c *Config) resolveSecrets() error {
+	if c.TronGrid.APIKey == "" && c.TronGrid.APIKeyFile != "" {
+		v, err := readSecret(c.TronGrid.APIKeyFile)
+		if err != nil {
+			return fmt.Errorf("trongrid api key: %w", err)
+		}
+		c.TronGrid.APIKey = v
+	}
+	if c.Ethereum.RPCURL == "" && c.Ethereum.RPCURLFile != "" {
+		v, err := readSecret(c.Ethereum.RPCURLFile)
+		if err != nil {
+			return fmt.Errorf("ethereum rpc url: %w", err)
+		}
+		c.Ethereum.RPCURL = v
+	}
+	return nil
+}
+
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Validate checks the configuration and returns every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	if c.GRPCEndpoint == "" {
+		errs = append(errs, errors.New("grpc_endpoint is required"))
+	}
+	if c.Profile != nil {
+		// 配置文件里写死的节点不会被 -network 覆盖，属于其他网络时直接拒绝启动
+		for _, e := range []struct{ key, value string }{
+			{"grpc_endpoint", c.GRPCEndpoint},
+			{"solidity_endpoint", c.SolidityEndpoint},
+			{"trongrid.base_url", c.TronGrid.BaseURL},
+		} {
+			if err := c.Profile.ValidateEndpoint(e.value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.key, err))
+			}
+		}
+	}
+	if u, err := url.Parse(c.TronGrid.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("trongrid.base_url %q is not a valid URL", c.TronGrid.BaseURL))
+	}
+	if c.TronGrid.Timeout <= 0 {
+		errs = append(errs, errors.New("trongrid.timeout must be positive"))
+	}
+	if c.TronGrid.PageSize < 1 || c.TronGrid.PageSize > 200 {
+		errs = append(errs, fmt.Errorf("trongrid.page_size must be between 1 and 200, got %d", c.TronGrid.PageSize))
+	}
+	if c.Watch.PollInterval <= 0 {
+		errs = append(errs, errors.New("watch.poll_interval must be positive"))
+	}
+	if c.Watch.LookbackWindow <= 0 {
+		errs = append(errs, errors.New("watch.lookback_window must be positive"))
+	}
+	if c.Retry.InitialBackoff <= 0 || c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+		errs = append(errs, errors.New("retry.initial_backoff must be positive and not above retry.max_backoff"))
+	}
+	if c.Retry.BreakerThreshold < 1 || c.Retry.BreakerCooldown <= 0 {
+		errs = append(errs, errors.New("retry.breaker_threshold and retry.breaker_cooldown must be positive"))
+	}
+	if c.Health.MaxLag <= 0 || c.Health.StallTimeout <= 0 {
+		errs = append(errs, errors.New("health.max_lag and health.stall_timeout must be positive"))
+	}
+	switch c.Source.Kind {
+	case SourceTronGrid, SourceNode, SourceFailover:
+	default:
+		errs = append(errs, fmt.Errorf("source.kind must be %s, %s or %s, got %q", SourceTronGrid, SourceNode, SourceFailover, c.Source.Kind))
+	}
+	if c.Source.FailoverAfter < 1 || c.Source.BatchBlocks < 1 {
+		errs = append(errs, errors.New("source.failover_after and source.batch_blocks must be positive"))
+	}
+	switch c.Monitor.Finality {
+	case FinalityHead, FinalityDepth:
+	case FinalitySolidity:
+		if c.SolidityEndpoint == "" {
+			errs = append(errs, errors.New("monitor.finality solidity requires solidity_endpoint"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("monitor.finality must be %s, %s or %s, got %q", FinalityHead, FinalityDepth, FinalitySolidity, c.Monitor.Finality))
+	}
+	if c.Monitor.Confirmations < 0 || c.Monitor.Workers < 1 || c.Monitor.ForkWindow < 1 {
+		errs = append(errs, errors.New("monitor.confirmations must not be negative, monitor.workers and monitor.fork_window must be positive"))
+	}
+	if _, err := c.Risk.Options(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Risk.LookalikePrefix < 0 || c.Risk.LookalikeSuffix < 0 {
+		errs = append(errs, errors.New("risk.lookalike_prefix and risk.lookalike_suffix must not be negative"))
+	}
+	for _, a := range c.Risk.Counterparties {
+		if err := validateAddress(a); err != nil {
+			errs = append(errs, fmt.Errorf("risk.counterparties: %w", err))
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.Profile == nil {
+		errs = append(errs, fmt.Errorf("network %q is not resolved", c.Network))
+	}
+	for _, t := range c.Tokens {
+		if err := validateAddress(t.Contract); err != nil {
+			errs = append(errs, fmt.Errorf("tokens: %w", err))
+		} else if c.Profile != nil {
+			if err := c.Profile.ValidateContract(t.Contract); err != nil {
+				errs = append(errs, fmt.Errorf("tokens: %w", err))
+			}
+		}
+		if t.Decimals < 0 || t.Decimals > maxDecimals {
+			errs = append(errs, fmt.Errorf("tokens: decimals of %s must be between 0 and %d, got %d", t.Contract, maxDecimals, t.Decimals))
+		}
+	}
+	for _, t := range c.Watch.Tokens {
+		if err := validateAddress(t); err != nil {
+			errs = append(errs, fmt.Errorf("watch.tokens: %w", err))
+		} else if c.Profile != nil {
+			// 只入账本网络的已知代币（内置或在 tokens 中声明，精度随之确定，粉尘阈值才有意义）
+			if err := c.Profile.ValidateToken(t); err != nil {
+				errs = append(errs, fmt.Errorf("watch.tokens: %w; declare it under tokens", err))
+			}
+		}
+	}
+	for _, a := range c.Watch.Addresses {
+		if err := validateAddress(a); err != nil {
+			errs = append(errs, fmt.Errorf("watch.addresses: %w", err))
+		} else if c.Profile != nil {
+			if err := c.Profile.ValidateAddress(a); err != nil {
+				errs = append(errs, fmt.Errorf("watch.addresses: %w", err))
+			}
+		}
+	}
+	for _, a := range c.Archive.Contracts {
+		if err := validateAddress(a); err != nil {
+			errs = append(errs, fmt.Errorf("archive.contracts: %w", err))
+		} else if c.Profile != nil {
+			if err := c.Profile.ValidateContract(a); err != nil {
+				errs = append(errs, fmt.Errorf("archive.contracts: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RequireTronGridKey reports an error when no TronGrid API key is configured
+func (c *Config) RequireTronGridKey() error {
+	if c.TronGrid.APIKey == "" {
+		return fmt.Errorf("trongrid api key is empty: set %s or %s", EnvTronGridAPIKey, EnvTronGridAPIKeyFile)
+	}
+	return nil
+}
+
+// RequireEthereumRPC reports an error when no Ethereum RPC URL is configured
+func (c *Config) RequireEthereumRPC() error {
+	if c.Ethereum.RPCURL == "" {
+		return fmt.Errorf("ethereum rpc url is empty: set %s or %s", EnvEthRPCURL, EnvEthRPCURLFile)
+	}
+	return nil
+}
+
+func validateAddress(s string) error {
+	a, err := address.Base58ToAddress(s)
+	if err != nil || !a.IsValid() {
+		return fmt.Errorf("invalid TRON address %q", s)
+	}
+	return nil
+}
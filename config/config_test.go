@@ -0,0 +1,189 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourname/tron-demo/network"
+)
+
+const customToken = "TUpMhErZL2fhh4sVNULAbNKLokS4GjC1F4"
+
+// resolved returns the defaults for network after applying edit, resolved like Load does
+func resolved(t *testing.T, name string, edit func(c *Config)) *Config {
+	t.Helper()
+	c := Default()
+	c.Network = name
+	if edit != nil {
+		edit(c)
+	}
+	if err := c.applyProfile(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestValidateDefaults(t *testing.T) {
+	for _, name := range network.Names() {
+		if err := resolved(t, name, nil).Validate(); err != nil {
+			t.Errorf("%s defaults: %v", name, err)
+		}
+	}
+}
+
+func TestValidateTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(c *Config)
+		wantErr string
+	}{
+		{
+			name: "built-in token",
+			edit: func(c *Config) { c.Watch.Tokens = []string{"TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"} },
+		},
+		{
+			name:    "unknown contract",
+			edit:    func(c *Config) { c.Watch.Tokens = []string{customToken} },
+			wantErr: customToken + " is not a known mainnet token",
+		},
+		{
+			name: "unknown contract without dust check",
+			edit: func(c *Config) {
+				c.Risk.DustThreshold = "0"
+				c.Watch.Tokens = []string{customToken}
+			},
+			wantErr: customToken + " is not a known mainnet token",
+		},
+		{
+			name:    "token of another network",
+			edit:    func(c *Config) { c.Watch.Tokens = []string{"TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"} },
+			wantErr: "is USDT on nile",
+		},
+		{
+			name: "declared token",
+			edit: func(c *Config) {
+				c.Tokens = []TokenConfig{{Symbol: "TUSD", Contract: customToken, Decimals: 18}}
+				c.Watch.Tokens = []string{customToken}
+			},
+		},
+		{
+			name: "declared token with invalid decimals",
+			edit: func(c *Config) {
+				c.Tokens = []TokenConfig{{Symbol: "TUSD", Contract: customToken, Decimals: 19}}
+			},
+			wantErr: "must be between 0 and 18",
+		},
+		{
+			name: "declared token of another network",
+			edit: func(c *Config) {
+				c.Tokens = []TokenConfig{{Symbol: "USDT", Contract: "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf", Decimals: 6}}
+			},
+			wantErr: "is USDT on nile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved(t, network.Mainnet, tt.edit).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeclaredTokenDecimals(t *testing.T) {
+	c := resolved(t, network.Mainnet, func(c *Config) {
+		c.Tokens = []TokenConfig{{Symbol: "TUSD", Contract: customToken, Decimals: 18}}
+	})
+	if d, ok := c.Profile.TokenDecimals(customToken); !ok || d != 18 {
+		t.Errorf("TokenDecimals = %d, %v", d, ok)
+	}
+	// 声明的代币不改动内置的网络配置
+	p, _ := network.Get(network.Mainnet)
+	if _, ok := p.TokenDecimals(customToken); ok {
+		t.Error("declared token leaked into the built-in profile")
+	}
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(c *Config)
+		wantErr string
+	}{
+		{"profile endpoints", nil, ""},
+		{"private node", func(c *Config) { c.GRPCEndpoint = "my-node:50051" }, ""},
+		{"mainnet grpc", func(c *Config) { c.GRPCEndpoint = "grpc.trongrid.io:50051" }, "grpc_endpoint: endpoint grpc.trongrid.io:50051 belongs to mainnet"},
+		{"mainnet solidity", func(c *Config) { c.SolidityEndpoint = "grpc.trongrid.io:50052" }, "solidity_endpoint"},
+		{"mainnet trongrid", func(c *Config) { c.TronGrid.BaseURL = "https://api.trongrid.io/" }, "trongrid.base_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved(t, network.Nile, tt.edit).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 示例配置切换到测试网时不能仍然指向主网
+func TestExampleConfigOnTestnet(t *testing.T) {
+	c := Default()
+	if err := c.LoadFile("../config.example.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	c.Network = network.Nile
+	c.Watch.Tokens = nil
+	c.Watch.Addresses = nil
+	if err := c.applyProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	p, _ := network.Get(network.Nile)
+	if c.GRPCEndpoint != p.GRPCEndpoint || c.TronGrid.BaseURL != p.TronGridURL {
+		t.Errorf("endpoints %s %s, want the nile profile", c.GRPCEndpoint, c.TronGrid.BaseURL)
+	}
+}
+
+func TestValidateArchiveContracts(t *testing.T) {
+	tests := []struct {
+		name      string
+		contracts []string
+		wantErr   string
+	}{
+		{"empty", nil, ""},
+		{"any contract", []string{customToken}, ""},
+		{"not an address", []string{"0xdAC17F958D2ee523a2206206994597C13D831ec7"}, "archive.contracts: invalid TRON address"},
+		{"bad checksum", []string{"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u"}, "archive.contracts: invalid TRON address"},
+		{"token of another network", []string{"TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"}, "archive.contracts: contract TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf is USDT on nile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved(t, network.Mainnet, func(c *Config) { c.Archive.Contracts = tt.contracts }).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
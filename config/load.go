@@ -0,0 +1,250 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourname/tron-demo/network"
+)
+
+// Environment variables understood by Load
+const (
+	EnvConfigFile         = "TRON_CONFIG"
+	EnvNetwork            = "TRON_NETWORK"
+	EnvGRPCEndpoint       = "TRON_GRPC_ENDPOINT"
+	EnvSolidityEndpoint   = "TRON_SOLIDITY_ENDPOINT"
+	EnvTronGridBaseURL    = "TRONGRID_BASE_URL"
+	EnvTronGridAPIKey     = "TRONGRID_API_KEY"
+	EnvTronGridAPIKeyFile = "TRONGRID_API_KEY_FILE"
+	EnvWatchTokens        = "TRON_WATCH_TOKENS"
+	EnvWatchAddresses     = "TRON_WATCH_ADDRESSES"
+	EnvPollInterval       = "TRON_POLL_INTERVAL"
+	EnvLookbackWindow     = "TRON_LOOKBACK_WINDOW"
+	EnvSinks              = "TRON_SINKS"
+	EnvStateFile          = "TRON_STATE_FILE"
+	EnvListenAddr         = "TRON_LISTEN_ADDR"
+	EnvMaxLag             = "TRON_MAX_LAG"
+	EnvShutdownTimeout    = "TRON_SHUTDOWN_TIMEOUT"
+	EnvSource             = "TRON_SOURCE"
+	EnvDustThreshold      = "TRON_DUST_THRESHOLD"
+	EnvIncludeFlagged     = "TRON_INCLUDE_FLAGGED"
+	EnvMonitorStateFile   = "TRON_MONITOR_STATE_FILE"
+	EnvFinality           = "TRON_FINALITY"
+	EnvMonitorWorkers     = "TRON_MONITOR_WORKERS"
+	EnvArchive            = "TRON_ARCHIVE"
+	EnvArchiveContracts   = "TRON_ARCHIVE_CONTRACTS"
+	EnvEthRPCURL          = "ETH_RPC_URL"
+	EnvEthRPCURLFile      = "ETH_RPC_URL_FILE"
+	EnvEthToken           = "ETH_TOKEN"
+	EnvEthAddress         = "ETH_ADDRESS"
+)
+
+// option binds one setting to its environment variable and, optionally, a flag.
+// Secrets have no flag so they never show up in the process list.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func options() []option {
+	return []option{
+		{"network", EnvNetwork, "network profile: " + strings.Join(network.Names(), ", "), func(c *Config, v string) error {
+			c.Network = v
+			return nil
+		}},
+		{"grpc", EnvGRPCEndpoint, "TRON full node gRPC endpoint", func(c *Config, v string) error {
+			c.GRPCEndpoint = v
+			return nil
+		}},
+		{"solidity-grpc", EnvSolidityEndpoint, "TRON solidity node gRPC endpoint", func(c *Config, v string) error {
+			c.SolidityEndpoint = v
+			return nil
+		}},
+		{"trongrid-url", EnvTronGridBaseURL, "TronGrid base URL", func(c *Config, v string) error {
+			c.TronGrid.BaseURL = v
+			return nil
+		}},
+		{"", EnvTronGridAPIKey, "", func(c *Config, v string) error {
+			c.TronGrid.APIKey = v
+			return nil
+		}},
+		{"trongrid-key-file", EnvTronGridAPIKeyFile, "file containing the TronGrid API key", func(c *Config, v string) error {
+			c.TronGrid.APIKeyFile = v
+			return nil
+		}},
+		{"tokens", EnvWatchTokens, "comma separated TRC20 contracts to watch", func(c *Config, v string) error {
+			c.Watch.Tokens = splitList(v)
+			return nil
+		}},
+		{"addresses", EnvWatchAddresses, "comma separated addresses to watch", func(c *Config, v string) error {
+			c.Watch.Addresses = splitList(v)
+			return nil
+		}},
+		{"poll-interval", EnvPollInterval, "interval between polls", func(c *Config, v string) error {
+			return setDuration(&c.Watch.PollInterval, v)
+		}},
+		{"lookback", EnvLookbackWindow, "time window to look back on every poll", func(c *Config, v string) error {
+			return setDuration(&c.Watch.LookbackWindow, v)
+		}},
+		{"sink", EnvSinks, "comma separated event sinks: stdout, file:<path>, discard", func(c *Config, v string) error {
+			c.Sinks = v
+			return nil
+		}},
+		{"listen", EnvListenAddr, "address of the embedded HTTP server serving /metrics and health probes, e.g. :9102", func(c *Config, v string) error {
+			c.ListenAddr = v
+			return nil
+		}},
+		{"max-lag", EnvMaxLag, "readiness fails when the cursor lags the chain by more than this", func(c *Config, v string) error {
+			return setDuration(&c.Health.MaxLag, v)
+		}},
+		{"state", EnvStateFile, "file persisting watcher cursors across restarts", func(c *Config, v string) error {
+			c.StateFile = v
+			return nil
+		}},
+		{"shutdown-timeout", EnvShutdownTimeout, "deadline for a clean shutdown after SIGINT/SIGTERM", func(c *Config, v string) error {
+			return setDuration(&c.ShutdownTimeout, v)
+		}},
+		{"source", EnvSource, "transfer source: trongrid, node or failover", func(c *Config, v string) error {
+			c.Source.Kind = v
+			return nil
+		}},
+		{"dust-threshold", EnvDustThreshold, "flag non-zero transfers below this many tokens as dust; 0 disables", func(c *Config, v string) error {
+			c.Risk.DustThreshold = v
+			return nil
+		}},
+		{"include-flagged", EnvIncludeFlagged, "credit and list transfers flagged as dust/poisoning instead of excluding them (true/false)", func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", v)
+			}
+			c.Risk.IncludeFlagged = b
+			return nil
+		}},
+		{"monitor-state", EnvMonitorStateFile, "file persisting the block monitor's last processed block", func(c *Config, v string) error {
+			c.Monitor.StateFile = v
+			return nil
+		}},
+		{"finality", EnvFinality, "newest block the monitor processes: head, depth or solidity", func(c *Config, v string) error {
+			c.Monitor.Finality = v
+			return nil
+		}},
+		{"workers", EnvMonitorWorkers, "blocks the monitor fetches in parallel while catching up", func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", v)
+			}
+			c.Monitor.Workers = n
+			return nil
+		}},
+		{"archive", EnvArchive, "SQLite file archiving processed blocks, transactions and events", func(c *Config, v string) error {
+			c.Archive.Path = v
+			return nil
+		}},
+		{"archive-contracts", EnvArchiveContracts, "comma separated contracts whose transactions are archived (default: all)", func(c *Config, v string) error {
+			c.Archive.Contracts = splitList(v)
+			return nil
+		}},
+		{"", EnvEthRPCURL, "", func(c *Config, v string) error {
+			c.Ethereum.RPCURL = v
+			return nil
+		}},
+		{"eth-rpc-file", EnvEthRPCURLFile, "file containing the Ethereum RPC URL", func(c *Config, v string) error {
+			c.Ethereum.RPCURLFile = v
+			return nil
+		}},
+		{"eth-token", EnvEthToken, "ERC20 token contract", func(c *Config, v string) error {
+			c.Ethereum.Token = v
+			return nil
+		}},
+		{"eth-address", EnvEthAddress, "Ethereum address to watch", func(c *Config, v string) error {
+			c.Ethereum.Address = v
+			return nil
+		}},
+	}
+}
+
+// Load registers the shared flags on fs, parses args and returns the
+// validated configuration. Commands register their own flags on fs first.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	opts := options()
+	configPath := fs.String("config", os.Getenv(EnvConfigFile), "path to a YAML config file (env "+EnvConfigFile+")")
+	flagValues := make(map[string]*string, len(opts))
+	for _, o := range opts {
+		if o.flag == "" {
+			continue
+		}
+		flagValues[o.flag] = fs.String(o.flag, "", fmt.Sprintf("%s (env %s)", o.usage, o.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *configPath != "" {
+		if err := c.LoadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, o := range opts {
+		if v, ok := os.LookupEnv(o.env); ok && v != "" {
+			if err := o.set(c, v); err != nil {
+				return nil, fmt.Errorf("env %s: %w", o.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		v, ok := flagValues[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		for _, o := range opts {
+			if o.flag == f.Name {
+				if err := o.set(c, *v); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := c.applyProfile(); err != nil {
+		return nil, err
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, nil
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+sinks: file:from-file.jsonl
+trongrid:
+  base_url: https://trongrid.example.com
+  page_size: 50
+watch:
+  poll_interval: 20s
+  lookback_window: 2m
+`)
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv(EnvPollInterval, "30s")
+	t.Setenv(EnvSinks, "discard")
+	t.Setenv(EnvTronGridBaseURL, "")
+
+	c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-sink", "stdout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over env and file", c.Sinks, "stdout"},
+		{"env over file", c.Watch.PollInterval, 30 * time.Second},
+		{"file over default", c.Watch.LookbackWindow, 2 * time.Minute},
+		{"empty env keeps file", c.TronGrid.BaseURL, "https://trongrid.example.com"},
+		{"file keeps unrelated defaults", c.TronGrid.Timeout, Default().TronGrid.Timeout},
+		{"file value", c.TronGrid.PageSize, 50},
+		{"default", c.Network, Default().Network},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFlagOverridesEnv(t *testing.T) {
+	t.Setenv(EnvConfigFile, writeFile(t, "env.yaml", "sinks: discard\n"))
+	path := writeFile(t, "flag.yaml", "sinks: file:events.jsonl\n")
+	c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sinks != "file:events.jsonl" {
+		t.Errorf("Sinks = %q, want the file given with -config", c.Sinks)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	keyFile := writeFile(t, "key", "from-file\n")
+	t.Setenv(EnvTronGridAPIKeyFile, keyFile)
+
+	c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.TronGrid.APIKey != "from-file" {
+		t.Errorf("APIKey = %q, want the trimmed file content", c.TronGrid.APIKey)
+	}
+
+	// 直接给出的密钥优先于密钥文件
+	t.Setenv(EnvTronGridAPIKey, "from-env")
+	if c, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.TronGrid.APIKey != "from-env" {
+		t.Errorf("APIKey = %q, want the env value", c.TronGrid.APIKey)
+	}
+}
+
+func TestLoadRejectsSecretsInFile(t *testing.T) {
+	t.Setenv(EnvConfigFile, writeFile(t, "config.yaml", "trongrid:\n  api_key: secret\n"))
+	if _, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil); err == nil {
+		t.Fatal("api_key in the config file accepted")
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv(EnvPollInterval, "soon")
+	if _, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil); err == nil {
+		t.Fatal("invalid duration accepted")
+	}
+}
+
+func TestLoadConfirmations(t *testing.T) {
+	tests := []struct {
+		file string
+		want int64 // -1: 网络的固化深度
+	}{
+		{"monitor:\n  finality: depth\n", -1},
+		{"monitor:\n  finality: depth\n  confirmations: 0\n", -1},
+		{"monitor:\n  finality: depth\n  confirmations: 5\n", 5},
+	}
+	for _, tt := range tests {
+		t.Setenv(EnvConfigFile, writeFile(t, "config.yaml", tt.file))
+		c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-network", "nile"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tt.want
+		if want < 0 {
+			want = c.Profile.SolidifyDepth
+		}
+		if c.Monitor.Finality != FinalityDepth || c.Monitor.Confirmations != want || want == 0 {
+			t.Errorf("%q: finality %s, confirmations %d, want depth %d", tt.file, c.Monitor.Finality, c.Monitor.Confirmations, want)
+		}
+	}
+}
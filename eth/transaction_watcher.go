@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
+	"os"
 	"strings"
 
 	"github.com/ethereum/go-ethereum"
@@ -13,23 +15,31 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
-)
-
-const (
-	infuraURL     = "https://mainnet.infura.io/v3/17765d4ea21943b09d2b02370131ac3a" // 替换为你的
-	usdtAddress   = "0xdAC17F958D2ee523a2206206994597C13D831ec7"                    // ERC20 USDT
-	targetAddress = "0xc8Fb0Ec6C8331cE5e014a34E7e2adc85BC9C701A"                    // 替换为你监听的地址
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/config"
 )
 
 func main() {
-	client, err := ethclient.Dial(infuraURL)
+	// RPC 地址包含密钥，只能通过 ETH_RPC_URL 或 ETH_RPC_URL_FILE 提供
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.RequireEthereumRPC(); err != nil {
+		log.Fatal(err)
+	}
+	if !common.IsHexAddress(cfg.Ethereum.Token) || !common.IsHexAddress(cfg.Ethereum.Address) {
+		log.Fatalf("Invalid ethereum token %q or address %q", cfg.Ethereum.Token, cfg.Ethereum.Address)
+	}
+
+	client, err := ethclient.Dial(cfg.Ethereum.RPCURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to Infura: %v", err)
+		log.Fatalf("Failed to connect to RPC: %v", err)
 	}
 	defer client.Close()
 
-	contractAddress := common.HexToAddress(usdtAddress)
-	toAddress := common.HexToAddress(targetAddress)
+	contractAddress := common.HexToAddress(cfg.Ethereum.Token) // ERC20 USDT
+	toAddress := common.HexToAddress(cfg.Ethereum.Address)     // 监听的地址
 
 	// ERC20 Transfer(address indexed from, address indexed to, uint256 value)
 	transferEventSig := []byte("Transfer(address,address,uint256)")
@@ -72,7 +82,7 @@ func main() {
 		fmt.Printf("✅ 收到转账:\n")
 		fmt.Printf("From: %s\n", transferEvent.From.Hex())
 		fmt.Printf("To:   %s\n", transferEvent.To.Hex())
-		fmt.Printf("Amount: %s USDT (raw: %s)\n", new(big.Float).Quo(new(big.Float).SetInt(transferEvent.Value), big.NewFloat(1e6)).Text('f', 6), transferEvent.Value.String())
+		fmt.Printf("Amount: %s USDT (raw: %s)\n", amount.New(transferEvent.Value, cfg.Ethereum.Decimals), transferEvent.Value.String())
 		fmt.Printf("TxHash: %s\n\n", vLog.TxHash.Hex())
 	}
 }
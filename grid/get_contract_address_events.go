@@ -1,20 +1,39 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+
+	"github.com/yourname/tron-demo/config"
 )
 
 func main() {
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(cfg.Watch.Tokens) == 0 {
+		log.Fatal("no token contract configured")
+	}
 
-	url := "https://api.trongrid.io/v1/contracts/TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t/events"
+	url := strings.TrimRight(cfg.TronGrid.BaseURL, "/") + "/v1/contracts/" + cfg.Watch.Tokens[0] + "/events"
 
 	req, _ := http.NewRequest("GET", url, nil)
 
 	req.Header.Add("accept", "application/json")
-
-	res, _ := http.DefaultClient.Do(req)
+	if cfg.TronGrid.APIKey != "" {
+		req.Header.Add("TRON-PRO-API-KEY", cfg.TronGrid.APIKey)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	defer res.Body.Close()
 	body, _ := io.ReadAll(res.Body)
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/statefile"
+)
+
+// orderByAsc 回补按时间正序翻页，触到分页深度上限时已处理的部分是一个连续前缀
+const orderByAsc = "block_timestamp,asc"
+
+// backfillState is the progress of one backfill run, checkpointed after every page
+type backfillState struct {
+	Start  int64    `json:"start"`
+	End    int64    `json:"end"`
+	Tokens []string `json:"tokens"`
+	// Pending holds the windows not finished yet, including the page position of started ones
+	Pending []*cursor `json:"pending"`
+	// Done counts finished windows; Splits counts windows shrunk because of the pagination depth limit
+	Done   int `json:"done"`
+	Splits int `json:"splits"`
+}
+
+// backfiller re-scans [Start, End] by splitting it into sub-windows that are
+// fetched concurrently. Events go through the live poller's handleEvents, so
+// published events carry the same idempotency keys as the live loop.
+type backfiller struct {
+	p        *poller
+	path     string
+	workers  int
+	maxPages int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	state   *backfillState
+	claimed map[*cursor]bool
+	active  int
+	err     error
+}
+
+// newBackfiller resumes the progress at path when it covers the same range and
+// tokens, otherwise starts a new run split into windows of the given size.
+// A zero end reuses the end of the saved progress, or now for a new run, so
+// rerunning without -backfill-to resumes instead of starting another range.
+func newBackfiller(p *poller, path string, start, end time.Time, window time.Duration, workers, maxPages int) (*backfiller, error) {
+	if window <= 0 || workers < 1 || maxPages < 1 {
+		return nil, errors.New("backfill window, workers and max pages must be positive")
+	}
+
+	st, err := loadBackfillState(path)
+	if err != nil {
+		return nil, err
+	}
+	if end.IsZero() {
+		end = time.Now()
+		if st != nil {
+			end = time.UnixMilli(st.End)
+		}
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("backfill start %s is not before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	startMs, endMs := start.UnixMilli(), end.UnixMilli()
+	if st != nil {
+		if st.Start != startMs || st.End != endMs || !slices.Equal(st.Tokens, p.cfg.Watch.Tokens) {
+			return nil, fmt.Errorf("backfill progress %s belongs to another range or token set; remove it or pass another -backfill-state", path)
+		}
+		log.Printf("[backfill] Resuming from %s: %d windows done, %d pending", path, st.Done, len(st.Pending))
+	} else {
+		st = &backfillState{Start: startMs, End: endMs, Tokens: p.cfg.Watch.Tokens}
+		for _, contract := range p.cfg.Watch.Tokens {
+			for from := startMs; from <= endMs; from += window.Milliseconds() {
+				to := min(from+window.Milliseconds()-1, endMs)
+				st.Pending = append(st.Pending, &cursor{Contract: contract, MinTs: from, MaxTs: to})
+			}
+		}
+	}
+
+	b := &backfiller{
+		p:        p,
+		path:     path,
+		workers:  workers,
+		maxPages: maxPages,
+		state:    st,
+		claimed:  make(map[*cursor]bool),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b, nil
+}
+
+func loadBackfillState(path string) (*backfillState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read backfill progress %s: %w", path, err)
+	}
+	var st backfillState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parse backfill progress %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// Run processes all pending windows with at most b.workers in parallel.
+// Cancelling ctx lets every worker finish its page in flight, checkpoints and returns nil.
+func (b *backfiller) Run(ctx context.Context) error {
+	log.Printf("[backfill] %s to %s: %d windows pending, %d workers",
+		time.UnixMilli(b.state.Start).Format(time.RFC3339), time.UnixMilli(b.state.End).Format(time.RFC3339),
+		len(b.state.Pending), b.workers)
+
+	// 取消时唤醒等待任务的 worker
+	stop := context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				cur := b.next(ctx)
+				if cur == nil {
+					return
+				}
+				err := b.process(ctx, cur)
+				b.release(cur, err)
+			}
+		}()
+	}
+	wg.Wait()
+	b.checkpoint()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.state.Pending) > 0 {
+		log.Printf("[backfill] Stopped with %d windows pending; rerun with the same range to resume", len(b.state.Pending))
+		return nil
+	}
+	log.Printf("[backfill] Completed: %d windows, %d split because of the pagination depth limit", b.state.Done, b.state.Splits)
+	return nil
+}
+
+// next claims an unclaimed pending window, waiting while other workers may still
+// split theirs; it returns nil when everything is done, a worker failed or ctx is cancelled
+func (b *backfiller) next(ctx context.Context) *cursor {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if ctx.Err() != nil || b.err != nil {
+			return nil
+		}
+		for _, cur := range b.state.Pending {
+			if !b.claimed[cur] {
+				b.claimed[cur] = true
+				b.active++
+				return cur
+			}
+		}
+		if b.active == 0 {
+			return nil
+		}
+		b.cond.Wait()
+	}
+}
+
+// release returns a window to the scheduler after process
+func (b *backfiller) release(cur *cursor, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.claimed, cur)
+	b.active--
+	if err != nil && !errors.Is(err, context.Canceled) && b.err == nil {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
+
+// process pages through one window in ascending order; when the window is too
+// deep to paginate the unprocessed remainder is split in two and re-queued
+func (b *backfiller) process(ctx context.Context, cur *cursor) error {
+	inflight := context.WithoutCancel(ctx)
+	for failures := 0; ; {
+		r, err := b.p.fetchPage(ctx, inflight, cur, orderByAsc)
+		if err != nil {
+			var fe *FetchError
+			// TronGrid 拒绝过深的 fingerprint 翻页（400），缩小窗口重来
+			if cur.Page > 0 && errors.As(err, &fe) && fe.StatusCode == http.StatusBadRequest {
+				return b.split(cur)
+			}
+			return err
+		}
+
+		matched, err := b.p.handleEvents(inflight, cur.Contract, r.Data)
+		if err != nil {
+			if err := b.p.retryPublish(ctx, failures, err); err != nil {
+				return err
+			}
+			failures++
+			continue
+		}
+		failures = 0
+		b.p.stats.fetchSucceeded(len(r.Data), matched)
+		metrics.PagesFetched.WithLabelValues(cur.Contract).Inc()
+		metrics.EventsProcessed.WithLabelValues(EventSource, cur.Contract).Add(float64(len(r.Data)))
+		metrics.EventsMatched.WithLabelValues(EventSource, cur.Contract).Add(float64(matched))
+
+		b.mu.Lock()
+		for _, ev := range r.Data {
+			cur.MaxBlock = max(cur.MaxBlock, ev.BlockNumber)
+			cur.LastTs = max(cur.LastTs, ev.BlockTimestamp)
+		}
+		cur.Fingerprint = r.Meta.Fingerprint
+		cur.Page++
+		b.mu.Unlock()
+
+		if r.Meta.Fingerprint == "" || len(r.Data) == 0 {
+			b.finish(cur)
+			return nil
+		}
+		if cur.Page >= b.maxPages {
+			return b.split(cur)
+		}
+		b.checkpoint()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// finish removes a completed window from the pending list
+func (b *backfiller) finish(cur *cursor) {
+	b.mu.Lock()
+	b.state.Pending = slices.DeleteFunc(b.state.Pending, func(c *cursor) bool { return c == cur })
+	b.state.Done++
+	done, pending := b.state.Done, len(b.state.Pending)
+	b.mu.Unlock()
+
+	log.Printf("[backfill] Window %s %s..%s done after %d pages (done=%d pending=%d)", cur.Contract,
+		time.UnixMilli(cur.MinTs).Format(time.RFC3339), time.UnixMilli(cur.MaxTs).Format(time.RFC3339), cur.Page, done, pending)
+	b.checkpoint()
+}
+
+// split replaces cur by two halves of its unprocessed remainder. Events at the
+// boundary timestamp are fetched again; republishing them is harmless because
+// events are keyed by txid#event_index.
+func (b *backfiller) split(cur *cursor) error {
+	from := cur.MinTs
+	if cur.LastTs > from {
+		from = cur.LastTs
+	}
+	if from >= cur.MaxTs {
+		return fmt.Errorf("window %s at %s holds more than %d pages of events in a single millisecond",
+			cur.Contract, time.UnixMilli(from).Format(time.RFC3339Nano), b.maxPages)
+	}
+	mid := from + (cur.MaxTs-from)/2
+	left := &cursor{Contract: cur.Contract, MinTs: from, MaxTs: mid}
+	right := &cursor{Contract: cur.Contract, MinTs: mid + 1, MaxTs: cur.MaxTs}
+
+	b.mu.Lock()
+	i := slices.Index(b.state.Pending, cur)
+	b.state.Pending = slices.Replace(b.state.Pending, i, i+1, left, right)
+	b.state.Splits++
+	b.mu.Unlock()
+
+	log.Printf("[backfill] Window %s %s..%s exceeds the pagination depth after %d pages, split at %s", cur.Contract,
+		time.UnixMilli(cur.MinTs).Format(time.RFC3339), time.UnixMilli(cur.MaxTs).Format(time.RFC3339), cur.Page,
+		time.UnixMilli(mid).Format(time.RFC3339))
+	b.checkpoint()
+	return nil
+}
+
+// checkpoint persists the progress; failures are logged because the in-memory progress is still valid
+func (b *backfiller) checkpoint() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := statefile.WriteJSON(b.path, b.state); err != nil {
+		log.Printf("[backfill] Failed to save progress to %s: %v", b.path, err)
+	}
+}
+
+// parseTime accepts RFC3339, a date (2006-01-02, UTC) or unix milliseconds
+func parseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339, YYYY-MM-DD or unix milliseconds", s)
+}
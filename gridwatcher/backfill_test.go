@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/tron-demo/config"
+)
+
+const testToken = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+func testBackfillPoller() *poller {
+	cfg := config.Default()
+	cfg.Watch.Tokens = []string{testToken}
+	return &poller{cfg: cfg}
+}
+
+func TestNewBackfillerWindows(t *testing.T) {
+	start := time.UnixMilli(0)
+	tests := []struct {
+		name   string
+		end    time.Time
+		window time.Duration
+		want   [][2]int64
+	}{
+		{"exact", time.UnixMilli(2 * 3600_000), time.Hour, [][2]int64{{0, 3599_999}, {3600_000, 7199_999}, {7200_000, 7200_000}}},
+		{"partial last window", time.UnixMilli(5400_000), time.Hour, [][2]int64{{0, 3599_999}, {3600_000, 5400_000}}},
+		{"window larger than range", time.UnixMilli(1000), time.Hour, [][2]int64{{0, 1000}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "backfill.json")
+			b, err := newBackfiller(testBackfillPoller(), path, start, tt.end, tt.window, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b.state.Pending) != len(tt.want) {
+				t.Fatalf("got %d windows, want %d", len(b.state.Pending), len(tt.want))
+			}
+			for i, cur := range b.state.Pending {
+				if cur.MinTs != tt.want[i][0] || cur.MaxTs != tt.want[i][1] {
+					t.Errorf("window %d = %d..%d, want %d..%d", i, cur.MinTs, cur.MaxTs, tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func TestNewBackfillerInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	p := testBackfillPoller()
+	if _, err := newBackfiller(p, path, time.UnixMilli(10), time.UnixMilli(10), time.Hour, 1, 1); err == nil {
+		t.Error("empty range accepted")
+	}
+	if _, err := newBackfiller(p, path, time.UnixMilli(0), time.UnixMilli(10), 0, 1, 1); err == nil {
+		t.Error("zero window accepted")
+	}
+}
+
+func TestBackfillerResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	p := testBackfillPoller()
+	start, end := time.UnixMilli(0), time.UnixMilli(2*3600_000)
+	b, err := newBackfiller(p, path, start, end, time.Hour, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.state.Pending[0].Page = 3
+	b.state.Pending[0].Fingerprint = "fp"
+	b.finish(b.state.Pending[1])
+
+	// 相同范围续跑，保留页位置
+	r, err := newBackfiller(p, path, start, end, time.Hour, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.state.Done != 1 || len(r.state.Pending) != 2 || r.state.Pending[0].Page != 3 || r.state.Pending[0].Fingerprint != "fp" {
+		t.Fatalf("resumed state = %+v", r.state)
+	}
+
+	// 不指定结束时间时沿用进度文件中的结束时间
+	r, err = newBackfiller(p, path, start, time.Time{}, time.Hour, 1, 10)
+	if err != nil {
+		t.Fatalf("resume without end: %v", err)
+	}
+	if r.state.End != end.UnixMilli() || r.state.Done != 1 {
+		t.Fatalf("resumed state = %+v", r.state)
+	}
+
+	if _, err := newBackfiller(p, path, start, time.UnixMilli(3*3600_000), time.Hour, 1, 10); err == nil {
+		t.Error("progress of another range accepted")
+	}
+}
+
+func TestBackfillerSplit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	b, err := newBackfiller(testBackfillPoller(), path, time.UnixMilli(0), time.UnixMilli(1000), time.Hour, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur := b.state.Pending[0]
+	cur.LastTs = 200
+	if err := b.split(cur); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.state.Pending) != 2 || b.state.Splits != 1 {
+		t.Fatalf("state after split = %+v", b.state)
+	}
+	// 从已处理到的时间戳开始对半拆分
+	left, right := b.state.Pending[0], b.state.Pending[1]
+	if left.MinTs != 200 || left.MaxTs != 600 || right.MinTs != 601 || right.MaxTs != 1000 {
+		t.Errorf("split into %d..%d and %d..%d", left.MinTs, left.MaxTs, right.MinTs, right.MaxTs)
+	}
+
+	single := &cursor{Contract: testToken, MinTs: 5, MaxTs: 5}
+	b.state.Pending = append(b.state.Pending, single)
+	if err := b.split(single); err == nil {
+		t.Error("split of a single millisecond accepted")
+	}
+}
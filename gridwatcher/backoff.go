@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff computes exponential retry delays with jitter
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// rand returns a number in [0, 1) for the jitter; nil uses math/rand
+	rand func() float64
+}
+
+// Delay returns the wait before retry number attempt (0-based).
+// The delay grows exponentially up to Max and is jittered into [d/2, d]
+// so that several watchers do not hammer TronGrid in lockstep.
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt))
+	// 次数很大时结果为 +Inf（Initial 为 0 时为 NaN），都按 Max 处理
+	if !(d <= float64(b.Max)) {
+		d = float64(b.Max)
+	}
+	random := b.rand
+	if random == nil {
+		random = rand.Float64
+	}
+	half := d / 2
+	return time.Duration(half + random()*half)
+}
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker pauses polling after too many consecutive failures.
+// After Cooldown it lets a single probe through (half-open); a success closes it again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	state     string
+	// now is the clock; time.Now unless replaced in tests
+	now func() time.Time
+}
+
+// NewCircuitBreaker returns a closed breaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed, now: time.Now}
+}
+
+// Wait returns how long the caller must wait before the next request (0 when allowed)
+func (b *CircuitBreaker) Wait() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen {
+		return 0
+	}
+	remaining := b.cooldown - b.now().Sub(b.openedAt)
+	if remaining > 0 {
+		return remaining
+	}
+	b.state = BreakerHalfOpen
+	return 0
+}
+
+// Success records a successful request and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.state = BreakerClosed
+}
+
+// Failure records a failed request and reports whether the breaker just opened
+func (b *CircuitBreaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		return true
+	}
+	return false
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// sleepCtx sleeps for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
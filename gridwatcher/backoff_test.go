@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		rand    float64
+		want    time.Duration
+	}{
+		{"first, lowest jitter", 0, 0, 250 * time.Millisecond},
+		{"first, highest jitter", 0, 0.999999, 499_999_750},
+		{"grows exponentially", 3, 0, 2 * time.Second},
+		{"capped at max", 10, 0, 15 * time.Second},
+		{"max with full jitter", 10, 1, 30 * time.Second},
+		{"overflow", 5000, 0.5, 22500 * time.Millisecond},
+		{"int overflow", math.MaxInt, 0, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Backoff{Initial: 500 * time.Millisecond, Max: 30 * time.Second, Multiplier: 2, rand: func() float64 { return tt.rand }}
+			if got := b.Delay(tt.attempt); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2}
+	for attempt := range 100 {
+		full := time.Minute
+		if attempt < 6 {
+			full = time.Second << attempt
+		}
+		if d := b.Delay(attempt); d < full/2 || d > full {
+			t.Fatalf("Delay(%d) = %v, want within [%v, %v]", attempt, d, full/2, full)
+		}
+	}
+}
+
+func TestBackoffZeroInitial(t *testing.T) {
+	// 0 * Inf 为 NaN，不能变成任意的时长
+	b := Backoff{Max: time.Second, Multiplier: 2, rand: func() float64 { return 0 }}
+	if d := b.Delay(0); d != 0 {
+		t.Errorf("Delay(0) = %v", d)
+	}
+	if d := b.Delay(5000); d != 500*time.Millisecond {
+		t.Errorf("Delay(5000) = %v, want half of max", d)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	b := NewCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if b.Failure() {
+			t.Fatalf("opened after %d failures", i+1)
+		}
+	}
+	if b.State() != BreakerClosed || b.Wait() != 0 {
+		t.Fatalf("state %s below the threshold", b.State())
+	}
+	if !b.Failure() || b.State() != BreakerOpen {
+		t.Fatalf("not opened at the threshold: %s", b.State())
+	}
+
+	now = now.Add(20 * time.Second)
+	if w := b.Wait(); w != 40*time.Second {
+		t.Errorf("Wait = %v, want the rest of the cooldown", w)
+	}
+	if b.State() != BreakerOpen {
+		t.Errorf("state %s during cooldown", b.State())
+	}
+
+	// 冷却结束放行一次探测；探测失败立即重新打开
+	now = now.Add(40 * time.Second)
+	if w := b.Wait(); w != 0 || b.State() != BreakerHalfOpen {
+		t.Fatalf("after cooldown: wait %v, state %s", w, b.State())
+	}
+	if !b.Failure() || b.State() != BreakerOpen {
+		t.Fatalf("failed probe: state %s", b.State())
+	}
+	if w := b.Wait(); w != time.Minute {
+		t.Errorf("Wait = %v, want a full cooldown from the failed probe", w)
+	}
+
+	// 探测成功关闭熔断并清零失败计数
+	now = now.Add(time.Minute)
+	b.Wait()
+	b.Success()
+	if b.State() != BreakerClosed || b.Wait() != 0 {
+		t.Fatalf("after a successful probe: state %s", b.State())
+	}
+	if b.Failure() {
+		t.Error("opened on the first failure after closing")
+	}
+}
+
+func TestCircuitBreakerSuccessResets(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute)
+	b.Failure()
+	b.Success()
+	if b.Failure() {
+		t.Error("failures were not consecutive but the breaker opened")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yourname/tron-demo/health"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/source"
+)
+
+// cursorKeyBlock labels the block cursor of the block based loop in lag metrics
+const cursorKeyBlock = "block"
+
+// RunSource is the block based counterpart of Run: it walks confirmed blocks
+// with a single block cursor and reads transfers from src, which may be the
+// full node or a failover between TronGrid and the full node.
+func (p *poller) RunSource(ctx context.Context, src source.TransferSource) error {
+	p.mu.Lock()
+	p.src = src
+	p.mu.Unlock()
+
+	for {
+		if err := p.pollSource(ctx, src); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			p.checkpoint()
+			return err
+		}
+		if err := sleepCtx(ctx, p.cfg.Watch.PollInterval); err != nil {
+			break
+		}
+	}
+
+	log.Printf("[poller] Stopping, checkpointing cursor")
+	p.checkpoint()
+	return nil
+}
+
+// pollSource processes every block from the cursor up to the source's confirmed head
+func (p *poller) pollSource(ctx context.Context, src source.TransferSource) error {
+	var head int64
+	if err := p.callSource(ctx, "head", func(ctx context.Context) error {
+		var err error
+		head, err = src.Head(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.head = head
+	next := p.state.NextBlock
+	if next == 0 {
+		// 首次运行：从链头往回 lookback 窗口对应的区块数开始
+		next = head - int64(p.cfg.Watch.LookbackWindow/p.cfg.Profile.BlockTime) + 1
+		p.state.NextBlock = next
+		log.Printf("[poller] No block cursor, starting at block %d", next)
+	}
+	p.mu.Unlock()
+
+	// 已开始的批次不随 ctx 取消而中断，保证整批发布完成后再推进游标
+	inflight := context.WithoutCancel(ctx)
+	failures := 0
+	for next <= head {
+		to := min(head, next+p.cfg.Source.BatchBlocks-1)
+
+		var transfers []source.Transfer
+		err := p.callSource(ctx, "transfers", func(context.Context) error {
+			var err error
+			transfers, err = src.Transfers(inflight, p.cfg.Watch.Tokens, next, to)
+			return err
+		})
+		if errors.Is(err, source.ErrBeyondHead) {
+			// 当前数据源落后于之前看到的链头（比如刚切换），下一轮再取
+			log.Printf("[poller] Blocks %d-%d not confirmed on %s yet", next, to, sourceName(src))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		matched := 0
+		var lastTs int64
+		var publishErr error
+		for _, t := range transfers {
+			hit, err := p.handleTransfer(inflight, t, true)
+			if err != nil {
+				publishErr = err
+				break
+			}
+			if hit {
+				matched++
+			}
+			metrics.EventsProcessed.WithLabelValues(EventSource, t.Contract).Inc()
+			lastTs = max(lastTs, t.BlockTimestamp)
+		}
+		if publishErr != nil {
+			// 发布失败不推进区块游标，退避后重新读取并发布这一批
+			if err := p.retryPublish(ctx, failures, publishErr); err != nil {
+				return err
+			}
+			failures++
+			continue
+		}
+		failures = 0
+		p.stats.fetchSucceeded(len(transfers), matched)
+		log.Printf("[poller] Blocks %d-%d via %s: %d transfers, %d matched", next, to, sourceName(src), len(transfers), matched)
+
+		next = to + 1
+		p.mu.Lock()
+		p.state.NextBlock = next
+		p.mu.Unlock()
+		p.checkpoint()
+
+		// 没有转账的批次按出块时间估算时间戳
+		cursorTime := time.UnixMilli(lastTs)
+		if lastTs == 0 {
+			cursorTime = time.Now().Add(-time.Duration(head-to) * p.cfg.Profile.BlockTime)
+		}
+		metrics.Lag.SetCursor(EventSource, cursorKeyBlock, to, cursorTime)
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callSource calls fn with backoff until it succeeds, fails with ErrBeyondHead
+// or a fatal error, or ctx is cancelled. Other source errors are retried: the
+// failover source switches underneath and a single source may come back.
+func (p *poller) callSource(ctx context.Context, what string, fn func(context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		p.stats.attempted()
+		err := fn(ctx)
+		if err == nil || errors.Is(err, source.ErrBeyondHead) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// API key 被拒绝等错误重试也不会好，直接退出
+		if source.IsFatal(err) {
+			p.stats.fetchFailed(err, ErrorFatal, false)
+			metrics.FetchErrors.WithLabelValues(ErrorFatal.String()).Inc()
+			return fmt.Errorf("source %s: %w", what, err)
+		}
+		p.stats.fetchFailed(err, ErrorRetryable, false)
+		metrics.FetchErrors.WithLabelValues(ErrorRetryable.String()).Inc()
+
+		delay := p.backoff.Delay(attempt)
+		log.Printf("[poller] Source %s failed (%v), retrying in %v", what, err, delay.Round(time.Millisecond))
+		if err := sleepCtx(ctx, delay); err != nil {
+			return fmt.Errorf("source %s: %w", what, err)
+		}
+	}
+}
+
+// sourceName includes the active source for failover sources
+func sourceName(src source.TransferSource) string {
+	if f, ok := src.(*source.Failover); ok {
+		return f.Name() + "/" + f.Active()
+	}
+	return src.Name()
+}
+
+// blockStatus is Status for the block based loop; p.mu must be held
+func (p *poller) blockStatus(st *Stats) health.Status {
+	cursorBlock := p.state.NextBlock - 1
+	lagBlocks := max(p.head-cursorBlock, 0)
+	// 区块游标在启动前尚未确定时按启动时间计算
+	lag := time.Duration(lagBlocks) * p.cfg.Profile.BlockTime
+	if p.head == 0 {
+		lag = time.Since(p.startedAt)
+	}
+	return health.Status{
+		Watcher:   EventSource,
+		Network:   p.cfg.Profile.Name,
+		Tokens:    p.cfg.Watch.Tokens,
+		Addresses: len(p.cfg.Watch.Addresses),
+		Cursor: map[string]any{
+			"next_block": p.state.NextBlock,
+			"source":     sourceName(p.src),
+		},
+		CursorBlock:         cursorBlock,
+		ChainHead:           p.head,
+		LagSeconds:          lag.Seconds(),
+		LagBlocks:           lagBlocks,
+		StartedAt:           p.startedAt,
+		LastActivity:        st.LastAttemptAt,
+		LastSuccess:         st.LastSuccessAt,
+		Errors:              map[string]int64{"retryable": st.RetryableErrors, "fatal": st.FatalErrors, "decode": st.DecodeErrors},
+		ConsecutiveFailures: st.ConsecutiveFailures,
+		LastError:           st.LastError,
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorClass tells the poll loop whether a failed fetch may be retried
+type ErrorClass int
+
+const (
+	// ErrorRetryable covers timeouts, connection errors, 429 and 5xx responses
+	ErrorRetryable ErrorClass = iota
+	// ErrorFatal covers errors that will not go away by retrying (bad API key, bad request)
+	ErrorFatal
+)
+
+func (c ErrorClass) String() string {
+	if c == ErrorFatal {
+		return "fatal"
+	}
+	return "retryable"
+}
+
+// FetchError is returned by FetchTransferEvents for every failed request
+type FetchError struct {
+	Class      ErrorClass
+	StatusCode int // 0 for transport errors
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s error: http %d: %v", e.Class, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s error: %v", e.Class, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Fatal lets source.IsFatal and the failover source recognize errors that retrying will not fix
+func (e *FetchError) Fatal() bool {
+	return e.Class == ErrorFatal
+}
+
+// IsRetryable reports whether err is a FetchError that may succeed on retry
+func IsRetryable(err error) bool {
+	var fe *FetchError
+	return errors.As(err, &fe) && fe.Class == ErrorRetryable
+}
+
+// classifyTransportError classifies an error returned before any HTTP status was received.
+// Cancellation is returned as is: it is a shutdown, not a failed fetch.
+func classifyTransportError(err error) error {
+	// 调用方取消（关闭进程）不是 TronGrid 的问题，不计入错误统计
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	// 超时、连接被重置、EOF 等传输层错误都按可重试处理
+	return &FetchError{Class: ErrorRetryable, Err: err}
+}
+
+// classifyStatus classifies a non-2xx HTTP response
+func classifyStatus(status int, body string) *FetchError {
+	class := ErrorFatal
+	switch {
+	case status == http.StatusTooManyRequests, status == http.StatusRequestTimeout, status >= 500:
+		class = ErrorRetryable
+	}
+	return &FetchError{Class: class, StatusCode: status, Err: errors.New(body)}
+}
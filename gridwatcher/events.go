@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// Event names of the TRC20 and USDT specific events we decode into typed structs
+const (
+	EventNameApproval            = "Approval"
+	EventNameAddedBlackList      = "AddedBlackList"
+	EventNameRemovedBlackList    = "RemovedBlackList"
+	EventNameDestroyedBlackFunds = "DestroyedBlackFunds"
+	EventNameIssue               = "Issue"
+	EventNameRedeem              = "Redeem"
+)
+
+// TransferEvent is Transfer(address indexed from, address indexed to, uint256 value)
+type TransferEvent struct {
+	From  string
+	To    string
+	Value *big.Int
+}
+
+// ApprovalEvent is Approval(address indexed owner, address indexed spender, uint256 value)
+type ApprovalEvent struct {
+	Owner   string
+	Spender string
+	Value   *big.Int
+}
+
+// BlackListEvent is USDT's AddedBlackList(address _user) / RemovedBlackList(address _user)
+type BlackListEvent struct {
+	Added bool
+	User  string
+}
+
+// DestroyedBlackFundsEvent is USDT's DestroyedBlackFunds(address _blackListedUser, uint _balance)
+type DestroyedBlackFundsEvent struct {
+	BlackListedUser string
+	Balance         *big.Int
+}
+
+// SupplyEvent is USDT's Issue(uint amount) / Redeem(uint amount)
+type SupplyEvent struct {
+	Issue  bool
+	Amount *big.Int
+}
+
+// DecodeError reports a field that is missing or has an unexpected type or format
+type DecodeError struct {
+	Event string
+	Field string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode %s.%s: %v", e.Event, e.Field, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode returns the typed struct for known events and a generic
+// map[string]interface{} (decoded via result_type) for everything else.
+func (e Event) Decode() (interface{}, error) {
+	switch e.EventName {
+	case EventNameTransfer:
+		return e.Transfer()
+	case EventNameApproval:
+		return e.Approval()
+	case EventNameAddedBlackList, EventNameRemovedBlackList:
+		return e.BlackList()
+	case EventNameDestroyedBlackFunds:
+		return e.DestroyedBlackFunds()
+	case EventNameIssue, EventNameRedeem:
+		return e.Supply()
+	default:
+		return e.DecodeGeneric()
+	}
+}
+
+// Transfer decodes a Transfer event
+func (e Event) Transfer() (*TransferEvent, error) {
+	if err := e.expect(EventNameTransfer); err != nil {
+		return nil, err
+	}
+	from, err := e.address("from", "0")
+	if err != nil {
+		return nil, err
+	}
+	to, err := e.address("to", "1")
+	if err != nil {
+		return nil, err
+	}
+	value, err := e.unsigned("value", "2")
+	if err != nil {
+		return nil, err
+	}
+	return &TransferEvent{From: from, To: to, Value: value}, nil
+}
+
+// Approval decodes an Approval event
+func (e Event) Approval() (*ApprovalEvent, error) {
+	if err := e.expect(EventNameApproval); err != nil {
+		return nil, err
+	}
+	owner, err := e.address("owner", "0")
+	if err != nil {
+		return nil, err
+	}
+	spender, err := e.address("spender", "1")
+	if err != nil {
+		return nil, err
+	}
+	value, err := e.unsigned("value", "2")
+	if err != nil {
+		return nil, err
+	}
+	return &ApprovalEvent{Owner: owner, Spender: spender, Value: value}, nil
+}
+
+// BlackList decodes AddedBlackList and RemovedBlackList events
+func (e Event) BlackList() (*BlackListEvent, error) {
+	if err := e.expect(EventNameAddedBlackList, EventNameRemovedBlackList); err != nil {
+		return nil, err
+	}
+	user, err := e.address("_user", "0")
+	if err != nil {
+		return nil, err
+	}
+	return &BlackListEvent{Added: e.EventName == EventNameAddedBlackList, User: user}, nil
+}
+
+// DestroyedBlackFunds decodes a DestroyedBlackFunds event
+func (e Event) DestroyedBlackFunds() (*DestroyedBlackFundsEvent, error) {
+	if err := e.expect(EventNameDestroyedBlackFunds); err != nil {
+		return nil, err
+	}
+	user, err := e.address("_blackListedUser", "0")
+	if err != nil {
+		return nil, err
+	}
+	balance, err := e.unsigned("_balance", "1")
+	if err != nil {
+		return nil, err
+	}
+	return &DestroyedBlackFundsEvent{BlackListedUser: user, Balance: balance}, nil
+}
+
+// Supply decodes Issue and Redeem events
+func (e Event) Supply() (*SupplyEvent, error) {
+	if err := e.expect(EventNameIssue, EventNameRedeem); err != nil {
+		return nil, err
+	}
+	amount, err := e.unsigned("amount", "0")
+	if err != nil {
+		return nil, err
+	}
+	return &SupplyEvent{Issue: e.EventName == EventNameIssue, Amount: amount}, nil
+}
+
+// DecodeGeneric decodes every named field using the result_type metadata:
+// addresses become base58 strings, (u)int types *big.Int, bool bool, anything else stays a string.
+func (e Event) DecodeGeneric() (map[string]interface{}, error) {
+	if len(e.ResultType) == 0 {
+		return nil, &DecodeError{Event: e.EventName, Field: "result_type", Err: fmt.Errorf("missing")}
+	}
+	out := make(map[string]interface{}, len(e.ResultType))
+	for name, typ := range e.ResultType {
+		var (
+			v   interface{}
+			err error
+		)
+		switch {
+		case typ == "address":
+			v, err = e.address(name)
+		case strings.HasPrefix(typ, "uint") || strings.HasPrefix(typ, "int"):
+			v, err = e.integer(name, strings.HasPrefix(typ, "int"))
+		case typ == "bool":
+			var s string
+			if s, err = e.str(name); err == nil {
+				v = s == "true"
+			}
+		default:
+			v, err = e.str(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+func (e Event) expect(names ...string) error {
+	for _, n := range names {
+		if e.EventName == n {
+			return nil
+		}
+	}
+	return &DecodeError{Event: e.EventName, Field: "event_name", Err: fmt.Errorf("want %s", strings.Join(names, " or "))}
+}
+
+// str returns the first present key as a string; TronGrid repeats fields under their position index
+func (e Event) str(keys ...string) (string, error) {
+	for _, k := range keys {
+		raw, ok := e.Result[k]
+		if !ok {
+			continue
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return "", &DecodeError{Event: e.EventName, Field: k, Err: fmt.Errorf("unexpected type %T", raw)}
+		}
+		return s, nil
+	}
+	return "", &DecodeError{Event: e.EventName, Field: keys[0], Err: fmt.Errorf("missing")}
+}
+
+func (e Event) address(keys ...string) (string, error) {
+	s, err := e.str(keys...)
+	if err != nil {
+		return "", err
+	}
+	addr, err := EvmHexToTronBase58(s)
+	if err != nil {
+		return "", &DecodeError{Event: e.EventName, Field: keys[0], Err: err}
+	}
+	return addr, nil
+}
+
+func (e Event) unsigned(keys ...string) (*big.Int, error) {
+	return e.integer(keys[0], false, keys[1:]...)
+}
+
+func (e Event) integer(key string, signed bool, fallback ...string) (*big.Int, error) {
+	s, err := e.str(append([]string{key}, fallback...)...)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, &DecodeError{Event: e.EventName, Field: key, Err: fmt.Errorf("invalid integer %q", s)}
+	}
+	if !signed && v.Sign() < 0 {
+		return nil, &DecodeError{Event: e.EventName, Field: key, Err: fmt.Errorf("negative value %s", s)}
+	}
+	return v, nil
+}
+
+// EvmHexToTronBase58 converts an EVM style hex address (0x + 40 hex, or 41 + 40 hex) to TRON base58
+func EvmHexToTronBase58(h string) (string, error) {
+	h = strings.TrimPrefix(strings.ToLower(h), "0x")
+	if len(h) == 42 && strings.HasPrefix(h, "41") {
+		h = h[2:]
+	}
+	if len(h) != 40 {
+		return "", fmt.Errorf("invalid hex address length %d", len(h))
+	}
+	raw, err := hex.DecodeString(h)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex address: %w", err)
+	}
+	return base58.CheckEncode(raw, 0x41), nil
+}
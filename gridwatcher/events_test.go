@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const (
+	usdtBase58 = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	usdtHex    = "a614f803b6fd780986a42c78ec9c7f77e6ded13c"
+)
+
+// transferPayload is a Transfer event as returned by /v1/contracts/{address}/events
+const transferPayload = `{
+	"block_number": 62913164,
+	"block_timestamp": 1718000001000,
+	"caller_contract_address": "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+	"contract_address": "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+	"event_index": 0,
+	"event_name": "Transfer",
+	"result": {
+		"0": "0x4b1db7c9a4e5c6a26f6b4ad6b79e18bf0a4cc94f",
+		"1": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+		"2": "25000000",
+		"from": "0x4b1db7c9a4e5c6a26f6b4ad6b79e18bf0a4cc94f",
+		"to": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+		"value": "25000000"
+	},
+	"result_type": {"from": "address", "to": "address", "value": "uint256"},
+	"event": "Transfer(address indexed from, address indexed to, uint256 value)",
+	"transaction_id": "4a3e6e5d0c2b1f6a9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f"
+}`
+
+func parseEvent(t *testing.T, payload string) Event {
+	t.Helper()
+	var e Event
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestEvmHexToTronBase58(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"0x" + usdtHex, usdtBase58, ""},
+		{"0X" + strings.ToUpper(usdtHex), usdtBase58, ""},
+		{"41" + usdtHex, usdtBase58, ""},
+		{"0x41" + usdtHex, usdtBase58, ""},
+		{usdtHex, usdtBase58, ""},
+		{"", "", "length 0"},
+		{"0x" + usdtHex[:38], "", "length 38"},
+		{"42" + usdtHex, "", "length 42"},
+		{"0x" + usdtHex[:39] + "g", "", "invalid hex address"},
+		{usdtBase58, "", "length 34"},
+	}
+	for _, tt := range tests {
+		got, err := EvmHexToTronBase58(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("EvmHexToTronBase58(%q) = %q, %v; want error %q", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("EvmHexToTronBase58(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeTransfer(t *testing.T) {
+	e := parseEvent(t, transferPayload)
+	tr, err := e.Transfer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, _ := EvmHexToTronBase58("4b1db7c9a4e5c6a26f6b4ad6b79e18bf0a4cc94f")
+	if tr.From != from || tr.To != usdtBase58 || tr.Value.Cmp(big.NewInt(25_000_000)) != 0 {
+		t.Errorf("Transfer = %+v", tr)
+	}
+	if v, err := e.Decode(); err != nil || v.(*TransferEvent).Value.Int64() != 25_000_000 {
+		t.Errorf("Decode = %v, %v", v, err)
+	}
+}
+
+// 按名称缺失时退回位置索引；两者都缺失、类型或格式不对时返回 DecodeError
+func TestDecodeTransferErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      func(r map[string]interface{})
+		eventName string
+		field     string
+	}{
+		{"positional only", func(r map[string]interface{}) {
+			delete(r, "from")
+			delete(r, "to")
+			delete(r, "value")
+		}, "", ""},
+		{"missing value", func(r map[string]interface{}) {
+			delete(r, "value")
+			delete(r, "2")
+		}, "", "value"},
+		{"missing from", func(r map[string]interface{}) {
+			delete(r, "from")
+			delete(r, "0")
+		}, "", "from"},
+		{"malformed address", func(r map[string]interface{}) { r["to"] = "0xzz14f803b6fd780986a42c78ec9c7f77e6ded13c" }, "", "to"},
+		{"short address", func(r map[string]interface{}) { r["to"] = "0xa614f803" }, "", "to"},
+		{"numeric value", func(r map[string]interface{}) { r["value"] = 25000000.0 }, "", "value"},
+		{"hex value", func(r map[string]interface{}) { r["value"] = "0x17d7840" }, "", "value"},
+		{"negative value", func(r map[string]interface{}) { r["value"] = "-1" }, "", "value"},
+		{"wrong event", nil, "Approval", "event_name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseEvent(t, transferPayload)
+			if tt.edit != nil {
+				tt.edit(e.Result)
+			}
+			if tt.eventName != "" {
+				e.EventName = tt.eventName
+			}
+			tr, err := e.Transfer()
+			if tt.field == "" {
+				if err != nil || tr.Value.Int64() != 25_000_000 {
+					t.Fatalf("Transfer = %+v, %v", tr, err)
+				}
+				return
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) || de.Field != tt.field {
+				t.Fatalf("Transfer = %+v, %v; want a DecodeError on %s", tr, err, tt.field)
+			}
+		})
+	}
+}
+
+func TestDecodeApproval(t *testing.T) {
+	e := parseEvent(t, `{
+		"event_name": "Approval",
+		"result": {
+			"0": "0x4b1db7c9a4e5c6a26f6b4ad6b79e18bf0a4cc94f",
+			"1": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+			"2": "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+			"owner": "0x4b1db7c9a4e5c6a26f6b4ad6b79e18bf0a4cc94f",
+			"spender": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+			"value": "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+		},
+		"result_type": {"owner": "address", "spender": "address", "value": "uint256"}
+	}`)
+	v, err := e.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := v.(*ApprovalEvent)
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if a.Spender != usdtBase58 || a.Value.Cmp(maxUint256) != 0 {
+		t.Errorf("Approval = %+v", a)
+	}
+}
+
+func TestDecodeUSDTEvents(t *testing.T) {
+	added := parseEvent(t, `{"event_name": "AddedBlackList", "result": {"0": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c", "_user": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c"}, "result_type": {"_user": "address"}}`)
+	if v, err := added.Decode(); err != nil || *v.(*BlackListEvent) != (BlackListEvent{Added: true, User: usdtBase58}) {
+		t.Errorf("AddedBlackList = %v, %v", v, err)
+	}
+	removed := parseEvent(t, `{"event_name": "RemovedBlackList", "result": {"0": "41a614f803b6fd780986a42c78ec9c7f77e6ded13c"}}`)
+	if v, err := removed.Decode(); err != nil || *v.(*BlackListEvent) != (BlackListEvent{User: usdtBase58}) {
+		t.Errorf("RemovedBlackList = %v, %v", v, err)
+	}
+	missing := parseEvent(t, `{"event_name": "AddedBlackList", "result": {}}`)
+	if _, err := missing.BlackList(); err == nil || !strings.Contains(err.Error(), "decode AddedBlackList._user: missing") {
+		t.Errorf("AddedBlackList without user: %v", err)
+	}
+
+	destroyed := parseEvent(t, `{"event_name": "DestroyedBlackFunds", "result": {"_blackListedUser": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c", "_balance": "1234500"}}`)
+	v, err := destroyed.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := v.(*DestroyedBlackFundsEvent); d.BlackListedUser != usdtBase58 || d.Balance.Int64() != 1_234_500 {
+		t.Errorf("DestroyedBlackFunds = %+v", d)
+	}
+
+	redeem := parseEvent(t, `{"event_name": "Redeem", "result": {"0": "1000000000", "amount": "1000000000"}}`)
+	if v, err := redeem.Decode(); err != nil || v.(*SupplyEvent).Issue || v.(*SupplyEvent).Amount.Int64() != 1_000_000_000 {
+		t.Errorf("Redeem = %v, %v", v, err)
+	}
+}
+
+func TestDecodeGeneric(t *testing.T) {
+	e := parseEvent(t, `{
+		"event_name": "Swap",
+		"result": {
+			"sender": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+			"amountIn": "1000",
+			"delta": "-25",
+			"exact": "true",
+			"tag": "abc"
+		},
+		"result_type": {"sender": "address", "amountIn": "uint256", "delta": "int256", "exact": "bool", "tag": "bytes32"}
+	}`)
+	v, err := e.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if m["sender"] != usdtBase58 || m["amountIn"].(*big.Int).Int64() != 1000 || m["delta"].(*big.Int).Int64() != -25 ||
+		m["exact"] != true || m["tag"] != "abc" {
+		t.Errorf("DecodeGeneric = %v", m)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"no result_type", `{"event_name": "Swap", "result": {"a": "1"}}`, "decode Swap.result_type: missing"},
+		{"missing field", `{"event_name": "Swap", "result": {}, "result_type": {"a": "uint256"}}`, "decode Swap.a: missing"},
+		{"negative uint", `{"event_name": "Swap", "result": {"a": "-1"}, "result_type": {"a": "uint8"}}`, "negative value"},
+		{"bad address", `{"event_name": "Swap", "result": {"a": "0x12"}, "result_type": {"a": "address"}}`, "invalid hex address length"},
+		{"non-string", `{"event_name": "Swap", "result": {"a": true}, "result_type": {"a": "bool"}}`, "unexpected type bool"},
+	}
+	for _, tt := range tests {
+		_, err := parseEvent(t, tt.payload).DecodeGeneric()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: DecodeGeneric error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/config"
+	"github.com/yourname/tron-demo/health"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/risk"
+	"github.com/yourname/tron-demo/sink"
+	"github.com/yourname/tron-demo/source"
+)
+
+// orderBy 降序：最新的在前
+const orderBy = "block_timestamp,desc"
+
+// cursor is the position of the poll loop inside one token's time window.
+// It is only advanced after a page was fetched and processed, so a retry
+// resumes on the same page instead of restarting or skipping it.
+type cursor struct {
+	Contract    string `json:"contract"`
+	MinTs       int64  `json:"min_ts"`
+	MaxTs       int64  `json:"max_ts"`
+	Fingerprint string `json:"fingerprint"`
+	Page        int    `json:"page"`
+	// MaxBlock is the highest block number seen in this window so far
+	MaxBlock int64 `json:"max_block,omitempty"`
+	// LastTs is the highest block timestamp seen in this window so far (backfill only)
+	LastTs int64 `json:"last_ts,omitempty"`
+}
+
+// poller runs the fetch/filter/publish loop with retries and a circuit breaker
+type poller struct {
+	cfg     *config.Config
+	watcher *EventWatcher
+	events  sink.EventSink
+	watch   map[string]struct{} // base58 addresses
+	backoff Backoff
+	breaker *CircuitBreaker
+	stats   *Stats
+	// detector flags dust spam and address poisoning before anything is credited
+	detector *risk.Detector
+
+	startedAt time.Time
+
+	// mu guards state against concurrent reads from the status endpoint;
+	// state is only written by the poll loop.
+	mu sync.Mutex
+	// state holds the last processed window end per contract and interrupted windows.
+	// After an outage the next window starts at the last end, so the pause does not
+	// leave a gap; after a restart an interrupted window resumes on its saved page.
+	state *watcherState
+	// src and head are set by RunSource: the block based source and its last confirmed head
+	src  source.TransferSource
+	head int64
+}
+
+func newPoller(cfg *config.Config, watcher *EventWatcher, events sink.EventSink, watch map[string]struct{}) (*poller, error) {
+	state, err := loadState(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	detector, err := cfg.Detector()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range state.Counterparties {
+		detector.Remember(a)
+	}
+	// 启动时用已保存的游标初始化延迟指标，卡死在启动阶段也能告警
+	for _, contract := range cfg.Watch.Tokens {
+		last := time.Now()
+		if end, ok := state.LastEnd[contract]; ok {
+			last = time.UnixMilli(end)
+		}
+		metrics.Lag.SetCursor(EventSource, contract, 0, last)
+	}
+
+	return &poller{
+		cfg:     cfg,
+		watcher: watcher,
+		events:  events,
+		watch:   watch,
+		backoff: Backoff{
+			Initial:    cfg.Retry.InitialBackoff,
+			Max:        cfg.Retry.MaxBackoff,
+			Multiplier: 2,
+		},
+		breaker:   NewCircuitBreaker(cfg.Retry.BreakerThreshold, cfg.Retry.BreakerCooldown),
+		stats:     &Stats{},
+		detector:  detector,
+		state:     state,
+		startedAt: time.Now(),
+	}, nil
+}
+
+// Run polls until ctx is cancelled. On cancellation the page in flight is
+// finished and published, the cursor is checkpointed and Run returns nil.
+// Any other returned error is fatal.
+func (p *poller) Run(ctx context.Context) error {
+	for {
+		if err := p.pollOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			// 只有不可重试的错误（如 API Key 无效）才会走到这里
+			p.checkpoint()
+			return err
+		}
+
+		st := p.stats.Snapshot()
+		log.Printf("[poller] Stats: pages=%d events=%d matched=%d retryable_errors=%d fatal_errors=%d breaker=%s",
+			st.PagesFetched, st.EventsProcessed, st.EventsMatched, st.RetryableErrors, st.FatalErrors, p.breaker.State())
+
+		// 完成当前时间窗口的所有分页后，等待一段时间，然后重新计算新的时间窗口
+		log.Printf("[poller] Completed time window, waiting %v before checking next time window", p.cfg.Watch.PollInterval)
+		if err := sleepCtx(ctx, p.cfg.Watch.PollInterval); err != nil {
+			break
+		}
+	}
+
+	log.Printf("[poller] Stopping, checkpointing cursor")
+	p.checkpoint()
+	return nil
+}
+
+// checkpoint persists the cursors; failures are logged because the in-memory state is still valid
+func (p *poller) checkpoint() {
+	if err := p.state.save(p.cfg.StateFile); err != nil {
+		log.Printf("[poller] Failed to save state to %s: %v", p.cfg.StateFile, err)
+	}
+}
+
+// pollOnce processes one time window for every watched token.
+// It only returns an error for fatal failures or when ctx is cancelled.
+func (p *poller) pollOnce(ctx context.Context) error {
+	// 外层：每次重新计算时间窗口
+	// 固定时间窗口，避免在分页过程中时间窗口变化导致 fingerprint 失效
+	currentTime := time.Now()
+	for _, contract := range p.cfg.Watch.Tokens {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 上次被中断的窗口优先从保存的页继续
+		p.mu.Lock()
+		cur, ok := p.state.InFlight[contract]
+		if ok {
+			log.Printf("[poller] Resuming interrupted window for %s at page %d", contract, cur.Page+1)
+		} else {
+			minTs := currentTime.Add(-p.cfg.Watch.LookbackWindow).UnixMilli()
+			if last, ok := p.state.LastEnd[contract]; ok && last < minTs {
+				log.Printf("[poller] Resuming %s from last cursor %s", contract, time.UnixMilli(last).Format(time.RFC3339))
+				minTs = last
+			}
+			cur = &cursor{Contract: contract, MinTs: minTs, MaxTs: currentTime.UnixMilli()}
+			p.state.InFlight[contract] = cur
+		}
+		p.mu.Unlock()
+
+		log.Printf("[poller] Starting new time window for %s: %s to %s", contract,
+			time.UnixMilli(cur.MinTs).Format(time.RFC3339), time.UnixMilli(cur.MaxTs).Format(time.RFC3339))
+
+		if err := p.pollWindow(ctx, cur); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		delete(p.state.InFlight, contract)
+		p.state.LastEnd[contract] = cur.MaxTs
+		p.mu.Unlock()
+		p.checkpoint()
+		metrics.Lag.SetCursor(EventSource, contract, cur.MaxBlock, time.UnixMilli(cur.MaxTs))
+	}
+	return nil
+}
+
+// pollWindow pages through one fixed time window.
+// Cancelling ctx stops it between pages; the page in flight is always completed.
+func (p *poller) pollWindow(ctx context.Context, cur *cursor) error {
+	// 已开始的请求和发布不随 ctx 取消而中断，保证整页处理完成
+	inflight := context.WithoutCancel(ctx)
+	for failures := 0; ; {
+		r, err := p.fetchPage(ctx, inflight, cur, orderBy)
+		if err != nil {
+			return err
+		}
+
+		matched, err := p.handleEvents(inflight, cur.Contract, r.Data)
+		if err != nil {
+			// 发布失败不推进游标，退避后重新拉取并发布本页
+			if err := p.retryPublish(ctx, failures, err); err != nil {
+				return err
+			}
+			failures++
+			continue
+		}
+		failures = 0
+		p.stats.fetchSucceeded(len(r.Data), matched)
+		metrics.PagesFetched.WithLabelValues(cur.Contract).Inc()
+		metrics.EventsProcessed.WithLabelValues(EventSource, cur.Contract).Add(float64(len(r.Data)))
+		metrics.EventsMatched.WithLabelValues(EventSource, cur.Contract).Add(float64(matched))
+
+		if matched > 0 {
+			log.Printf("[poller] Found %d matched events on page %d", matched, cur.Page+1)
+		}
+
+		// 本页处理完才推进游标
+		p.mu.Lock()
+		for _, ev := range r.Data {
+			if ev.BlockNumber > cur.MaxBlock {
+				cur.MaxBlock = ev.BlockNumber
+			}
+		}
+		cur.Fingerprint = r.Meta.Fingerprint
+		cur.Page++
+		p.mu.Unlock()
+
+		// 如果到达数据末尾（没有更多数据），结束当前时间窗口
+		if r.Meta.Fingerprint == "" || len(r.Data) == 0 {
+			log.Printf("[poller] Reached end of current time window: fingerprint=%s, dataCount=%d", r.Meta.Fingerprint, len(r.Data))
+			return nil
+		}
+		p.checkpoint()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// fetchPage fetches the page at cur, retrying retryable errors with backoff.
+// The circuit breaker pauses all requests after repeated failures.
+// ctx interrupts waits between attempts; reqCtx is used for the request itself.
+func (p *poller) fetchPage(ctx, reqCtx context.Context, cur *cursor, order string) (*TronGridResp, error) {
+	for attempt := 0; ; attempt++ {
+		if wait := p.breaker.Wait(); wait > 0 {
+			log.Printf("[poller] Circuit breaker open, pausing %v", wait.Round(time.Second))
+			if err := sleepCtx(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		p.stats.attempted()
+		log.Printf("[poller] Fetching %s page %d (attempt %d)", cur.Contract, cur.Page+1, attempt+1)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		r, err := p.watcher.FetchTransferEvents(reqCtx, cur.Contract, cur.Fingerprint, true /*onlyConfirmed*/, p.cfg.TronGrid.PageSize, cur.MinTs, cur.MaxTs, order)
+		if err == nil {
+			p.breaker.Success()
+			metrics.BreakerOpen.Set(0)
+			return r, nil
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+
+		var fe *FetchError
+		if errors.As(err, &fe) {
+			metrics.FetchErrors.WithLabelValues(fe.Class.String()).Inc()
+		}
+		if !IsRetryable(err) {
+			p.stats.fetchFailed(err, ErrorFatal, false)
+			return nil, fmt.Errorf("fetch %s page %d: %w", cur.Contract, cur.Page+1, err)
+		}
+
+		opened := p.breaker.Failure()
+		p.stats.fetchFailed(err, ErrorRetryable, opened)
+		if opened {
+			metrics.BreakerOpen.Set(1)
+			log.Printf("[poller] Circuit breaker opened after %d consecutive failures", p.stats.Snapshot().ConsecutiveFailures)
+			continue
+		}
+
+		delay := p.backoff.Delay(attempt)
+		log.Printf("[poller] Fetch failed (%v), retrying in %v", err, delay.Round(time.Millisecond))
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Status implements health.Reporter
+func (p *poller) Status() health.Status {
+	st := p.stats.Snapshot()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.src != nil {
+		return p.blockStatus(&st)
+	}
+
+	type tokenCursor struct {
+		LastEnd  time.Time `json:"last_end"`
+		InFlight *cursor   `json:"in_flight,omitempty"`
+	}
+	cursors := make(map[string]tokenCursor, len(p.cfg.Watch.Tokens))
+	var cursorBlock int64
+	oldest := time.Now()
+	for _, contract := range p.cfg.Watch.Tokens {
+		tc := tokenCursor{LastEnd: p.startedAt}
+		if end, ok := p.state.LastEnd[contract]; ok {
+			tc.LastEnd = time.UnixMilli(end)
+		}
+		if cur, ok := p.state.InFlight[contract]; ok {
+			c := *cur
+			tc.InFlight = &c
+			if c.MaxBlock > cursorBlock {
+				cursorBlock = c.MaxBlock
+			}
+		}
+		if tc.LastEnd.Before(oldest) {
+			oldest = tc.LastEnd
+		}
+		cursors[contract] = tc
+	}
+
+	// TronGrid 不提供链头，按出块时间估算
+	lag := time.Since(oldest)
+	lagBlocks := int64(lag / p.cfg.Profile.BlockTime)
+	var head int64
+	if cursorBlock > 0 {
+		head = cursorBlock + lagBlocks
+	}
+
+	return health.Status{
+		Watcher:             EventSource,
+		Network:             p.cfg.Profile.Name,
+		Tokens:              p.cfg.Watch.Tokens,
+		Addresses:           len(p.cfg.Watch.Addresses),
+		Cursor:              cursors,
+		CursorBlock:         cursorBlock,
+		ChainHead:           head,
+		LagSeconds:          lag.Seconds(),
+		LagBlocks:           lagBlocks,
+		StartedAt:           p.startedAt,
+		LastActivity:        st.LastAttemptAt,
+		LastSuccess:         st.LastSuccessAt,
+		Errors:              map[string]int64{"retryable": st.RetryableErrors, "fatal": st.FatalErrors, "breaker_opens": st.BreakerOpens, "decode": st.DecodeErrors},
+		ConsecutiveFailures: st.ConsecutiveFailures,
+		LastError:           st.LastError,
+		Breaker:             p.breaker.State(),
+	}
+}
+
+// tokenLabel returns the token symbol for well-known contracts, otherwise the contract itself
+func (p *poller) tokenLabel(contract string) string {
+	if t, ok := p.cfg.Profile.TokenByContract(contract); ok {
+		return t.Symbol
+	}
+	return contract
+}
+
+// learnCounterparty remembers the peer of a clean transfer involving a watched
+// address; it is persisted with the next checkpoint
+func (p *poller) learnCounterparty(t risk.Transfer, reasons []risk.Reason) {
+	if !p.detector.Learn(t, reasons) {
+		return
+	}
+	p.mu.Lock()
+	p.state.Counterparties = p.detector.Known()
+	p.mu.Unlock()
+}
+
+// handleEvents filters one page and publishes matched transfers; returns the match
+// count. It stops at the first event that could not be published.
+func (p *poller) handleEvents(ctx context.Context, contract string, data []Event) (int, error) {
+	matchedCount := 0
+	for _, ev := range data {
+		// 只处理 Transfer（我们请求里已经指定 Transfer，这里再保险）
+		if ev.EventName != EventNameTransfer {
+			continue
+		}
+
+		tr, err := ev.Transfer()
+		if err != nil {
+			// 解析失败的事件计数并记录，绝不当作空地址去匹配
+			p.stats.decodeFailed()
+			metrics.DecodeErrors.WithLabelValues(EventSource, ev.EventName).Inc()
+			log.Printf("[poller] Failed to decode event tx=%s index=%d: %v", ev.TransactionID, ev.EventIndex, err)
+			continue
+		}
+
+		matched, err := p.handleTransfer(ctx, source.Transfer{
+			TxID:           ev.TransactionID,
+			LogIndex:       ev.EventIndex,
+			BlockNumber:    ev.BlockNumber,
+			BlockTimestamp: ev.BlockTimestamp,
+			Contract:       contract,
+			From:           tr.From,
+			To:             tr.To,
+			Value:          tr.Value,
+		}, !ev.Unconfirmed)
+		if err != nil {
+			return matchedCount, err
+		}
+		if matched {
+			matchedCount++
+		}
+	}
+	return matchedCount, nil
+}
+
+// handleTransfer screens one transfer and publishes it when it goes to a watched
+// address; it reports whether the transfer matched. Every source goes through here,
+// so events carry the same idempotency key no matter where they came from.
+// A publish error is returned so the caller retries instead of advancing its cursor.
+func (p *poller) handleTransfer(ctx context.Context, t source.Transfer, confirmed bool) (bool, error) {
+	// 与关注地址无关的转账直接跳过，不做风险评估；未配置关注地址时监控全部
+	_, isToWatched := p.watch[t.To]
+	_, isFromWatched := p.watch[t.From]
+	isToWatched = isToWatched || len(p.watch) == 0
+	if !isToWatched && !isFromWatched {
+		return false, nil
+	}
+
+	decimals, known := p.cfg.Profile.TokenDecimals(t.Contract)
+	value := amount.New(t.Value, decimals)
+	// 事件接口和区块回执都不带交易签名者，Caller 留空，fake_transfer_from 规则在 gridwatcher 中不适用：
+	// 他人发起的 0 金额 transferFrom 只标记为 zero_value（见 readme）
+	transfer := risk.Transfer{From: t.From, To: t.To, Value: value, Unscaled: !known}
+	// 先评估再学习：学到的对手方不会再被判为仿冒地址。
+	// 转出也要学习（付过款的地址最常被仿冒），但只发布转入
+	reasons := p.detector.Assess(transfer)
+	p.learnCounterparty(transfer, reasons)
+	if !isToWatched {
+		return false, nil
+	}
+
+	// 幂等键：txid + event_index
+	idempotencyKey := fmt.Sprintf("%s%s%d", t.TxID, IdempotencyKeySeparator, t.LogIndex)
+	eventType := EventTypeDeposit
+	var data map[string]string
+	if len(reasons) > 0 {
+		tag := risk.Join(reasons)
+		for _, r := range reasons {
+			metrics.RiskFlagged.WithLabelValues(EventSource, string(r)).Inc()
+		}
+		data = map[string]string{"risk": tag}
+		// 默认不入账：改成 FLAGGED_TRANSFER 发布，消费者只对 DEPOSIT 入账
+		if !p.cfg.Risk.IncludeFlagged {
+			eventType = EventTypeFlagged
+		}
+	}
+	if eventType == EventTypeDeposit {
+		metrics.Deposits.WithLabelValues(EventSource, p.tokenLabel(t.Contract)).Inc()
+	}
+	log.Printf("[poller] %s hit: to=%s from=%s value=%s tx=%s confirmed=%v key=%s risk=%s",
+		eventType, t.To, t.From, value, t.TxID, confirmed, idempotencyKey, data["risk"])
+
+	// 发布到事件下游（入库 unique(idempotencyKey) + 触发入账流程由消费者负责）
+	if err := p.events.Publish(ctx, sink.Event{
+		Source:         EventSource,
+		Type:           eventType,
+		Key:            idempotencyKey,
+		TxID:           t.TxID,
+		BlockNumber:    t.BlockNumber,
+		BlockTimestamp: t.BlockTimestamp,
+		Contract:       t.Contract,
+		From:           t.From,
+		To:             t.To,
+		Value:          &value,
+		Unscaled:       !known,
+		Confirmed:      confirmed,
+		Data:           data,
+	}); err != nil {
+		return true, fmt.Errorf("publish event %s: %w", idempotencyKey, err)
+	}
+	return true, nil
+}
+
+// retryPublish waits before a page or batch whose events could not all be
+// published is processed again. The cursor was not advanced, so nothing is
+// lost; events published before the failure are sent again under the same key.
+// It only returns an error when ctx is cancelled.
+func (p *poller) retryPublish(ctx context.Context, attempt int, err error) error {
+	delay := p.backoff.Delay(attempt)
+	log.Printf("[poller] %v, retrying in %v", err, delay.Round(time.Millisecond))
+	return sleepCtx(ctx, delay)
+}
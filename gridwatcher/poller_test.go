@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math/big"
+	"testing"
+
+	"github.com/yourname/tron-demo/config"
+	"github.com/yourname/tron-demo/sink"
+	"github.com/yourname/tron-demo/source"
+)
+
+const testWatched = "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF"
+
+type failingSink struct{ err error }
+
+func (s failingSink) Publish(context.Context, sink.Event) error { return s.err }
+func (s failingSink) Close() error                              { return nil }
+
+func testPoller(t *testing.T, events sink.EventSink) *poller {
+	t.Helper()
+	cfg, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-network", "mainnet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Watch.Tokens = []string{testToken}
+	cfg.Watch.Addresses = []string{testWatched}
+	cfg.StateFile = ""
+	p, err := newPoller(cfg, nil, events, map[string]struct{}{testWatched: {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func testTransfer(to string, value int64) source.Transfer {
+	return source.Transfer{
+		TxID:        "tx1",
+		LogIndex:    2,
+		BlockNumber: 100,
+		Contract:    testToken,
+		From:        "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8",
+		To:          to,
+		Value:       big.NewInt(value),
+	}
+}
+
+func TestHandleTransfer(t *testing.T) {
+	ch := sink.NewChannel(1)
+	p := testPoller(t, ch)
+
+	hit, err := p.handleTransfer(context.Background(), testTransfer(testWatched, 5_000_000), true)
+	if err != nil || !hit {
+		t.Fatalf("handleTransfer = %v, %v", hit, err)
+	}
+	ev := <-ch.C()
+	if ev.Type != EventTypeDeposit || ev.Key != "tx1#2" || ev.Value.String() != "5.000000" || ev.Unscaled {
+		t.Errorf("event = %+v", ev)
+	}
+
+	hit, err = p.handleTransfer(context.Background(), testTransfer("TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8", 1), true)
+	if err != nil || hit {
+		t.Errorf("unwatched transfer: handleTransfer = %v, %v", hit, err)
+	}
+}
+
+func TestHandleTransferWatchFilter(t *testing.T) {
+	ch := sink.NewChannel(1)
+	p := testPoller(t, ch)
+	const peer = "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"
+	const other = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+
+	// 两端都不是关注地址：不发布也不学习
+	tr := testTransfer(other, 5_000_000)
+	if hit, err := p.handleTransfer(context.Background(), tr, true); err != nil || hit {
+		t.Errorf("unwatched transfer: handleTransfer = %v, %v", hit, err)
+	}
+	if len(p.state.Counterparties) != 0 {
+		t.Errorf("unwatched transfer learned %v", p.state.Counterparties)
+	}
+
+	// 转出：不发布，但记住收款方
+	tr = testTransfer(peer, 5_000_000)
+	tr.From = testWatched
+	if hit, err := p.handleTransfer(context.Background(), tr, true); err != nil || hit {
+		t.Errorf("outgoing transfer: handleTransfer = %v, %v", hit, err)
+	}
+	if len(p.state.Counterparties) != 1 || p.state.Counterparties[0] != peer {
+		t.Errorf("outgoing transfer learned %v, want [%s]", p.state.Counterparties, peer)
+	}
+	select {
+	case ev := <-ch.C():
+		t.Errorf("published %+v", ev)
+	default:
+	}
+}
+
+func TestHandleTransferZeroValue(t *testing.T) {
+	ch := sink.NewChannel(1)
+	p := testPoller(t, ch)
+
+	// 数据源不带签名者：0 金额转账只标记 zero_value，不会判为 fake_transfer_from
+	if hit, err := p.handleTransfer(context.Background(), testTransfer(testWatched, 0), true); err != nil || !hit {
+		t.Fatalf("handleTransfer = %v, %v", hit, err)
+	}
+	ev := <-ch.C()
+	if ev.Type != EventTypeFlagged || ev.Data["risk"] != "zero_value" {
+		t.Errorf("event type=%s risk=%q, want %s zero_value", ev.Type, ev.Data["risk"], EventTypeFlagged)
+	}
+}
+
+func TestHandleTransferPublishError(t *testing.T) {
+	want := errors.New("sink down")
+	p := testPoller(t, failingSink{want})
+	hit, err := p.handleTransfer(context.Background(), testTransfer(testWatched, 5_000_000), true)
+	if !hit || !errors.Is(err, want) {
+		t.Errorf("handleTransfer = %v, %v; want the publish error", hit, err)
+	}
+}
+
+func TestClassifyTransportError(t *testing.T) {
+	if err := classifyTransportError(context.Canceled); err != context.Canceled {
+		t.Errorf("cancellation classified as %v", err)
+	}
+	if err := classifyTransportError(context.DeadlineExceeded); !IsRetryable(err) {
+		t.Errorf("timeout classified as %v", err)
+	}
+	if err := classifyStatus(401, "bad key"); IsRetryable(err) || !source.IsFatal(err) {
+		t.Errorf("401 classified as %v", err)
+	}
+}
+
+func TestCallSourceFatal(t *testing.T) {
+	p := testPoller(t, sink.NewChannel(1))
+	calls := 0
+	err := p.callSource(context.Background(), "head", func(context.Context) error {
+		calls++
+		return classifyStatus(403, "forbidden")
+	})
+	if calls != 1 || !source.IsFatal(err) {
+		t.Errorf("callSource = %v after %d calls, want the fatal error at once", err, calls)
+	}
+	if st := p.stats.Snapshot(); st.FatalErrors != 1 || st.RetryableErrors != 0 {
+		t.Errorf("stats: %d fatal, %d retryable", st.FatalErrors, st.RetryableErrors)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestGridSourceDecodeErrors(t *testing.T) {
+	// 第二个事件的 to 地址被截断，无法解析
+	broken := strings.Replace(transferPayload, `"to": "0xa614f803b6fd780986a42c78ec9c7f77e6ded13c"`, `"to": "0xa614"`, 1)
+	broken = strings.Replace(broken, `"event_index": 0`, `"event_index": 1`, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/walletsolidity/") {
+			_, _ = w.Write([]byte(`{"blockID": "00", "block_header": {"raw_data": {"number": 62913164, "timestamp": 1718000001000}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [` + transferPayload + `,` + broken + `], "meta": {}, "success": true}`))
+	}))
+	defer srv.Close()
+
+	var failed int
+	g := &gridSource{
+		watcher:      &EventWatcher{restyClient: resty.New(), baseURL: srv.URL},
+		pageSize:     200,
+		decodeFailed: func() { failed++ },
+	}
+	out, err := g.Transfers(context.Background(), []string{usdtBase58}, 62913164, 62913164)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].LogIndex != 0 {
+		t.Errorf("transfers = %+v", out)
+	}
+	if failed != 1 || g.DecodeErrors() != 1 {
+		t.Errorf("decode failures: hook %d, counter %d; want 1", failed, g.DecodeErrors())
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/yourname/tron-demo/statefile"
+)
+
+// watcherState is what the poller checkpoints to the state file
+type watcherState struct {
+	// LastEnd is the max timestamp of the last fully processed window per contract
+	LastEnd map[string]int64 `json:"last_end"`
+	// InFlight holds windows that were interrupted mid-pagination, per contract
+	InFlight map[string]*cursor `json:"in_flight,omitempty"`
+	// Counterparties are addresses the watched addresses transacted with,
+	// used to recognise lookalike senders (address poisoning)
+	Counterparties []string `json:"counterparties,omitempty"`
+	// NextBlock is the next block to process when reading from a block based
+	// source (node/failover); it is shared by all sources so a switch keeps the position
+	NextBlock int64 `json:"next_block,omitempty"`
+}
+
+func newWatcherState() *watcherState {
+	return &watcherState{
+		LastEnd:  make(map[string]int64),
+		InFlight: make(map[string]*cursor),
+	}
+}
+
+// loadState reads the state file; a missing file yields an empty state
+func loadState(path string) (*watcherState, error) {
+	st := newWatcherState()
+	if path == "" {
+		return st, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+	if st.LastEnd == nil {
+		st.LastEnd = make(map[string]int64)
+	}
+	if st.InFlight == nil {
+		st.InFlight = make(map[string]*cursor)
+	}
+	return st, nil
+}
+
+// save writes the state atomically so a crash never leaves a torn file
+func (s *watcherState) save(path string) error {
+	return statefile.WriteJSON(path, s)
+}
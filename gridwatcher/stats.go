@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats tracks the health of the poll loop so failures are visible without crashing
+type Stats struct {
+	mu                  sync.Mutex
+	PagesFetched        int64
+	EventsProcessed     int64
+	EventsMatched       int64
+	RetryableErrors     int64
+	FatalErrors         int64
+	ConsecutiveFailures int64
+	BreakerOpens        int64
+	DecodeErrors        int64
+	LastError           string
+	LastErrorAt         time.Time
+	LastSuccessAt       time.Time
+	LastAttemptAt       time.Time
+}
+
+func (s *Stats) attempted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastAttemptAt = time.Now()
+}
+
+func (s *Stats) fetchSucceeded(events, matched int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.PagesFetched++
+	s.EventsProcessed += int64(events)
+	s.EventsMatched += int64(matched)
+	s.ConsecutiveFailures = 0
+	s.LastSuccessAt = time.Now()
+}
+
+func (s *Stats) fetchFailed(err error, class ErrorClass, breakerOpened bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if class == ErrorFatal {
+		s.FatalErrors++
+	} else {
+		s.RetryableErrors++
+	}
+	s.ConsecutiveFailures++
+	if breakerOpened {
+		s.BreakerOpens++
+	}
+	s.LastError = err.Error()
+	s.LastErrorAt = time.Now()
+}
+
+func (s *Stats) decodeFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.DecodeErrors++
+}
+
+// Snapshot returns a copy of the counters
+func (s *Stats) Snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		PagesFetched:        s.PagesFetched,
+		EventsProcessed:     s.EventsProcessed,
+		EventsMatched:       s.EventsMatched,
+		RetryableErrors:     s.RetryableErrors,
+		FatalErrors:         s.FatalErrors,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		BreakerOpens:        s.BreakerOpens,
+		DecodeErrors:        s.DecodeErrors,
+		LastError:           s.LastError,
+		LastErrorAt:         s.LastErrorAt,
+		LastSuccessAt:       s.LastSuccessAt,
+		LastAttemptAt:       s.LastAttemptAt,
+	}
+}
@@ -1,7 +1,5 @@
 package main
 
-import "strings"
-
 // TronGridResp represents the response from TronGrid API
 type TronGridResp struct {
 	Data []Event `json:"data"`
@@ -12,31 +10,18 @@ type TronGridResp struct {
 	Success bool `json:"success"`
 }
 
-// Event represents a TronGrid event
+// Event represents a TronGrid event.
+// Use Decode or the typed accessors (Transfer, Approval, ...) instead of reading Result directly.
 type Event struct {
-	BlockNumber    int64                  `json:"block_number"`
-	BlockTimestamp int64                  `json:"block_timestamp"`
-	EventIndex     int64                  `json:"event_index"`
-	EventName      string                 `json:"event_name"`
-	TransactionID  string                 `json:"transaction_id"`
-	Unconfirmed    bool                   `json:"_unconfirmed"`
-	Result         map[string]interface{} `json:"result"`
-}
-
-// ToHex returns the "to" address in hex format (lowercase)
-func (e Event) ToHex() string {
-	v, _ := e.Result["to"].(string)
-	return strings.ToLower(v)
-}
-
-// FromHex returns the "from" address in hex format (lowercase)
-func (e Event) FromHex() string {
-	v, _ := e.Result["from"].(string)
-	return strings.ToLower(v)
-}
-
-// ValueStr returns the "value" as a string
-func (e Event) ValueStr() string {
-	v, _ := e.Result["value"].(string)
-	return v
+	BlockNumber     int64                  `json:"block_number"`
+	BlockTimestamp  int64                  `json:"block_timestamp"`
+	CallerAddress   string                 `json:"caller_contract_address"`
+	ContractAddress string                 `json:"contract_address"`
+	EventIndex      int64                  `json:"event_index"`
+	EventName       string                 `json:"event_name"`
+	Signature       string                 `json:"event"`
+	TransactionID   string                 `json:"transaction_id"`
+	Unconfirmed     bool                   `json:"_unconfirmed"`
+	Result          map[string]interface{} `json:"result"`
+	ResultType      map[string]string      `json:"result_type"`
 }
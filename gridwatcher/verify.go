@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourname/tron-demo/config"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/source"
+)
+
+// verifier periodically compares TronGrid with the full node over the most
+// recent blocks both consider confirmed, so silent gaps in either show up in
+// metrics before they show up as missing deposits.
+type verifier struct {
+	grid   source.TransferSource
+	node   source.TransferSource
+	tokens []string
+	every  time.Duration
+	blocks int64
+}
+
+func newVerifier(cfg *config.Config, watcher *EventWatcher, every time.Duration, blocks int64) (*verifier, error) {
+	node, err := newNodeSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{
+		grid:   &gridSource{watcher: watcher, pageSize: cfg.TronGrid.PageSize},
+		node:   node,
+		tokens: cfg.Watch.Tokens,
+		every:  every,
+		blocks: max(blocks, 1),
+	}, nil
+}
+
+// Run checks every v.every until ctx is cancelled
+func (v *verifier) Run(ctx context.Context) {
+	for {
+		if err := sleepCtx(ctx, v.every); err != nil {
+			return
+		}
+		to, err := v.commonHead(ctx)
+		if err != nil {
+			metrics.VerifyRuns.WithLabelValues("error").Inc()
+			log.Printf("[verify] Failed to get heads: %v", err)
+			continue
+		}
+		r, err := v.check(ctx, to-v.blocks+1, to)
+		if err != nil {
+			log.Printf("[verify] Blocks %d-%d: %v", to-v.blocks+1, to, err)
+			continue
+		}
+		if r.OK() {
+			log.Printf("[verify] Blocks %d-%d consistent: %d transfers", r.From, r.To, r.Matched)
+			continue
+		}
+		var b strings.Builder
+		_ = r.WriteText(&b)
+		log.Printf("[verify] Sources disagree:\n%s", b.String())
+	}
+}
+
+// commonHead is the highest block both sources serve as confirmed
+func (v *verifier) commonHead(ctx context.Context) (int64, error) {
+	gh, err := v.grid.Head(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", v.grid.Name(), err)
+	}
+	nh, err := v.node.Head(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", v.node.Name(), err)
+	}
+	return min(gh, nh), nil
+}
+
+// check compares [from, to] and records the outcome in metrics
+func (v *verifier) check(ctx context.Context, from, to int64) (*source.Report, error) {
+	r, err := source.Verify(ctx, v.grid, v.node, v.tokens, from, to)
+	if err != nil {
+		metrics.VerifyRuns.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.VerifyDiscrepancies.WithLabelValues("only_" + r.SourceA).Add(float64(len(r.OnlyA)))
+	metrics.VerifyDiscrepancies.WithLabelValues("only_" + r.SourceB).Add(float64(len(r.OnlyB)))
+	metrics.VerifyDiscrepancies.WithLabelValues("mismatched").Add(float64(len(r.Mismatched)))
+	metrics.VerifyDiscrepancies.WithLabelValues("undecodable_" + r.SourceA).Add(float64(r.UndecodedA))
+	metrics.VerifyDiscrepancies.WithLabelValues("undecodable_" + r.SourceB).Add(float64(r.UndecodedB))
+	result := "ok"
+	if !r.OK() {
+		result = "mismatch"
+	}
+	metrics.VerifyRuns.WithLabelValues(result).Inc()
+	return r, nil
+}
+
+// runVerify is the one-shot CLI: it prints the diff report for [from, to] and
+// returns the process exit code (0 consistent, 1 differences, 2 error)
+func runVerify(cfg *config.Config, watcher *EventWatcher, from, to int64) int {
+	v, err := newVerifier(cfg, watcher, 0, 0)
+	if err != nil {
+		log.Printf("[verify] %v", err)
+		return 2
+	}
+	ctx := context.Background()
+	if to == 0 {
+		if to, err = v.commonHead(ctx); err != nil {
+			log.Printf("[verify] %v", err)
+			return 2
+		}
+	}
+	r, err := v.check(ctx, from, to)
+	if err != nil {
+		log.Printf("[verify] %v", err)
+		return 2
+	}
+	if err := r.WriteText(os.Stdout); err != nil {
+		log.Printf("[verify] %v", err)
+		return 2
+	}
+	if !r.OK() {
+		return 1
+	}
+	return 0
+}
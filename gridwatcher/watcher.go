@@ -2,22 +2,38 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
 	"github.com/go-resty/resty/v2"
+	"github.com/yourname/tron-demo/archive"
+	"github.com/yourname/tron-demo/config"
+	"github.com/yourname/tron-demo/health"
+	"github.com/yourname/tron-demo/lifecycle"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+	"github.com/yourname/tron-demo/source"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // ---------- Fetch + filter ----------
 
 type EventWatcher struct {
 	restyClient *resty.Client
+	baseURL     string
 }
 
 // FetchTransferEvents fetches Transfer events from TronGrid API
 // Parameters:
 //   - ctx: context for cancellation
+//   - contract: TRC20 contract address (base58)
 //   - fingerprint: pagination token from previous response (empty for first page)
 //   - onlyConfirmed: if true, only returns confirmed events
 //   - limit: max number of events per page (default 20, max 200)
@@ -26,8 +42,8 @@ type EventWatcher struct {
 //   - orderBy: sort order, "block_timestamp,desc" (newest first) or "block_timestamp,asc" (oldest first)
 //
 // API Reference: https://developers.tron.network/reference/get-events-by-contract-address
-func (w *EventWatcher) FetchTransferEvents(ctx context.Context, fingerprint string, onlyConfirmed bool, limit int, minBlockTimestamp, maxBlockTimestamp int64, orderBy string) (*TronGridResp, error) {
-	url := fmt.Sprintf(TronGridUrl, UsdtContract)
+func (w *EventWatcher) FetchTransferEvents(ctx context.Context, contract, fingerprint string, onlyConfirmed bool, limit int, minBlockTimestamp, maxBlockTimestamp int64, orderBy string) (*TronGridResp, error) {
+	url := w.baseURL + fmt.Sprintf(ContractEventsPath, contract)
 
 	req := w.restyClient.R().
 		SetContext(ctx).
@@ -61,20 +77,23 @@ func (w *EventWatcher) FetchTransferEvents(ctx context.Context, fingerprint stri
 		log.Printf("[FetchTransferEvents] Using order_by: %s", orderBy)
 	}
 
-	log.Printf("[FetchTransferEvents] Requesting events: onlyConfirmed=%v, limit=%d, minBlockTimestamp=%d, maxBlockTimestamp=%d, orderBy=%s",
-		onlyConfirmed, limit, minBlockTimestamp, maxBlockTimestamp, orderBy)
+	log.Printf("[FetchTransferEvents] Requesting events: contract=%s, onlyConfirmed=%v, limit=%d, minBlockTimestamp=%d, maxBlockTimestamp=%d, orderBy=%s",
+		contract, onlyConfirmed, limit, minBlockTimestamp, maxBlockTimestamp, orderBy)
 
 	var result TronGridResp
+	start := time.Now()
 	resp, err := req.SetResult(&result).Get(url)
 
 	if err != nil {
+		metrics.ObserveTronGrid(metricsEndpointEvents, 0, start)
 		log.Printf("[FetchTransferEvents] Request failed: %v", err)
-		return nil, err
+		return nil, classifyTransportError(err)
 	}
+	metrics.ObserveTronGrid(metricsEndpointEvents, resp.StatusCode(), start)
 
 	if !resp.IsSuccess() {
 		log.Printf("[FetchTransferEvents] HTTP error: status=%d, body=%s", resp.StatusCode(), resp.String())
-		return nil, fmt.Errorf("http %d: %s", resp.StatusCode(), resp.String())
+		return nil, classifyStatus(resp.StatusCode(), resp.String())
 	}
 
 	log.Printf("[FetchTransferEvents] Success: received %d events, fingerprint=%s", len(result.Data), result.Meta.Fingerprint)
@@ -82,118 +101,192 @@ func (w *EventWatcher) FetchTransferEvents(ctx context.Context, fingerprint stri
 }
 
 func main() {
-	log.Println("[main] Starting TronGrid event watcher")
+	backfillFrom := flag.String("backfill-from", "", "re-scan history from this time (RFC3339, YYYY-MM-DD or unix ms) instead of watching live")
+	backfillTo := flag.String("backfill-to", "", "end of the backfill range (default: the end of the resumed run, or now)")
+	backfillWindow := flag.Duration("backfill-window", time.Hour, "size of the sub-windows a backfill is split into")
+	backfillWorkers := flag.Int("backfill-workers", 4, "sub-windows fetched in parallel during a backfill")
+	backfillMaxPages := flag.Int("backfill-max-pages", 50, "pages per sub-window before it is split to stay within TronGrid's pagination depth")
+	backfillState := flag.String("backfill-state", "", "backfill progress file for resuming (default: <state>.backfill or gridwatcher.backfill.json)")
+	verifyFrom := flag.Int64("verify-from", 0, "compare TronGrid with the full node from this block, print a diff report and exit")
+	verifyTo := flag.Int64("verify-to", 0, "last block of -verify-from (default: confirmed head)")
+	verifyEvery := flag.Duration("verify-every", 0, "periodically compare TronGrid with the full node over recent blocks; 0 disables")
+	verifyBlocks := flag.Int64("verify-blocks", 200, "number of recent confirmed blocks checked by -verify-every")
+	replayFrom := flag.Int64("replay-from", 0, "re-run the transfers archived from this block through the watcher instead of watching, then exit")
+	replayTo := flag.Int64("replay-to", 0, "last block of -replay-from (default: last archived block)")
+	replaySpeed := flag.Float64("replay-speed", 0, "replay pace relative to the original block times, e.g. 1 or 10; 0 replays at full speed")
+	replayDryRun := flag.Bool("replay-dry-run", false, "publish nothing; diff the replayed events against those recorded in the archive")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("[main] %v", err)
+	}
+	// 重放只读归档，不需要 TronGrid
+	if *replayFrom != 0 {
+		os.Exit(runReplay(cfg, *replayFrom, *replayTo, *replaySpeed, *replayDryRun))
+	}
+	if err := cfg.RequireTronGridKey(); err != nil {
+		log.Fatalf("[main] %v", err)
+	}
+	log.Println("[main] API Key configured")
 
-	// 1) 你的关注地址池（示例：base58）
-	watchBase58 := []string{
-		"TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF", // Binance-Cold 4
+	log.Printf("[main] Starting TronGrid event watcher on %s (%s)", cfg.Profile.Name, cfg.TronGrid.BaseURL)
+	if cfg.Profile.Testnet {
+		log.Printf("[main] WARNING: %s is a testnet, events must not be credited as real funds", cfg.Profile.Name)
 	}
 
-	log.Printf("[main] Monitoring %d addresses", len(watchBase58))
+	events, err := sink.Open(cfg.Sinks)
+	if err != nil {
+		log.Fatalf("[main] Failed to open event sink: %v", err)
+	}
+	events = metrics.InstrumentSink(cfg.Sinks, events)
+	log.Printf("[main] Publishing events to: %s", cfg.Sinks)
+	if cfg.Archive.Path != "" {
+		// 发布过的事件记入归档，重放时用来对比
+		store, err := archive.Open(cfg.Archive.Path, archive.Options{})
+		if err != nil {
+			log.Fatalf("[main] %v", err)
+		}
+		defer store.Close()
+		events = sink.Multi(events, store.Sink())
+		log.Printf("[main] Recording published events in %s", cfg.Archive.Path)
+	}
+
+	// 1) 你的关注地址池（base58，来自配置；为空时监控全部转账）
+	log.Printf("[main] Monitoring %d addresses on %d tokens", len(cfg.Watch.Addresses), len(cfg.Watch.Tokens))
 
-	// 2) 预处理成 hex set
-	watchHex := make(map[string]struct{}, len(watchBase58))
-	for _, a := range watchBase58 {
+	// 2) 预处理成 set（事件解码后地址统一为 base58）
+	watch := make(map[string]struct{}, len(cfg.Watch.Addresses))
+	for _, a := range cfg.Watch.Addresses {
 		h, err := TronBase58ToEvmHex(a)
 		if err != nil {
 			log.Fatalf("[main] Failed to convert address %s: %v", a, err)
 		}
-		watchHex[h] = struct{}{}
+		watch[a] = struct{}{}
 		log.Printf("[main] Watching address: base58=%s, hex=%s", a, h)
 	}
 
-	if TronGridAPIKey == "" {
-		log.Fatal("[main] API Key is empty, please set a valid TronGrid API Key")
-	}
-	log.Println("[main] API Key configured")
-
 	restyClient := resty.New().
-		SetTimeout(DefaultTimeout).
-		SetHeader(HeaderTronProAPIKey, TronGridAPIKey)
+		SetTimeout(cfg.TronGrid.Timeout).
+		SetHeader(HeaderTronProAPIKey, cfg.TronGrid.APIKey)
 
 	watcher := &EventWatcher{
 		restyClient: restyClient,
+		baseURL:     strings.TrimRight(cfg.TronGrid.BaseURL, "/"),
 	}
 
-	ctx := context.Background()
-	// 监控从当前时间往前推指定时间窗口到当前时间的全部转账交易
-	orderBy := "block_timestamp,desc" // 降序：最新的在前
-
-	log.Printf("[main] Starting to monitor events from (current time - %v) to current time", LookbackWindow)
+	if *verifyFrom != 0 {
+		os.Exit(runVerify(cfg, watcher, *verifyFrom, *verifyTo))
+	}
 
-	for {
-		// 外层循环：每次重新计算时间窗口
-		// 固定时间窗口，避免在分页过程中时间窗口变化导致 fingerprint 失效
-		currentTime := time.Now()
-		startTime := currentTime.Add(-LookbackWindow)
-		minBlockTimestamp := startTime.UnixMilli()
-		maxBlockTimestamp := currentTime.UnixMilli()
+	p, err := newPoller(cfg, watcher, events, watch)
+	if err != nil {
+		log.Fatalf("[main] %v", err)
+	}
 
-		log.Printf("[main] Starting new time window: %s to %s", startTime.Format(time.RFC3339), currentTime.Format(time.RFC3339))
+	if *backfillFrom != "" {
+		runBackfill(cfg, p, events, *backfillFrom, *backfillTo, *backfillState, *backfillWindow, *backfillWorkers, *backfillMaxPages)
+		return
+	}
 
-		// 内层循环：使用固定的时间窗口进行分页
-		fingerprint := ""
-		pageCount := 0
-		for {
-			pageCount++
-			log.Printf("[main] Fetching page %d (time range: %s to %s)", pageCount,
-				startTime.Format(time.RFC3339),
-				currentTime.Format(time.RFC3339))
+	src, err := newTransferSource(cfg, watcher, p.stats.decodeFailed)
+	if err != nil {
+		log.Fatalf("[main] %v", err)
+	}
+	if src == nil {
+		log.Printf("[main] Starting to monitor events from (current time - %v) to current time", cfg.Watch.LookbackWindow)
+	} else {
+		log.Printf("[main] Reading confirmed blocks from source %s", cfg.Source.Kind)
+	}
 
-			r, err := watcher.FetchTransferEvents(ctx, fingerprint, true /*onlyConfirmed*/, DefaultLimit, minBlockTimestamp, maxBlockTimestamp, orderBy)
+	// SIGINT/SIGTERM 取消 ctx：当前页处理完、游标落盘后退出
+	err = lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		health.Register(mux, p, health.Options{MaxLag: cfg.Health.MaxLag, StallTimeout: cfg.Health.StallTimeout})
+		go lifecycle.ServeHTTP(ctx, cfg.ListenAddr, mux)
+		if *verifyEvery > 0 {
+			v, err := newVerifier(cfg, watcher, *verifyEvery, *verifyBlocks)
 			if err != nil {
-				log.Fatalf("[main] Failed to fetch events: %v", err)
+				return err
 			}
+			go v.Run(ctx)
+		}
+		if src != nil {
+			return p.RunSource(ctx, src)
+		}
+		return p.Run(ctx)
+	})
+	if cerr := events.Close(); cerr != nil {
+		log.Printf("[main] Failed to flush event sink: %v", cerr)
+	}
+	if err != nil {
+		log.Fatalf("[main] Watcher stopped: %v", err)
+	}
+	log.Println("[main] Watcher stopped")
+}
 
-			log.Printf("[main] Processing %d events from page %d", len(r.Data), pageCount)
-
-			matchedCount := 0
-			// 处理事件
-			for _, ev := range r.Data {
-				// 只处理 Transfer（我们请求里已经指定 Transfer，这里再保险）
-				if ev.EventName != EventNameTransfer {
-					continue
-				}
-
-				to := ev.ToHex()
-				from := ev.FromHex()
-
-				// 只监控to地址的交易
-				isToWatched := true
-				//if _, ok := watchHex[to]; ok {
-				//	isToWatched = true
-				//}
-
-				// 如果 to 是监控地址，则处理
-				if isToWatched {
-					matchedCount++
-					// 幂等键：txid + event_index
-					idempotencyKey := fmt.Sprintf("%s%s%d", ev.TransactionID, IdempotencyKeySeparator, ev.EventIndex)
-					eventType := "DEPOSIT"
-					log.Printf("[main] %s hit: to=%s from=%s value=%s tx=%s confirmed=%v key=%s",
-						eventType, to, from, ev.ValueStr(), ev.TransactionID, !ev.Unconfirmed, idempotencyKey)
-
-					// TODO: 入库（unique(idempotencyKey)）+ 触发入账流程
-				}
-			}
+// runBackfill re-scans a past range instead of running the live loop.
+// It does not touch the live cursor state, so it may run next to a live watcher.
+func runBackfill(cfg *config.Config, p *poller, events sink.EventSink, from, to, statePath string, window time.Duration, workers, maxPages int) {
+	start, err := parseTime(from)
+	if err != nil {
+		log.Fatalf("[main] -backfill-from: %v", err)
+	}
+	// 不指定结束时间时由 newBackfiller 沿用进度文件里的结束时间（新任务为当前时间）
+	var end time.Time
+	if to != "" {
+		if end, err = parseTime(to); err != nil {
+			log.Fatalf("[main] -backfill-to: %v", err)
+		}
+	}
+	if statePath == "" {
+		statePath = "gridwatcher.backfill.json"
+		if cfg.StateFile != "" {
+			statePath = cfg.StateFile + ".backfill"
+		}
+	}
 
-			if matchedCount > 0 {
-				log.Printf("[main] Found %d matched events on page %d", matchedCount, pageCount)
-			}
+	b, err := newBackfiller(p, statePath, start, end, window, workers, maxPages)
+	if err != nil {
+		log.Fatalf("[main] %v", err)
+	}
+	err = lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go lifecycle.ServeHTTP(ctx, cfg.ListenAddr, mux)
+		return b.Run(ctx)
+	})
+	if cerr := events.Close(); cerr != nil {
+		log.Printf("[main] Failed to flush event sink: %v", cerr)
+	}
+	if err != nil {
+		log.Fatalf("[main] Backfill stopped: %v", err)
+	}
+}
 
-			// 如果到达数据末尾（没有更多数据），退出内层循环，重新计算时间窗口
-			if r.Meta.Fingerprint == "" || len(r.Data) == 0 {
-				log.Printf("[main] Reached end of current time window: fingerprint=%s, dataCount=%d", r.Meta.Fingerprint, len(r.Data))
-				break
-			}
+// newTransferSource returns the block based source selected by cfg.Source.Kind,
+// or nil for the default TronGrid time window loop. decodeFailed is called for
+// every TronGrid event that could not be decoded.
+func newTransferSource(cfg *config.Config, watcher *EventWatcher, decodeFailed func()) (source.TransferSource, error) {
+	if cfg.Source.Kind == config.SourceTronGrid {
+		return nil, nil
+	}
 
-			// 继续分页获取（使用固定的时间窗口）
-			fingerprint = r.Meta.Fingerprint
-			log.Printf("[main] Continuing to next page with fingerprint: %s", fingerprint)
-		}
+	node, err := newNodeSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Source.Kind == config.SourceNode {
+		return node, nil
+	}
+	grid := &gridSource{watcher: watcher, pageSize: cfg.TronGrid.PageSize, decodeFailed: decodeFailed}
+	return source.NewFailover(cfg.Source.FailoverAfter, cfg.Source.RetryPrimary, grid, node), nil
+}
 
-		// 完成当前时间窗口的所有分页后，等待一段时间，然后重新计算新的时间窗口
-		log.Printf("[main] Completed time window, waiting %v before checking next time window", PollInterval)
-		time.Sleep(PollInterval)
+// newNodeSource connects to the configured full node
+func newNodeSource(cfg *config.Config) (*source.Node, error) {
+	c := client.NewGrpcClient(cfg.GRPCEndpoint)
+	if err := c.Start(grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
+		return nil, fmt.Errorf("start grpc client %s: %w", cfg.GRPCEndpoint, err)
 	}
+	return source.NewNode(c, cfg.Profile.SolidifyDepth), nil
 }
@@ -0,0 +1,124 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Status is the snapshot a watcher reports on /status
+type Status struct {
+	Watcher   string   `json:"watcher"`
+	Network   string   `json:"network"`
+	Tokens    []string `json:"tokens"`
+	Addresses int      `json:"watched_addresses"`
+
+	// Cursor is watcher specific: per-contract windows for gridwatcher, the next block for monitor
+	Cursor      any   `json:"cursor"`
+	CursorBlock int64 `json:"cursor_block"`
+	ChainHead   int64 `json:"chain_head"`
+
+	LagSeconds float64 `json:"lag_seconds"`
+	LagBlocks  int64   `json:"lag_blocks"`
+
+	StartedAt    time.Time `json:"started_at"`
+	LastActivity time.Time `json:"last_activity"`
+	LastSuccess  time.Time `json:"last_success"`
+
+	Errors              map[string]int64 `json:"errors"`
+	ConsecutiveFailures int64            `json:"consecutive_failures"`
+	LastError           string           `json:"last_error,omitempty"`
+	Breaker             string           `json:"breaker,omitempty"`
+
+	// Throughput is reported by watchers that process whole blocks
+	Throughput *Throughput `json:"throughput,omitempty"`
+}
+
+// Throughput is the processing rate over the last reporting interval
+type Throughput struct {
+	BlocksPerSecond       float64 `json:"blocks_per_second"`
+	TransactionsPerSecond float64 `json:"transactions_per_second"`
+}
+
+// Reporter is implemented by watchers that expose their status
+type Reporter interface {
+	Status() Status
+}
+
+// ReporterFunc adapts a function to Reporter
+type ReporterFunc func() Status
+
+// Status implements Reporter
+func (f ReporterFunc) Status() Status {
+	return f()
+}
+
+// Options controls when the probes fail
+type Options struct {
+	// MaxLag makes /readyz fail once the cursor is further behind than this
+	MaxLag time.Duration
+	// StallTimeout makes /healthz fail when the loop has not done anything for this long
+	StallTimeout time.Duration
+}
+
+// Register adds /healthz, /readyz and /status to mux
+func Register(mux *http.ServeMux, r Reporter, opts Options) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		st := r.Status()
+		if err := Live(st, opts); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		st := r.Status()
+		if err := Ready(st, opts); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		st := r.Status()
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Status
+			Live  string `json:"live"`
+			Ready string `json:"ready"`
+		}{st, probeText(Live(st, opts)), probeText(Ready(st, opts))})
+	})
+}
+
+// Live reports whether the watcher loop is still making attempts
+func Live(st Status, opts Options) error {
+	last := st.LastActivity
+	if last.IsZero() {
+		last = st.StartedAt
+	}
+	if opts.StallTimeout > 0 && time.Since(last) > opts.StallTimeout {
+		return fmt.Errorf("no activity for %v", time.Since(last).Round(time.Second))
+	}
+	return nil
+}
+
+// Ready reports whether the watcher is caught up enough to be relied on
+func Ready(st Status, opts Options) error {
+	if st.LastSuccess.IsZero() {
+		return fmt.Errorf("no successful fetch yet")
+	}
+	if opts.MaxLag > 0 && st.LagSeconds > opts.MaxLag.Seconds() {
+		return fmt.Errorf("lag %.0fs exceeds %v", st.LagSeconds, opts.MaxLag)
+	}
+	return nil
+}
+
+func probeText(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, mux *http.ServeMux, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestProbes(t *testing.T) {
+	st := Status{Watcher: "monitor", StartedAt: time.Now()}
+	mux := http.NewServeMux()
+	Register(mux, ReporterFunc(func() Status { return st }), Options{MaxLag: time.Minute, StallTimeout: 10 * time.Minute})
+
+	steps := []struct {
+		name            string
+		update          func()
+		healthz, readyz int
+		readyzContains  string
+	}{
+		{"just started", func() {}, 200, 503, "no successful fetch yet"},
+		{"caught up", func() {
+			st.LastActivity = time.Now()
+			st.LastSuccess = time.Now()
+			st.LagSeconds = 3
+		}, 200, 200, "ok"},
+		{"lag at the limit", func() { st.LagSeconds = 60 }, 200, 200, "ok"},
+		{"lag over the limit", func() { st.LagSeconds = 61 }, 200, 503, "lag 61s exceeds 1m0s"},
+		{"stalled", func() { st.LastActivity = time.Now().Add(-11 * time.Minute) }, 503, 503, "exceeds"},
+		{"recovered", func() {
+			st.LastActivity = time.Now()
+			st.LagSeconds = 0
+		}, 200, 200, "ok"},
+	}
+	for _, s := range steps {
+		s.update()
+		if code, body := get(t, mux, "/healthz"); code != s.healthz {
+			t.Errorf("%s: /healthz = %d %q, want %d", s.name, code, body, s.healthz)
+		}
+		code, body := get(t, mux, "/readyz")
+		if code != s.readyz || !strings.Contains(body, s.readyzContains) {
+			t.Errorf("%s: /readyz = %d %q, want %d containing %q", s.name, code, body, s.readyz, s.readyzContains)
+		}
+	}
+}
+
+// 尚无活动时按启动时间判断是否卡住
+func TestLiveBeforeFirstActivity(t *testing.T) {
+	opts := Options{StallTimeout: time.Minute}
+	if err := Live(Status{StartedAt: time.Now()}, opts); err != nil {
+		t.Errorf("Live just after start: %v", err)
+	}
+	if err := Live(Status{StartedAt: time.Now().Add(-2 * time.Minute)}, opts); err == nil {
+		t.Error("Live after a stalled start succeeded")
+	}
+	if err := Live(Status{StartedAt: time.Now().Add(-time.Hour)}, Options{}); err != nil {
+		t.Errorf("Live without a stall timeout: %v", err)
+	}
+}
+
+func TestReadyWithoutMaxLag(t *testing.T) {
+	if err := Ready(Status{LastSuccess: time.Now(), LagSeconds: 1e6}, Options{}); err != nil {
+		t.Errorf("Ready without max lag: %v", err)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	st := Status{Watcher: "gridwatcher", StartedAt: time.Now(), LastSuccess: time.Now(), LagSeconds: 120}
+	mux := http.NewServeMux()
+	Register(mux, ReporterFunc(func() Status { return st }), Options{MaxLag: time.Minute})
+
+	code, body := get(t, mux, "/status")
+	if code != http.StatusOK {
+		t.Fatalf("/status = %d", code)
+	}
+	var got struct {
+		Watcher string `json:"watcher"`
+		Live    string `json:"live"`
+		Ready   string `json:"ready"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Watcher != "gridwatcher" || got.Live != "ok" || !strings.Contains(got.Ready, "exceeds") {
+		t.Errorf("/status = %+v", got)
+	}
+}
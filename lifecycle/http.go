@@ -0,0 +1,36 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP serves handler on addr until ctx is cancelled, then shuts the server down.
+// An empty addr disables the server.
+func ServeHTTP(ctx context.Context, addr string, handler http.Handler) error {
+	if addr == "" {
+		return nil
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("[lifecycle] HTTP server listening on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[lifecycle] HTTP server on %s failed: %v", addr, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrShutdownTimeout is returned when fn does not return within the shutdown deadline
+var ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+
+// Run calls fn with a context that is cancelled on SIGINT or SIGTERM.
+// After the signal fn gets up to timeout to finish in-flight work and flush
+// its state; if it takes longer Run gives up and returns ErrShutdownTimeout.
+// A second signal also aborts the wait.
+func Run(timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case sig := <-sigs:
+		log.Printf("[lifecycle] Received %s, shutting down (deadline %v)", sig, timeout)
+		cancel()
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		log.Printf("[lifecycle] Shutdown complete")
+		return err
+	case <-timer.C:
+		return ErrShutdownTimeout
+	case sig := <-sigs:
+		return fmt.Errorf("received second %s: %w", sig, ErrShutdownTimeout)
+	}
+}
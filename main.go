@@ -1,28 +1,76 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"math/big"
+	"net/http"
+	"os"
 
 	"github.com/fbsobreira/gotron-sdk/pkg/account"
 	"github.com/fbsobreira/gotron-sdk/pkg/address"
 	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
 	"github.com/fbsobreira/gotron-sdk/pkg/store"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/archive"
 	"github.com/yourname/tron-demo/block"
+	"github.com/yourname/tron-demo/config"
+	"github.com/yourname/tron-demo/health"
+	"github.com/yourname/tron-demo/lifecycle"
+	"github.com/yourname/tron-demo/metrics"
 	"github.com/yourname/tron-demo/monitor"
+	"github.com/yourname/tron-demo/replay"
+	"github.com/yourname/tron-demo/sink"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
-	// 主网地址
-	tornEndpoint := "grpc.trongrid.io:50051"
-	gRPCWalletClient := client.NewGrpcClient(tornEndpoint)
-	err := gRPCWalletClient.Start(grpc.WithInsecure())
+	startFlag := flag.Int64("start", 0, "block number to start monitoring from (default: saved cursor, then the latest processable block)")
+	archiveFrom := flag.Int64("archive-from", 0, "archive blocks from this number into -archive instead of monitoring, then exit")
+	archiveTo := flag.Int64("archive-to", 0, "last block of -archive-from (default: keep following the chain)")
+	archiveServe := flag.Bool("archive-serve", false, "only serve the -archive query API on listen_addr, without a node")
+	replayFrom := flag.Int64("replay-from", 0, "re-run archived blocks from this number through the monitor instead of monitoring, then exit")
+	replayTo := flag.Int64("replay-to", 0, "last block of -replay-from (default: last archived block)")
+	replaySpeed := flag.Float64("replay-speed", 0, "replay pace relative to the original block times, e.g. 1 or 10; 0 replays at full speed")
+	replayDryRun := flag.Bool("replay-dry-run", false, "publish nothing; diff the replayed events against those recorded in the archive")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	// 本地归档：随监听写入，或单独归档一段区块 / 只提供查询
+	var store *archive.Store
+	if cfg.Archive.Path != "" {
+		store, err = archive.Open(cfg.Archive.Path, archive.Options{Contracts: cfg.Archive.Contracts})
+		if err != nil {
+			log.Fatalf("failed to open archive: %v", err)
+		}
+		defer store.Close()
+	} else if *archiveFrom != 0 || *archiveServe || *replayFrom != 0 {
+		log.Fatal("-archive-from, -archive-serve and -replay-from require an archive path (-archive)")
+	}
+	if *archiveServe {
+		serveArchive(cfg, store)
+		return
+	}
+
+	// 节点地址（来自配置，默认主网）
+	fmt.Printf("网络: %s, 节点: %s\n", cfg.Profile.Name, cfg.GRPCEndpoint)
+	gRPCWalletClient := client.NewGrpcClient(cfg.GRPCEndpoint)
+	err = gRPCWalletClient.Start(grpc.WithInsecure())
 	if err != nil {
 		log.Fatalf("failed to start grpc client: %v", err)
 		return
 	}
+	if *replayFrom != 0 {
+		code := runReplay(cfg, gRPCWalletClient, store, *replayFrom, *replayTo, *replaySpeed, *replayDryRun)
+		store.Close()
+		os.Exit(code)
+	}
 
 	//err = getAccountInfo(gRPCWalletClient, "TRvzGHTsfgbVkrFjCovFtyLU4HBd3u6Fdw")
 	//if err != nil {
@@ -30,18 +78,18 @@ func main() {
 	//	return
 	//}
 	//
-	//设置合约地址（USDT合约在主网的地址）
-	contractAddr := "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" // TRC20 USDT
-	// 设置用户地址
-	userAddress := "TRvzGHTsfgbVkrFjCovFtyLU4HBd3u6Fdw"
+	// 查询关注地址在各个关注代币合约上的余额
+	for _, userAddress := range cfg.Watch.Addresses {
+		for _, contractAddr := range cfg.Watch.Tokens {
+			balance, err := getTRC20Balance(gRPCWalletClient, userAddress, contractAddr, cfg.Profile.Decimals(contractAddr))
+			if err != nil {
+				log.Fatalf("获取TRC20余额失败: %v", err)
+			}
 
-	balance, err := getTRC20Balance(gRPCWalletClient, userAddress, contractAddr)
-	if err != nil {
-		log.Fatalf("获取USDT余额失败: %v", err)
+			fmt.Printf("%s 在合约 %s 上的余额: %s\n", userAddress, contractAddr, balance)
+		}
 	}
 
-	fmt.Printf("USDT余额: %s (单位：6位精度)\n", balance)
-
 	//TBk1CRZnfBqpY7Wr9DbfdJfpdVf3nGgk16
 	//behind sound trust make tray steak game jeans regret three coil dog hole cinnamon flat cart antique valley canyon laundry dinosaur real fuel potato
 	//createAccount()
@@ -57,11 +105,68 @@ func main() {
 	}
 	fmt.Println("当前区块高度：", num)
 
-	//从当前最新高度开始监听
-	startBlock := num // 例如你查询过的某个起始区块高度
-	err = monitor.MonitorBlockEvents(gRPCWalletClient, startBlock)
+	if *archiveFrom != 0 {
+		if err := runArchive(cfg, gRPCWalletClient, store, *archiveFrom, *archiveTo); err != nil {
+			store.Close()
+			log.Fatalf("archive failed: %v", err)
+		}
+		return
+	}
+
+	//-start 优先，其次是上次保存的游标，都没有时从最新的可处理区块开始
+	cursor, err := monitor.LoadCursor(cfg.Monitor.StateFile)
+	if err != nil {
+		log.Fatalf("failed to load monitor state: %v", err)
+	}
+	events, err := sink.Open(cfg.Sinks)
+	if err != nil {
+		log.Fatalf("failed to open event sink: %v", err)
+	}
+	events = metrics.InstrumentSink(cfg.Sinks, events)
+	if store != nil {
+		// 发布过的事件记入归档，重放时用来对比
+		events = sink.Multi(events, store.Sink())
+	}
+
+	opts, err := monitorOptions(cfg, gRPCWalletClient)
+	if err != nil {
+		log.Fatalf("failed to set up monitor: %v", err)
+	}
+	m := monitor.NewWithOptions(gRPCWalletClient, *startFlag, events, opts)
+	if store != nil {
+		if err := m.Subscribe(store.Handler()); err != nil {
+			log.Fatalf("failed to subscribe archive: %v", err)
+		}
+		fmt.Printf("处理过的区块归档到 %s\n", cfg.Archive.Path)
+	}
+	if *startFlag == 0 && cursor != nil {
+		fmt.Printf("从保存的游标继续：区块 %d（上一块 %d %s）\n", cursor.NextBlock, cursor.LastBlock, cursor.LastHash)
+		m.Resume(cursor)
+	}
+	// SIGINT/SIGTERM：处理完当前区块后退出
+	err = lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		health.Register(mux, health.ReporterFunc(func() health.Status {
+			st := m.Status()
+			st.Network = cfg.Profile.Name
+			st.Tokens = cfg.Watch.Tokens
+			st.Addresses = len(cfg.Watch.Addresses)
+			return st
+		}), health.Options{MaxLag: cfg.Health.MaxLag, StallTimeout: cfg.Health.StallTimeout})
+		if store != nil {
+			archive.Register(mux, store)
+		}
+		go lifecycle.ServeHTTP(ctx, cfg.ListenAddr, mux)
+		return m.Run(ctx)
+	})
+	if cerr := events.Close(); cerr != nil {
+		fmt.Println("刷新事件输出失败:", cerr)
+	}
+	fmt.Printf("监听已停止，下次从区块 %d 继续（-start %d）\n", m.NextBlock(), m.NextBlock())
 	if err != nil {
 		fmt.Println("监听失败:", err)
+		os.Exit(1)
 	}
 
 	// 使用自定义配置
@@ -82,8 +187,8 @@ func main() {
 	//}
 	//
 	//// 汇总 转入，转出
-	//sumIn, sumOut := trongrid.SumInOut(transfers)
-	//fmt.Printf("转入: %d, 转出: %d\n", sumIn, sumOut)
+	//sumIn, sumOut := trongrid.SumInOut(transfers, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	//fmt.Printf("转入: %s, 转出: %s\n", sumIn, sumOut)
 
 	//block.GetBlockByNum(gRPCWalletClient, int64(73216128))
 
@@ -114,9 +219,9 @@ func getAccountInfo(c *client.GrpcClient, addr string) error {
 
 	// Display information
 	fmt.Printf("Address: %s\n", addr)
-	fmt.Printf("Balance: %d sun (%f TRX)\n",
+	fmt.Printf("Balance: %d sun (%s TRX)\n",
 		account.Balance,
-		float64(account.Balance)/1e6)
+		amount.FromInt64(account.Balance, amount.TRXDecimals))
 	fmt.Printf("Created: %v\n", account.CreateTime)
 
 	// Check resources
@@ -134,25 +239,24 @@ func getAccountInfo(c *client.GrpcClient, addr string) error {
 }
 
 // 获取usdt余额
-func getTRC20Balance(c *client.GrpcClient, account, contract string) (*big.Int, error) {
+func getTRC20Balance(c *client.GrpcClient, account, contract string, decimals int32) (amount.Amount, error) {
 	contractAddr, err := address.Base58ToAddress(contract)
 	if err != nil {
-		return nil, fmt.Errorf("合约地址无效: %w", err)
+		return amount.Amount{}, fmt.Errorf("合约地址无效: %w", err)
 	}
 
 	accountAddr, err := address.Base58ToAddress(account)
 	if err != nil {
-		return nil, fmt.Errorf("账户地址无效: %w", err)
+		return amount.Amount{}, fmt.Errorf("账户地址无效: %w", err)
 	}
 
 	result, err := c.TRC20ContractBalance(accountAddr.String(), contractAddr.String())
 	log.Printf("result:%s, err:%s", result, err)
 	if err != nil {
-		return nil, err
+		return amount.Amount{}, err
 	}
 
-	balance := new(big.Int).SetBytes(result.Bytes())
-	return balance, nil
+	return amount.New(result, decimals), nil
 }
 
 // 创建账户
@@ -177,3 +281,162 @@ func createAccount() {
 	fmt.Printf("地址 Base58: %s\n", addr)
 	fmt.Printf("助记词: %s\n", acc.Mnemonic)
 }
+
+// runArchive archives blocks [from, to] (to 0: until stopped) without publishing
+// events or touching the monitor's cursor, so it may run next to a live monitor
+func runArchive(cfg *config.Config, c *client.GrpcClient, store *archive.Store, from, to int64) error {
+	opts, err := monitorOptions(cfg, c)
+	if err != nil {
+		return err
+	}
+	opts.StateFile = ""
+	opts.EndBlock = to
+	m := monitor.NewWithOptions(c, from, sink.Discard(), opts)
+	if err := m.Subscribe(store.Handler()); err != nil {
+		return err
+	}
+	fmt.Printf("归档区块 %d - %d 到 %s\n", from, to, cfg.Archive.Path)
+	err = lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		archive.Register(mux, store)
+		go lifecycle.ServeHTTP(ctx, cfg.ListenAddr, mux)
+		return m.Run(ctx)
+	})
+	first, last, rerr := store.Range(context.Background())
+	if rerr == nil {
+		fmt.Printf("归档结束，下次从区块 %d 继续；归档中的区块范围 %d - %d\n", m.NextBlock(), first, last)
+	}
+	return err
+}
+
+// runReplay re-runs archived blocks [from, to] through the monitor's publish and
+// handler pipeline and returns the exit code. Events go to the configured sinks
+// (and are recorded in the archive again) unless dryRun is set; a dry run
+// instead diffs them against the recorded events and fails when they differ.
+func runReplay(cfg *config.Config, c *client.GrpcClient, store *archive.Store, from, to int64, speed float64, dryRun bool) int {
+	ctx := context.Background()
+	if to == 0 {
+		_, last, err := store.Range(ctx)
+		if err != nil {
+			fmt.Println("读取归档失败:", err)
+			return 1
+		}
+		to = last
+	}
+	// 与监听相同的解码配置（abi_dir、fetch_abi），保证重放结果可比
+	opts, err := monitorOptions(cfg, c)
+	if err != nil {
+		fmt.Println("初始化监听失败:", err)
+		return 1
+	}
+	// 重放不读链头也不写游标；Confirmations 只决定事件是否按已确认发布，与原来的确认状态一致
+	opts.StateFile = ""
+	opts.Solidity = nil
+	opts.Confirmations = 0
+	if cfg.Monitor.Finality != config.FinalityHead {
+		opts.Confirmations = 1
+	}
+
+	var out sink.EventSink
+	collector := &replay.Collector{}
+	if dryRun {
+		out = collector
+	} else {
+		events, err := sink.Open(cfg.Sinks)
+		if err != nil {
+			fmt.Println("打开事件输出失败:", err)
+			return 1
+		}
+		out = sink.Multi(metrics.InstrumentSink(cfg.Sinks, events), store.Sink())
+		defer out.Close()
+	}
+
+	m := monitor.NewWithOptions(nil, from, out, opts)
+	fmt.Printf("重放区块 %d - %d（速度 %v，dry-run %v）\n", from, to, speed, dryRun)
+	var n int
+	err = lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		n, err = replay.Blocks(ctx, store, from, to, speed, m.Replay)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	})
+	fmt.Printf("已重放 %d 个区块\n", n)
+	if err != nil {
+		fmt.Println("重放失败:", err)
+		return 1
+	}
+	if !dryRun {
+		return 0
+	}
+
+	stored, err := store.Published(ctx, monitor.EventSource, from, to)
+	if err != nil {
+		fmt.Println("读取已记录的事件失败:", err)
+		return 1
+	}
+	report := replay.Diff(stored, collector.Events())
+	report.Print(os.Stdout)
+	if !report.Clean() {
+		return 1
+	}
+	return 0
+}
+
+// serveArchive serves the archive query API until SIGINT/SIGTERM
+func serveArchive(cfg *config.Config, store *archive.Store) {
+	if cfg.ListenAddr == "" {
+		log.Fatal("-archive-serve requires listen_addr")
+	}
+	fmt.Printf("查询接口: http://%s/archive/{transactions,events,range}（%s）\n", cfg.ListenAddr, cfg.Archive.Path)
+	err := lifecycle.Run(cfg.ShutdownTimeout, func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		archive.Register(mux, store)
+		return lifecycle.ServeHTTP(ctx, cfg.ListenAddr, mux)
+	})
+	if err != nil {
+		log.Fatalf("archive server stopped: %v", err)
+	}
+}
+
+// monitorOptions maps the monitor configuration onto monitor.Options
+func monitorOptions(cfg *config.Config, c *client.GrpcClient) (monitor.Options, error) {
+	opts := monitor.DefaultOptions()
+	opts.Workers = cfg.Monitor.Workers
+	opts.BlockTime = cfg.Profile.BlockTime
+	opts.StateFile = cfg.Monitor.StateFile
+	opts.ForkWindow = cfg.Monitor.ForkWindow
+	opts.Watch = cfg.Watch.Addresses
+
+	// 日志解码：先用 abi_dir 中的 ABI，其次链上 ABI，最后是内置的常见事件表
+	var fetch abi.Fetcher
+	if cfg.Monitor.FetchABI {
+		fetch = monitor.ContractABIFetcher(c)
+	}
+	opts.ABI = abi.NewRegistry(fetch)
+	if cfg.Monitor.ABIDir != "" {
+		n, err := opts.ABI.LoadDir(cfg.Monitor.ABIDir)
+		if err != nil {
+			return opts, err
+		}
+		fmt.Printf("已加载 %d 个合约 ABI（%s）\n", n, cfg.Monitor.ABIDir)
+	}
+	switch cfg.Monitor.Finality {
+	case config.FinalityDepth:
+		opts.Confirmations = cfg.Monitor.Confirmations
+	case config.FinalitySolidity:
+		conn, err := grpc.NewClient(cfg.SolidityEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return opts, err
+		}
+		opts.Solidity = api.NewWalletSolidityClient(conn)
+	}
+	// 解质押后可提取的时间按链参数计算，取不到时用默认 14 天
+	if d, err := monitor.UnfreezeDelay(c); err != nil {
+		fmt.Println("获取 getUnfreezeDelayDays 失败，使用默认值:", err)
+	} else {
+		opts.UnfreezeDelay = d
+	}
+	return opts, nil
+}
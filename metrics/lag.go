@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type position struct {
+	block int64
+	ts    time.Time
+}
+
+// LagCollector computes cursor lag at scrape time, so a stuck watcher shows
+// a growing lag even though it no longer updates anything.
+type LagCollector struct {
+	mu        sync.Mutex
+	blockTime time.Duration
+	heads     map[string]position
+	cursors   map[[2]string]position
+	// started stands in for the timestamp of cursors that have not processed anything yet
+	started time.Time
+	now     func() time.Time
+
+	secondsDesc *prometheus.Desc
+	blocksDesc  *prometheus.Desc
+}
+
+// NewLagCollector returns a collector that estimates block lag from blockTime when no head is known
+func NewLagCollector(blockTime time.Duration) *LagCollector {
+	return &LagCollector{
+		blockTime: blockTime,
+		heads:     make(map[string]position),
+		cursors:   make(map[[2]string]position),
+		started:   time.Now(),
+		now:       time.Now,
+		secondsDesc: prometheus.NewDesc(namespace+"_cursor_lag_seconds",
+			"Seconds between now and the newest block timestamp the watcher has fully processed.",
+			[]string{"watcher", "key"}, nil),
+		blocksDesc: prometheus.NewDesc(namespace+"_cursor_lag_blocks",
+			"Blocks between the chain head and the watcher cursor (estimated from block time when the head is unknown).",
+			[]string{"watcher", "key"}, nil),
+	}
+}
+
+// SetHead records the latest chain head seen by a watcher
+func (c *LagCollector) SetHead(watcher string, block int64, ts time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heads[watcher] = position{block: block, ts: ts}
+}
+
+// SetCursor records the newest fully processed position; block may be 0 when unknown.
+// A zero ts (or the unix epoch) means nothing was processed yet; the lag is
+// then counted from the collector's start.
+func (c *LagCollector) SetCursor(watcher, key string, block int64, ts time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cursors[[2]string{watcher, key}] = position{block: block, ts: ts}
+}
+
+// Describe implements prometheus.Collector
+func (c *LagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.secondsDesc
+	ch <- c.blocksDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *LagCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, cur := range c.cursors {
+		// 从未处理过的游标按启动时间算延迟，而不是从 1970 年算起
+		if cur.ts.IsZero() || cur.ts.Unix() <= 0 {
+			cur.ts = c.started
+		}
+		seconds := math.Max(0, now.Sub(cur.ts).Seconds())
+		blocks := math.Round(seconds / c.blockTime.Seconds())
+		if head, ok := c.heads[k[0]]; ok && cur.block > 0 {
+			// 已知链头时用真实高度差，再加上链头之后新出的块
+			blocks = float64(head.block-cur.block) + math.Floor(now.Sub(head.ts).Seconds()/c.blockTime.Seconds())
+		}
+		ch <- prometheus.MustNewConstMetric(c.secondsDesc, prometheus.GaugeValue, seconds, k[0], k[1])
+		ch <- prometheus.MustNewConstMetric(c.blocksDesc, prometheus.GaugeValue, math.Max(0, blocks), k[0], k[1])
+	}
+}
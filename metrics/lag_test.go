@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collect returns the lag seconds and blocks of every cursor, keyed by watcher/key
+func collect(t *testing.T, c *LagCollector) map[string][2]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	out := make(map[string][2]float64)
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		labels := make(map[string]string)
+		for _, l := range pb.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		key := labels["watcher"] + "/" + labels["key"]
+		v := out[key]
+		if m.Desc() == c.secondsDesc {
+			v[0] = pb.GetGauge().GetValue()
+		} else {
+			v[1] = pb.GetGauge().GetValue()
+		}
+		out[key] = v
+	}
+	return out
+}
+
+func TestLagCollector(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := NewLagCollector(3 * time.Second)
+	c.started = now.Add(-time.Minute)
+	c.now = func() time.Time { return now }
+
+	// 已知链头：高度差加上链头之后新出的块
+	c.SetHead("monitor", 1000, now.Add(-6*time.Second))
+	c.SetCursor("monitor", "blocks", 990, now.Add(-36*time.Second))
+	// 链头未知：按出块时间估算
+	c.SetCursor("gridwatcher", "TR7", 0, now.Add(-30*time.Second))
+	// 游标在未来（时钟偏差）不报负数
+	c.SetCursor("gridwatcher", "future", 0, now.Add(time.Minute))
+
+	got := collect(t, c)
+	want := map[string][2]float64{
+		"monitor/blocks":     {36, 12},
+		"gridwatcher/TR7":    {30, 10},
+		"gridwatcher/future": {0, 0},
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s = %v, want %v", k, got[k], w)
+		}
+	}
+}
+
+// 从未处理过的游标从启动时算起，而不是从 1970 年
+func TestLagCollectorNeverProcessed(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := NewLagCollector(3 * time.Second)
+	c.started = now.Add(-time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.SetCursor("gridwatcher", "zero", 0, time.Time{})
+	c.SetCursor("gridwatcher", "epoch", 0, time.UnixMilli(0))
+
+	got := collect(t, c)
+	for _, k := range []string{"gridwatcher/zero", "gridwatcher/epoch"} {
+		if got[k] != [2]float64{60, 20} {
+			t.Errorf("%s = %v, want a minute since start", k, got[k])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tron"
+
+var (
+	// TronGridRequestDuration is the latency of TronGrid HTTP requests by endpoint and status
+	TronGridRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "trongrid",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of TronGrid HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+
+	// PagesFetched counts event pages fetched by gridwatcher
+	PagesFetched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gridwatcher",
+		Name:      "pages_fetched_total",
+		Help:      "Event pages fetched from TronGrid.",
+	}, []string{"contract"})
+
+	// EventsProcessed counts events examined by a watcher. contract is a
+	// watched token for gridwatcher; the block monitor, which sees every
+	// contract on chain, uses a fixed kind instead (log, trx, trc10 or the
+	// staking event type) to keep the number of series bounded.
+	EventsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_processed_total",
+		Help:      "Events examined by a watcher.",
+	}, []string{"watcher", "contract"})
+
+	// EventsMatched counts events that matched a watched address
+	EventsMatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_matched_total",
+		Help:      "Events that matched a watched address.",
+	}, []string{"watcher", "contract"})
+
+	// DecodeErrors counts events that could not be decoded
+	DecodeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_decode_errors_total",
+		Help:      "Events that could not be decoded and were skipped.",
+	}, []string{"watcher", "event"})
+
+	// Deposits counts published deposits by token
+	Deposits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposits_total",
+		Help:      "Deposits published, by token.",
+	}, []string{"watcher", "token"})
+
+	// RiskFlagged counts transfers flagged as dust spam or address poisoning, by reason
+	RiskFlagged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "risk_flagged_total",
+		Help:      "Transfers flagged as dust spam or address poisoning, by reason.",
+	}, []string{"watcher", "reason"})
+
+	// FetchErrors counts failed TronGrid fetches by error class
+	FetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gridwatcher",
+		Name:      "fetch_errors_total",
+		Help:      "Failed TronGrid fetches by error class.",
+	}, []string{"class"})
+
+	// BreakerOpen is 1 while the gridwatcher circuit breaker is open
+	BreakerOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "gridwatcher",
+		Name:      "circuit_breaker_open",
+		Help:      "1 while the circuit breaker pauses polling.",
+	})
+
+	// BlocksProcessed counts blocks fully processed by a block based watcher
+	BlocksProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_processed_total",
+		Help:      "Blocks fully processed.",
+	}, []string{"watcher"})
+
+	// HandlerErrors counts failed calls of monitor handler callbacks, including retried ones
+	HandlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_errors_total",
+		Help:      "Failed handler callback calls, including calls that were retried.",
+	}, []string{"watcher", "handler"})
+
+	// FailedTransactions counts failed transactions seen by a block based watcher, by contract result
+	FailedTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_transactions_total",
+		Help:      "Failed transactions seen in processed blocks, by contract result.",
+	}, []string{"watcher", "result"})
+
+	// Reorgs counts chain reorganisations detected by a block based watcher
+	Reorgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reorgs_total",
+		Help:      "Chain reorganisations detected (parent hash mismatches).",
+	}, []string{"watcher"})
+
+	// BlocksRolledBack counts processed blocks abandoned by a reorganisation
+	BlocksRolledBack = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_rolled_back_total",
+		Help:      "Processed blocks abandoned by a chain reorganisation.",
+	}, []string{"watcher"})
+
+	// TransactionsProcessed counts transactions in processed blocks
+	TransactionsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_processed_total",
+		Help:      "Transactions in processed blocks.",
+	}, []string{"watcher"})
+
+	// GRPCRequestDuration is the latency of full node gRPC calls
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of full node gRPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// GRPCErrors counts failed full node gRPC calls
+	GRPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "errors_total",
+		Help:      "Failed full node gRPC calls.",
+	}, []string{"method"})
+
+	// SourceActive is 1 for the transfer source currently serving requests
+	SourceActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "source",
+		Name:      "active",
+		Help:      "1 for the transfer source currently serving requests.",
+	}, []string{"source"})
+
+	// SourceSwitches counts failovers between transfer sources
+	SourceSwitches = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "source",
+		Name:      "switches_total",
+		Help:      "Failovers between transfer sources.",
+	})
+
+	// VerifyRuns counts cross-source consistency checks by result (ok, mismatch, error)
+	VerifyRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "verify",
+		Name:      "runs_total",
+		Help:      "Cross-source consistency checks by result.",
+	}, []string{"result"})
+
+	// VerifyDiscrepancies counts transfers on which TronGrid and the full node disagree, by kind
+	// (only_<source>, mismatched, undecodable_<source>)
+	VerifyDiscrepancies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "verify",
+		Name:      "discrepancies_total",
+		Help:      "Transfers on which the sources disagree, by kind.",
+	}, []string{"kind"})
+
+	// SinkDeliveries counts event deliveries to sinks by outcome
+	SinkDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sink",
+		Name:      "deliveries_total",
+		Help:      "Event deliveries to downstream sinks by outcome.",
+	}, []string{"sink", "outcome"})
+
+	// Lag reports how far each watcher's cursor is behind the chain head
+	Lag = NewLagCollector(3 * time.Second)
+)
+
+func init() {
+	prometheus.MustRegister(
+		TronGridRequestDuration,
+		PagesFetched,
+		EventsProcessed,
+		EventsMatched,
+		DecodeErrors,
+		Deposits,
+		RiskFlagged,
+		FetchErrors,
+		BreakerOpen,
+		BlocksProcessed,
+		HandlerErrors,
+		FailedTransactions,
+		Reorgs,
+		BlocksRolledBack,
+		TransactionsProcessed,
+		GRPCRequestDuration,
+		GRPCErrors,
+		SourceActive,
+		SourceSwitches,
+		VerifyRuns,
+		VerifyDiscrepancies,
+		SinkDeliveries,
+		Lag,
+	)
+}
+
+// Handler returns the /metrics HTTP handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveTronGrid records one TronGrid request; status is 0 for transport errors
+func ObserveTronGrid(endpoint string, status int, start time.Time) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	TronGridRequestDuration.WithLabelValues(endpoint, label).Observe(time.Since(start).Seconds())
+}
+
+// ObserveGRPC records one gRPC call and counts it as an error when err is non-nil
+func ObserveGRPC(method string, start time.Time, err error) {
+	GRPCRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		GRPCErrors.WithLabelValues(method).Inc()
+	}
+}
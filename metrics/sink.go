@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/yourname/tron-demo/sink"
+)
+
+type instrumentedSink struct {
+	name string
+	next sink.EventSink
+}
+
+// InstrumentSink counts delivery outcomes of every event published to s
+func InstrumentSink(name string, s sink.EventSink) sink.EventSink {
+	return &instrumentedSink{name: name, next: s}
+}
+
+func (s *instrumentedSink) Publish(ctx context.Context, ev sink.Event) error {
+	err := s.next.Publish(ctx, ev)
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	SinkDeliveries.WithLabelValues(s.name, outcome).Inc()
+	return err
+}
+
+func (s *instrumentedSink) Close() error {
+	return s.next.Close()
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yourname/tron-demo/statefile"
+)
+
+// Cursor is the monitor's position, persisted so a restart resumes where it stopped
+type Cursor struct {
+	// NextBlock is the next block to process
+	NextBlock int64 `json:"next_block"`
+	// LastBlock and LastHash identify the last processed block; on resume the
+	// parent hash of NextBlock is checked against LastHash
+	LastBlock int64  `json:"last_block"`
+	LastHash  string `json:"last_hash"`
+	// Recent are the last processed blocks, oldest first, used to find the
+	// common ancestor when a reorganisation is detected after a restart
+	Recent    []BlockRef `json:"recent,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// LoadCursor reads the cursor saved at path; a missing file returns nil
+func LoadCursor(path string) (*Cursor, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read monitor state %s: %w", path, err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse monitor state %s: %w", path, err)
+	}
+	if c.NextBlock <= 0 {
+		return nil, fmt.Errorf("monitor state %s has no next_block", path)
+	}
+	return &c, nil
+}
+
+// saveCursor writes the cursor atomically so a crash never leaves a torn file
+func saveCursor(path string, c Cursor) error {
+	return statefile.WriteJSON(path, c)
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/yourname/tron-demo/sink"
+	"google.golang.org/grpc"
+)
+
+func TestSaveLoadCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.json")
+	want := Cursor{
+		NextBlock: 103,
+		LastBlock: 102,
+		LastHash:  ref(102, 'a').Hash,
+		Recent:    []BlockRef{ref(101, 'a'), ref(102, 'a')},
+		UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := saveCursor(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCursor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("LoadCursor = %+v, want %+v", *got, want)
+	}
+}
+
+func TestLoadCursorErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"", filepath.Join(dir, "missing.json")} {
+		if c, err := LoadCursor(path); c != nil || err != nil {
+			t.Errorf("LoadCursor(%q) = %v, %v; want nil, nil", path, c, err)
+		}
+	}
+	for name, content := range map[string]string{
+		"torn.json":    `{"next_block": 10`,
+		"no-next.json": `{"last_block": 9}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if c, err := LoadCursor(path); err == nil {
+			t.Errorf("%s: LoadCursor = %+v, want error", name, c)
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.json")
+	m := testMonitor(&fakeWallet{}, sink.NewChannel(10))
+	m.opts.StateFile = path
+	for num := int64(100); num <= 102; num++ {
+		if err := m.processBlock(context.Background(), fetched{block: testBlock(num, 'a', 'a')}); err != nil {
+			t.Fatalf("block %d: %v", num, err)
+		}
+	}
+	m.checkpoint()
+
+	c, err := LoadCursor(path)
+	if err != nil || c == nil {
+		t.Fatalf("LoadCursor = %v, %v", c, err)
+	}
+	if c.NextBlock != 103 || c.LastBlock != 102 || c.LastHash != ref(102, 'a').Hash || len(c.Recent) != 3 {
+		t.Errorf("cursor = %+v", c)
+	}
+
+	// 重启后按保存的区块哈希检查父哈希：同一分支可以继续，另一分支是分叉
+	resumed := testMonitor(&fakeWallet{}, sink.NewChannel(10))
+	resumed.Resume(c)
+	if resumed.NextBlock() != 103 {
+		t.Errorf("NextBlock = %d after resume", resumed.NextBlock())
+	}
+	if err := resumed.processBlock(context.Background(), fetched{block: testBlock(103, 'b', 'b')}); !errors.Is(err, errFork) {
+		t.Errorf("block on another branch: %v, want errFork", err)
+	}
+	if err := resumed.processBlock(context.Background(), fetched{block: testBlock(103, 'a', 'a')}); err != nil {
+		t.Errorf("block extending the saved chain: %v", err)
+	}
+
+	// 旧格式只有 last_block/last_hash
+	legacy := testMonitor(&fakeWallet{}, sink.NewChannel(10))
+	legacy.Resume(&Cursor{NextBlock: 103, LastBlock: 102, LastHash: ref(102, 'a').Hash})
+	if refs := legacy.window.refs(); len(refs) != 1 || refs[0] != ref(102, 'a') {
+		t.Errorf("window after legacy resume = %v", refs)
+	}
+}
+
+// headWallet serves GetNowBlock2 with a fixed head
+type headWallet struct {
+	fakeWallet
+	head int64
+}
+
+func (w *headWallet) GetNowBlock2(context.Context, *api.EmptyMessage, ...grpc.CallOption) (*api.BlockExtention, error) {
+	return testBlock(w.head, 'a', 'a'), nil
+}
+
+// solidityNode serves GetNowBlock2 of the solidity node
+type solidityNode struct {
+	api.WalletSolidityClient
+	head int64
+	err  error
+}
+
+func (s *solidityNode) GetNowBlock2(context.Context, *api.EmptyMessage, ...grpc.CallOption) (*api.BlockExtention, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return testBlock(s.head, 'a', 'a'), nil
+}
+
+func TestRefreshHeadFinality(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		finality string
+		want     int64
+	}{
+		{"head", func(*Options) {}, "head", 1000},
+		{"depth", func(o *Options) { o.Confirmations = 19 }, "depth", 981},
+		// 固化节点优先于确认数
+		{"solidity", func(o *Options) { o.Confirmations = 19; o.Solidity = &solidityNode{head: 975} }, "solidity", 975},
+	}
+	for _, tt := range tests {
+		m := testMonitor(&headWallet{head: 1000}, sink.NewChannel(1))
+		tt.opts(&m.opts)
+		if got := m.opts.Finality(); got != tt.finality {
+			t.Errorf("%s: Finality() = %s", tt.name, got)
+		}
+		got, err := m.refreshHead(context.Background())
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want || m.head != 1000 {
+			t.Errorf("%s: refreshHead = %d (head %d), want %d", tt.name, got, m.head, tt.want)
+		}
+	}
+
+	// 固化节点不可用时不退回到链头
+	m := testMonitor(&headWallet{head: 1000}, sink.NewChannel(1))
+	m.opts.Solidity = &solidityNode{err: errors.New("unavailable")}
+	if got, err := m.refreshHead(context.Background()); err == nil {
+		t.Errorf("refreshHead = %d with the solidity node down, want error", got)
+	}
+}
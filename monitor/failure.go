@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"strconv"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// Failure describes why a transaction failed and what it cost the sender
+type Failure struct {
+	Tx *Transaction
+	// Result is the contract result, e.g. REVERT or OUT_OF_ENERGY
+	Result string
+	// Message is the node's resMessage, if any
+	Message string
+	// RevertReason is the decoded require/revert message, panic or custom error
+	RevertReason string
+	// EnergyUsed is the total energy consumed, including energy paid by the contract owner
+	EnergyUsed int64
+	// EnergyFee, NetFee and Fee are the TRX burned for energy, bandwidth and in total
+	EnergyFee amount.Amount
+	NetFee    amount.Amount
+	Fee       amount.Amount
+	// Call is the token call the transaction attempted (transfer, transferFrom,
+	// approve); nil for other calls
+	Call *abi.Call
+}
+
+// newFailure describes a failed transaction from its receipt
+func newFailure(tx *Transaction, reg *abi.Registry) *Failure {
+	f := &Failure{Tx: tx, Result: "FAILED"}
+	if rets := tx.Raw.GetRet(); len(rets) > 0 && rets[0].GetContractRet() != core.Transaction_Result_DEFAULT {
+		f.Result = rets[0].GetContractRet().String()
+	}
+	if info := tx.Info; info != nil {
+		receipt := info.GetReceipt()
+		if receipt.GetResult() > core.Transaction_Result_SUCCESS {
+			f.Result = receipt.GetResult().String()
+		}
+		f.Message = string(info.GetResMessage())
+		f.EnergyUsed = receipt.GetEnergyUsageTotal()
+		f.EnergyFee = amount.FromInt64(receipt.GetEnergyFee(), amount.TRXDecimals)
+		f.NetFee = amount.FromInt64(receipt.GetNetFee(), amount.TRXDecimals)
+		f.Fee = amount.FromInt64(info.GetFee(), amount.TRXDecimals)
+		if results := info.GetContractResult(); len(results) > 0 {
+			f.RevertReason = reg.RevertReason(tx.To, results[0])
+		}
+	} else {
+		f.EnergyFee = amount.FromInt64(0, amount.TRXDecimals)
+		f.NetFee = amount.FromInt64(0, amount.TRXDecimals)
+		f.Fee = amount.FromInt64(0, amount.TRXDecimals)
+	}
+
+	if contracts := tx.Raw.GetRawData().GetContract(); len(contracts) > 0 && contracts[0].GetType() == core.Transaction_Contract_TriggerSmartContract {
+		var p core.TriggerSmartContract
+		if err := contracts[0].GetParameter().UnmarshalTo(&p); err == nil {
+			if call, err := abi.DecodeCall(p.Data); err == nil {
+				f.Call = call
+			}
+		}
+	}
+	return f
+}
+
+// Involves reports whether address sent the transaction, was called by it or
+// is an address argument of the attempted token call
+func (f *Failure) Involves(address string) bool {
+	if f.Tx.Owner == address || f.Tx.To == address {
+		return true
+	}
+	if f.Call == nil {
+		return false
+	}
+	for _, a := range f.Call.Args {
+		if a.Type == "address" && a.String() == address {
+			return true
+		}
+	}
+	return false
+}
+
+// publishFailure publishes a failed transaction involving a watched address
+// (any transaction when none are watched)
+func (m *Monitor) publishFailure(ctx context.Context, out sink.EventSink, f *Failure) error {
+	metrics.FailedTransactions.WithLabelValues(EventSource, f.Result).Inc()
+	if !m.watches(f.Involves) {
+		return nil
+	}
+
+	tx := f.Tx
+	data := map[string]string{
+		"contract_type": tx.Type,
+		"result":        f.Result,
+		"energy_used":   strconv.FormatInt(f.EnergyUsed, 10),
+		"energy_fee":    f.EnergyFee.String(),
+		"net_fee":       f.NetFee.String(),
+		"fee":           f.Fee.String(),
+	}
+	if f.Message != "" {
+		data["message"] = f.Message
+	}
+	if f.RevertReason != "" {
+		data["revert_reason"] = f.RevertReason
+	}
+	to := tx.To
+	if f.Call != nil {
+		data["method"] = f.Call.Signature
+		for _, a := range f.Call.Args {
+			data["call_"+a.Name] = a.String()
+		}
+		if a, ok := f.Call.Arg("to"); ok {
+			to = a.String()
+		}
+	}
+	if results := tx.Info.GetContractResult(); len(results) > 0 && len(results[0]) > 0 {
+		data["contract_result"] = hex.EncodeToString(results[0])
+	}
+
+	key := tx.ID + "#failed"
+	log.Printf("[monitor] %s: tx=%s from=%s to=%s result=%s reason=%q fee=%s key=%s",
+		EventTypeFailedTx, tx.ID, tx.Owner, to, f.Result, f.RevertReason, f.Fee, key)
+	contract := ""
+	if tx.Type == core.Transaction_Contract_TriggerSmartContract.String() {
+		contract = tx.To
+	}
+	return out.Publish(ctx, sink.Event{
+		Source:         EventSource,
+		Type:           EventTypeFailedTx,
+		Key:            key,
+		TxID:           tx.ID,
+		BlockNumber:    tx.Block.Number,
+		BlockTimestamp: tx.Block.Timestamp.UnixMilli(),
+		Contract:       contract,
+		From:           tx.Owner,
+		To:             to,
+		Confirmed:      tx.Block.Confirmed,
+		Data:           data,
+	})
+}
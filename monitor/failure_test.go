@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/sink"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// transferData is transfer(bob, 1000000) call data
+const transferData = "a9059cbb" +
+	"00000000000000000000000088403ac26730e33164eeac403291b48f300b782f" +
+	"00000000000000000000000000000000000000000000000000000000000f4240"
+
+// exceedsBalance is the revert payload of require(..., "ERC20: transfer amount exceeds balance")
+const exceedsBalance = "08c379a0" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000026" +
+	"45524332303a207472616e7366657220616d6f756e7420657863656564732062" +
+	"616c616e63650000000000000000000000000000000000000000000000000000"
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// failedCall is alice's reverted USDT transfer to bob with the given contract result
+func failedCall(t *testing.T, result []byte) *Transaction {
+	t.Helper()
+	param, err := anypb.New(&core.TriggerSmartContract{
+		OwnerAddress:    addressBytes(t, alice),
+		ContractAddress: addressBytes(t, usdt),
+		Data:            mustHex(t, transferData),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Transaction{
+		Block: &Block{Number: 100, Timestamp: time.UnixMilli(1_700_000_000_000)},
+		ID:    "tx1", Type: "TriggerSmartContract", Owner: alice, To: usdt,
+		Raw: &core.Transaction{
+			RawData: &core.TransactionRaw{Contract: []*core.Transaction_Contract{{
+				Type:      core.Transaction_Contract_TriggerSmartContract,
+				Parameter: param,
+			}}},
+			Ret: []*core.Transaction_Result{{ContractRet: core.Transaction_Result_REVERT}},
+		},
+		Info: &core.TransactionInfo{
+			Fee:            1_234_560,
+			ResMessage:     []byte("REVERT opcode executed"),
+			ContractResult: [][]byte{result},
+			Receipt: &core.ResourceReceipt{
+				EnergyUsageTotal: 13_000,
+				EnergyFee:        1_144_560,
+				NetFee:           90_000,
+				Result:           core.Transaction_Result_REVERT,
+			},
+		},
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	reg := abi.NewRegistry(nil)
+	f := newFailure(failedCall(t, mustHex(t, exceedsBalance)), reg)
+	if f.Result != "REVERT" || f.Message != "REVERT opcode executed" || f.RevertReason != "ERC20: transfer amount exceeds balance" {
+		t.Errorf("failure = %+v", f)
+	}
+	if f.EnergyUsed != 13_000 || f.EnergyFee.String() != "1.144560" || f.NetFee.String() != "0.090000" || f.Fee.String() != "1.234560" {
+		t.Errorf("energy %d, fees %s + %s = %s", f.EnergyUsed, f.EnergyFee, f.NetFee, f.Fee)
+	}
+	if f.Call == nil || f.Call.Signature != "transfer(address,uint256)" {
+		t.Fatalf("call = %+v", f.Call)
+	}
+	// 转账的收款人也算相关地址
+	for _, a := range []string{alice, usdt, bob} {
+		if !f.Involves(a) {
+			t.Errorf("Involves(%s) = false", a)
+		}
+	}
+	if f.Involves(carol) {
+		t.Error("Involves(carol) = true")
+	}
+
+	// 损坏的返回数据不能导致 panic，只是没有原因
+	for _, result := range [][]byte{nil, {0x08, 0xc3}, mustHex(t, exceedsBalance[:8+64+10]), mustHex(t, "4e487b71"+strings.Repeat("f", 20))} {
+		if f := newFailure(failedCall(t, result), reg); f.RevertReason != "" || f.Result != "REVERT" {
+			t.Errorf("result %x: reason %q, result %s", result, f.RevertReason, f.Result)
+		}
+	}
+
+	// 能量耗尽：收据结果优先于交易结果；没有收据时费用为 0
+	tx := failedCall(t, nil)
+	tx.Info.Receipt.Result = core.Transaction_Result_OUT_OF_ENERGY
+	if f := newFailure(tx, reg); f.Result != "OUT_OF_ENERGY" {
+		t.Errorf("Result = %s, want OUT_OF_ENERGY", f.Result)
+	}
+	tx.Info = nil
+	tx.Raw.Ret = nil
+	if f := newFailure(tx, reg); f.Result != "FAILED" || !f.Fee.IsZero() || f.Fee.String() != "0.000000" {
+		t.Errorf("without a receipt: result %s, fee %s", f.Result, f.Fee)
+	}
+}
+
+func TestPublishFailure(t *testing.T) {
+	ch := sink.NewChannel(1)
+	m := NewWithOptions(client.NewGrpcClient(""), 100, ch, Options{Watch: []string{bob}})
+	f := newFailure(failedCall(t, mustHex(t, exceedsBalance)), abi.NewRegistry(nil))
+	if err := m.publishFailure(context.Background(), ch, f); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-ch.C()
+	if ev.Type != EventTypeFailedTx || ev.Key != "tx1#failed" || ev.To != bob || ev.Contract != usdt || ev.Value != nil {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Data["revert_reason"] != "ERC20: transfer amount exceeds balance" || ev.Data["result"] != "REVERT" || ev.Data["call_value"] != "1000000" {
+		t.Errorf("data = %v", ev.Data)
+	}
+}
@@ -0,0 +1,234 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+)
+
+var (
+	// errFork means a block's parent hash does not match the block processed before it
+	errFork = errors.New("parent hash mismatch")
+	// errForkTooDeep means no block in the window is still on the canonical chain
+	errForkTooDeep = errors.New("fork deeper than the block window")
+)
+
+// BlockRef identifies a processed block
+type BlockRef struct {
+	Number int64  `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// blockRecord is a processed block kept for fork detection
+type blockRecord struct {
+	BlockRef
+	// events delivered from the block; known is false for blocks restored from
+	// the cursor file, whose events were delivered by an earlier run
+	events []sink.Event
+	known  bool
+	// undo is how far a rollback of the block got before it failed, so the
+	// retry neither revokes events nor calls handlers a second time
+	undo undoProgress
+}
+
+// undoProgress tracks the rollback of one abandoned block
+type undoProgress struct {
+	// revoked counts the block's events revoked so far, newest first
+	revoked int
+	// announced is set once the block rollback event is published
+	announced bool
+	// notified counts the handlers, in registration order, told about the rollback
+	notified int
+}
+
+// forkWindow holds the most recently processed blocks in ascending order
+type forkWindow struct {
+	size   int
+	blocks []blockRecord
+}
+
+// push appends a processed block; a gap (e.g. a new start block) restarts the window
+func (w *forkWindow) push(r blockRecord) {
+	if last, ok := w.last(); ok && last.Number != r.Number-1 {
+		w.blocks = nil
+	}
+	w.blocks = append(w.blocks, r)
+	if len(w.blocks) > w.size {
+		w.blocks = w.blocks[len(w.blocks)-w.size:]
+	}
+}
+
+func (w *forkWindow) last() (blockRecord, bool) {
+	if len(w.blocks) == 0 {
+		return blockRecord{}, false
+	}
+	return w.blocks[len(w.blocks)-1], true
+}
+
+// refs returns the block numbers and hashes for the cursor file
+func (w *forkWindow) refs() []BlockRef {
+	refs := make([]BlockRef, len(w.blocks))
+	for i, b := range w.blocks {
+		refs[i] = b.BlockRef
+	}
+	return refs
+}
+
+// restore fills the window from the cursor file
+func (w *forkWindow) restore(refs []BlockRef) {
+	w.blocks = nil
+	for _, r := range refs {
+		w.push(blockRecord{BlockRef: r})
+	}
+}
+
+// recorder passes events through to out and remembers the delivered ones
+type recorder struct {
+	out    sink.EventSink
+	events []sink.Event
+}
+
+func (r *recorder) Publish(ctx context.Context, ev sink.Event) error {
+	if err := r.out.Publish(ctx, ev); err != nil {
+		return err
+	}
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+// rollback walks back from the newest processed block to the first one that is
+// still on the node's chain, publishes a rollback for every event delivered
+// from the abandoned blocks and moves the cursor to the block after the common
+// ancestor. Blocks are dropped from the window once fully rolled back; on a
+// node or sink error the rest stays, so the fork is detected again on the next
+// attempt, which continues where this one stopped.
+func (m *Monitor) rollback(ctx context.Context) error {
+	m.mu.Lock()
+	blocks := append([]blockRecord(nil), m.window.blocks...)
+	m.mu.Unlock()
+
+	ancestor := -1
+	var ancestorTs int64
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b, err := getBlockByNum(m.client, blocks[i].Number)
+		if err != nil {
+			return fmt.Errorf("find common ancestor: %w", err)
+		}
+		if hex.EncodeToString(b.Blockid) == blocks[i].Hash {
+			ancestor = i
+			ancestorTs = b.GetBlockHeader().GetRawData().GetTimestamp()
+			break
+		}
+	}
+	if ancestor < 0 {
+		return fmt.Errorf("no common ancestor in the last %d blocks: %w", len(blocks), errForkTooDeep)
+	}
+
+	abandoned := blocks[ancestor+1:]
+	if len(abandoned) == 0 {
+		// 已处理的区块仍在主链上，说明取到的子块来自已被放弃的分叉（或落后的节点），重新获取即可
+		log.Printf("[monitor] Processed block %d %s is still canonical, refetching its successor", blocks[ancestor].Number, blocks[ancestor].Hash)
+		return nil
+	}
+
+	// 从最新的区块开始，按发布的逆序撤销；撤销完的区块立即移出窗口
+	for i := len(blocks) - 1; i > ancestor; i-- {
+		if err := m.undoBlock(ctx, i, blocks[i]); err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.window.blocks = m.window.blocks[:i]
+		m.mu.Unlock()
+		atomic.StoreInt64(&m.next, blocks[i].Number)
+		metrics.BlocksRolledBack.WithLabelValues(EventSource).Inc()
+	}
+
+	common := blocks[ancestor]
+	log.Printf("[monitor] Reorg: rolled back %d blocks (%d-%d), common ancestor %d %s",
+		len(abandoned), abandoned[0].Number, abandoned[len(abandoned)-1].Number, common.Number, common.Hash)
+	metrics.Reorgs.WithLabelValues(EventSource).Inc()
+
+	// 状态和延迟指标回到共同祖先
+	ts := time.UnixMilli(ancestorTs)
+	m.mu.Lock()
+	m.lastBlock = common.Number
+	m.lastBlockTs = ts
+	m.mu.Unlock()
+	metrics.Lag.SetCursor(EventSource, "blocks", common.Number, ts)
+	m.checkpoint()
+	return nil
+}
+
+// undoBlock revokes the events of the abandoned block b, at index i of the
+// window, and calls the handlers' OnRollback. Progress is saved in the window
+// so that a retry after an error skips what was already done.
+func (m *Monitor) undoBlock(stop context.Context, i int, b blockRecord) error {
+	ctx := context.WithoutCancel(stop)
+	p := b.undo
+	defer func() {
+		m.mu.Lock()
+		m.window.blocks[i].undo = p
+		m.mu.Unlock()
+	}()
+	for ; p.revoked < len(b.events); p.revoked++ {
+		if err := m.out.Publish(ctx, rollbackEvent(b, b.events[len(b.events)-1-p.revoked])); err != nil {
+			return err
+		}
+	}
+	if !p.announced {
+		if err := m.out.Publish(ctx, blockRollbackEvent(b)); err != nil {
+			return err
+		}
+		p.announced = true
+	}
+	// 处理器只会追加注册，已通知的个数在重试时仍然有效
+	handlers := m.subscribers()
+	for ; p.notified < len(handlers); p.notified++ {
+		// 处理器的重试等待可被关闭打断，重启后从这里继续
+		if err := handlers[p.notified].rollback(stop, &Block{Number: b.Number, Hash: b.Hash}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackEvent revokes ev, delivered from the abandoned block b
+func rollbackEvent(b blockRecord, ev sink.Event) sink.Event {
+	rb := ev
+	rb.Type = EventTypeRollback
+	rb.Key = ev.Key + "#rollback"
+	rb.Confirmed = false
+	rb.Data = map[string]string{
+		"rollback_type": ev.Type,
+		"rollback_key":  ev.Key,
+		"block_hash":    b.Hash,
+	}
+	return rb
+}
+
+// blockRollbackEvent announces that block b was abandoned. For blocks processed
+// by an earlier run the individual events are unknown and consumers must undo
+// everything they credited from the block.
+func blockRollbackEvent(b blockRecord) sink.Event {
+	events := "unknown"
+	if b.known {
+		events = strconv.Itoa(len(b.events))
+	}
+	return sink.Event{
+		Source:      EventSource,
+		Type:        EventTypeBlockRollback,
+		Key:         fmt.Sprintf("block#%d#%s#rollback", b.Number, b.Hash),
+		BlockNumber: b.Number,
+		Data:        map[string]string{"block_hash": b.Hash, "events": events},
+	}
+}
@@ -0,0 +1,262 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/sink"
+	"google.golang.org/grpc"
+)
+
+// fakeWallet serves GetBlockByNum2 from a map; other calls panic
+type fakeWallet struct {
+	api.WalletClient
+	blocks map[int64]*api.BlockExtention
+}
+
+func (w *fakeWallet) GetBlockByNum2(_ context.Context, in *api.NumberMessage, _ ...grpc.CallOption) (*api.BlockExtention, error) {
+	if b, ok := w.blocks[in.Num]; ok {
+		return b, nil
+	}
+	return &api.BlockExtention{}, nil
+}
+
+// blockID returns a deterministic block id for block num on the given branch
+func blockID(num int64, branch byte) []byte {
+	id := make([]byte, 32)
+	copy(id, fmt.Sprintf("%08d", num))
+	id[31] = branch
+	return id
+}
+
+func testBlock(num int64, branch, parentBranch byte) *api.BlockExtention {
+	return &api.BlockExtention{
+		Blockid: blockID(num, branch),
+		BlockHeader: &core.BlockHeader{RawData: &core.BlockHeaderRaw{
+			Number:     num,
+			ParentHash: blockID(num-1, parentBranch),
+			Timestamp:  1_700_000_000_000 + num*3000,
+		}},
+	}
+}
+
+func testMonitor(wallet api.WalletClient, out sink.EventSink) *Monitor {
+	c := client.NewGrpcClient("")
+	c.Client = wallet
+	return NewWithOptions(c, 100, out, Options{ForkWindow: 4})
+}
+
+func ref(num int64, branch byte) BlockRef {
+	return BlockRef{Number: num, Hash: hex.EncodeToString(blockID(num, branch))}
+}
+
+func TestProcessBlockDetectsFork(t *testing.T) {
+	m := testMonitor(&fakeWallet{}, sink.NewChannel(10))
+	for num := int64(100); num <= 102; num++ {
+		if err := m.processBlock(context.Background(), fetched{block: testBlock(num, 'a', 'a')}); err != nil {
+			t.Fatalf("block %d: %v", num, err)
+		}
+	}
+	if m.NextBlock() != 103 || len(m.window.blocks) != 3 {
+		t.Fatalf("next %d, window %v", m.NextBlock(), m.window.refs())
+	}
+	err := m.processBlock(context.Background(), fetched{block: testBlock(103, 'b', 'b')})
+	if !errors.Is(err, errFork) {
+		t.Fatalf("processBlock = %v, want errFork", err)
+	}
+	if m.NextBlock() != 103 {
+		t.Errorf("cursor moved to %d on a fork", m.NextBlock())
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ch := sink.NewChannel(10)
+	// 节点上 100 仍是主链，101、102 已被 b 分支替换
+	wallet := &fakeWallet{blocks: map[int64]*api.BlockExtention{
+		100: testBlock(100, 'a', 'a'),
+		101: testBlock(101, 'b', 'a'),
+		102: testBlock(102, 'b', 'b'),
+	}}
+	m := testMonitor(wallet, ch)
+	m.window.push(blockRecord{BlockRef: ref(100, 'a'), known: true})
+	m.window.push(blockRecord{BlockRef: ref(101, 'a'), known: true, events: []sink.Event{
+		{Source: EventSource, Type: "DEPOSIT", Key: "t1#0"},
+		{Source: EventSource, Type: "DEPOSIT", Key: "t1#1"},
+	}})
+	// 上次运行处理过的区块，事件未知
+	m.window.push(blockRecord{BlockRef: ref(102, 'a')})
+	m.next = 103
+	m.lastBlock = 102
+	m.lastBlockTs = time.UnixMilli(testBlock(102, 'a', 'a').BlockHeader.RawData.Timestamp)
+
+	if err := m.rollback(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.NextBlock() != 101 {
+		t.Errorf("NextBlock = %d, want 101", m.NextBlock())
+	}
+	if want := time.UnixMilli(testBlock(100, 'a', 'a').BlockHeader.RawData.Timestamp); m.lastBlock != 100 || !m.lastBlockTs.Equal(want) {
+		t.Errorf("last block %d at %v, want the common ancestor 100 at %v", m.lastBlock, m.lastBlockTs, want)
+	}
+	if refs := m.window.refs(); len(refs) != 1 || refs[0] != ref(100, 'a') {
+		t.Errorf("window = %v", refs)
+	}
+
+	// 从最新的区块开始按发布的逆序撤销
+	want := []struct{ typ, key, events string }{
+		{EventTypeBlockRollback, fmt.Sprintf("block#102#%s#rollback", ref(102, 'a').Hash), "unknown"},
+		{EventTypeRollback, "t1#1#rollback", ""},
+		{EventTypeRollback, "t1#0#rollback", ""},
+		{EventTypeBlockRollback, fmt.Sprintf("block#101#%s#rollback", ref(101, 'a').Hash), "2"},
+	}
+	for i, w := range want {
+		ev := <-ch.C()
+		if ev.Type != w.typ || ev.Key != w.key || ev.Data["events"] != w.events {
+			t.Errorf("event %d = %s %s %v, want %s %s", i, ev.Type, ev.Key, ev.Data, w.typ, w.key)
+		}
+	}
+	if len(ch.C()) != 0 {
+		t.Errorf("%d extra events", len(ch.C()))
+	}
+}
+
+// flakySink fails the publish calls whose 1-based number is in fail
+type flakySink struct {
+	sink.EventSink
+	fail  map[int]bool
+	calls int
+}
+
+func (s *flakySink) Publish(ctx context.Context, ev sink.Event) error {
+	s.calls++
+	if s.fail[s.calls] {
+		return errors.New("sink down")
+	}
+	return s.EventSink.Publish(ctx, ev)
+}
+
+func TestRollbackRetry(t *testing.T) {
+	ch := sink.NewChannel(10)
+	out := &flakySink{EventSink: ch, fail: map[int]bool{2: true}}
+	wallet := &fakeWallet{blocks: map[int64]*api.BlockExtention{
+		100: testBlock(100, 'a', 'a'),
+		101: testBlock(101, 'b', 'a'),
+		102: testBlock(102, 'b', 'b'),
+	}}
+	m := testMonitor(wallet, out)
+	m.window.push(blockRecord{BlockRef: ref(100, 'a'), known: true})
+	m.window.push(blockRecord{BlockRef: ref(101, 'a'), known: true, events: []sink.Event{
+		{Source: EventSource, Type: "DEPOSIT", Key: "t1#0"},
+		{Source: EventSource, Type: "DEPOSIT", Key: "t1#1"},
+	}})
+	m.window.push(blockRecord{BlockRef: ref(102, 'a'), known: true, events: []sink.Event{
+		{Source: EventSource, Type: "DEPOSIT", Key: "t2#0"},
+	}})
+	m.next = 103
+
+	calls := map[string][]int64{}
+	failed := false
+	for _, name := range []string{"first", "second"} {
+		err := m.Subscribe(Handler{Name: name, Retry: Retry{Attempts: 1}, OnRollback: func(_ context.Context, b *Block) error {
+			calls[name] = append(calls[name], b.Number)
+			// 第二个处理器第一次处理 102 时失败
+			if name == "second" && !failed {
+				failed = true
+				return errors.New("handler down")
+			}
+			return nil
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第一次：撤销 t2#0 后发布 102 的区块回滚失败；第二次：第二个处理器失败；第三次完成
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := m.rollback(context.Background()); err == nil {
+			t.Fatalf("attempt %d succeeded", attempt)
+		}
+		if m.NextBlock() != 103 || len(m.window.blocks) != 3 {
+			t.Errorf("attempt %d: next %d, window %v", attempt, m.NextBlock(), m.window.refs())
+		}
+	}
+	if err := m.rollback(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.NextBlock() != 101 || len(m.window.blocks) != 1 {
+		t.Errorf("next %d, window %v", m.NextBlock(), m.window.refs())
+	}
+
+	// 每个事件只撤销一次，每个处理器对每个区块只成功通知一次
+	want := []string{
+		"t2#0#rollback",
+		fmt.Sprintf("block#102#%s#rollback", ref(102, 'a').Hash),
+		"t1#1#rollback",
+		"t1#0#rollback",
+		fmt.Sprintf("block#101#%s#rollback", ref(101, 'a').Hash),
+	}
+	var got []string
+	for len(ch.C()) > 0 {
+		got = append(got, (<-ch.C()).Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+	if !slices.Equal(calls["first"], []int64{102, 101}) || !slices.Equal(calls["second"], []int64{102, 102, 101}) {
+		t.Errorf("handler calls %v", calls)
+	}
+}
+
+func TestRollbackCanonicalTip(t *testing.T) {
+	wallet := &fakeWallet{blocks: map[int64]*api.BlockExtention{101: testBlock(101, 'a', 'a')}}
+	m := testMonitor(wallet, sink.NewChannel(1))
+	m.window.push(blockRecord{BlockRef: ref(100, 'a')})
+	m.window.push(blockRecord{BlockRef: ref(101, 'a')})
+	m.next = 102
+	if err := m.rollback(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.NextBlock() != 102 || len(m.window.blocks) != 2 {
+		t.Errorf("next %d, window %v", m.NextBlock(), m.window.refs())
+	}
+}
+
+func TestRollbackTooDeep(t *testing.T) {
+	wallet := &fakeWallet{blocks: map[int64]*api.BlockExtention{
+		100: testBlock(100, 'b', 'b'),
+		101: testBlock(101, 'b', 'b'),
+	}}
+	m := testMonitor(wallet, sink.NewChannel(1))
+	m.window.push(blockRecord{BlockRef: ref(100, 'a')})
+	m.window.push(blockRecord{BlockRef: ref(101, 'a')})
+	m.next = 102
+	if err := m.rollback(context.Background()); !errors.Is(err, errForkTooDeep) {
+		t.Fatalf("rollback = %v, want errForkTooDeep", err)
+	}
+	if m.NextBlock() != 102 || len(m.window.blocks) != 2 {
+		t.Errorf("window changed: next %d, %v", m.NextBlock(), m.window.refs())
+	}
+}
+
+func TestForkWindowPush(t *testing.T) {
+	w := forkWindow{size: 2}
+	w.push(blockRecord{BlockRef: ref(1, 'a')})
+	w.push(blockRecord{BlockRef: ref(2, 'a')})
+	w.push(blockRecord{BlockRef: ref(3, 'a')})
+	if refs := w.refs(); len(refs) != 2 || refs[0].Number != 2 {
+		t.Errorf("window = %v, want the last 2 blocks", refs)
+	}
+	// 不连续的区块重新开始窗口
+	w.push(blockRecord{BlockRef: ref(10, 'a')})
+	if refs := w.refs(); len(refs) != 1 || refs[0].Number != 10 {
+		t.Errorf("window after a gap = %v", refs)
+	}
+}
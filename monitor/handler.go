@@ -0,0 +1,334 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yourname/tron-demo/metrics"
+)
+
+// Filter selects what a handler receives. Each non-empty criterion must match
+// (values within one criterion are alternatives); criteria that do not apply
+// to a callback kind are ignored for it. OnBlock and OnRollback are never filtered.
+type Filter struct {
+	// Contracts matches the emitting contract of a log, the called contract of a
+	// transaction (or any contract it emitted logs from) and the caller of an internal transaction
+	Contracts []string
+	// Signatures matches decoded logs by signature (Transfer(address,address,uint256)),
+	// event name (Transfer) or topic0 hex; a transaction matches when one of its logs does
+	Signatures []string
+	// Addresses matches transactions, logs and internal transactions involving any of the addresses
+	Addresses []string
+	// ContractTypes matches the transaction's system contract type, e.g. TriggerSmartContract
+	ContractTypes []string
+	// Assets matches native transfers by asset: AssetTRX or a TRC10 token id
+	Assets []string
+}
+
+// matchTx reports whether a transaction passes the filter
+func (f *Filter) matchTx(tx *Transaction) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, tx.Type) {
+		return false
+	}
+	if len(f.Contracts) > 0 && !slices.Contains(f.Contracts, tx.To) &&
+		!slices.ContainsFunc(tx.Logs, func(l *Log) bool { return slices.Contains(f.Contracts, l.Contract) }) {
+		return false
+	}
+	if len(f.Signatures) > 0 && !slices.ContainsFunc(tx.Logs, f.matchSignature) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.ContainsFunc(f.Addresses, tx.Involves) {
+		return false
+	}
+	return true
+}
+
+// matchLog reports whether a log passes the filter
+func (f *Filter) matchLog(l *Log) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, l.Tx.Type) {
+		return false
+	}
+	if len(f.Contracts) > 0 && !slices.Contains(f.Contracts, l.Contract) {
+		return false
+	}
+	if len(f.Signatures) > 0 && !f.matchSignature(l) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.ContainsFunc(f.Addresses, l.Involves) {
+		return false
+	}
+	return true
+}
+
+// matchStaking reports whether a staking contract passes the filter
+func (f *Filter) matchStaking(s *Staking) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, s.Tx.Type) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.ContainsFunc(f.Addresses, s.Involves) {
+		return false
+	}
+	return true
+}
+
+// matchFailure reports whether a failed transaction passes the filter
+func (f *Filter) matchFailure(fl *Failure) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, fl.Tx.Type) {
+		return false
+	}
+	if len(f.Contracts) > 0 && !slices.Contains(f.Contracts, fl.Tx.To) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.ContainsFunc(f.Addresses, fl.Involves) {
+		return false
+	}
+	return true
+}
+
+// matchTransfer reports whether a native transfer passes the filter
+func (f *Filter) matchTransfer(t *Transfer) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, t.Tx.Type) {
+		return false
+	}
+	if len(f.Assets) > 0 && !slices.Contains(f.Assets, t.Asset) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.Contains(f.Addresses, t.From) && !slices.Contains(f.Addresses, t.To) {
+		return false
+	}
+	return true
+}
+
+// matchInternal reports whether an internal transaction passes the filter
+func (f *Filter) matchInternal(it *InternalTx) bool {
+	if len(f.ContractTypes) > 0 && !slices.Contains(f.ContractTypes, it.Tx.Type) {
+		return false
+	}
+	if len(f.Contracts) > 0 && !slices.Contains(f.Contracts, it.Caller) {
+		return false
+	}
+	if len(f.Addresses) > 0 && !slices.Contains(f.Addresses, it.Caller) && !slices.Contains(f.Addresses, it.To) {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) matchSignature(l *Log) bool {
+	for _, s := range f.Signatures {
+		if l.Event != nil && (s == l.Event.Signature || s == l.Event.Name) {
+			return true
+		}
+		if len(l.Topics) > 0 && strings.EqualFold(strings.TrimPrefix(s, "0x"), fmt.Sprintf("%x", l.Topics[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry controls how often a failing handler callback is retried
+type Retry struct {
+	// Attempts is the total number of calls, including the first; 0 means 3
+	Attempts int
+	// Backoff is the wait before the first retry, doubled after each one up to
+	// maxHandlerBackoff; 0 means 1s
+	Backoff time.Duration
+}
+
+// maxHandlerBackoff caps the wait between retries of a handler callback
+const maxHandlerBackoff = time.Minute
+
+// Handler receives typed callbacks for the blocks processed by the monitor.
+// Any callback may be nil. Callbacks of one handler are called from a single
+// goroutine in chain order: OnBlock, then for each matching transaction
+// OnTransaction, its staking contract, failure, TRX/TRC10 transfers (including internal
+// payouts), matching logs and internal transactions.
+//
+// A callback that still fails after its retries stops the block unless
+// ContinueOnError is set. The block is then processed again, but only the
+// handlers that failed receive it again, from OnBlock on, so callbacks must be
+// idempotent (key on the transaction ID and index). Shutdown interrupts the
+// wait between retries; the block is then delivered again after the restart.
+type Handler struct {
+	// Name labels the handler in logs and metrics
+	Name   string
+	Filter Filter
+	Retry  Retry
+	// ContinueOnError logs and skips callbacks that fail after their retries
+	ContinueOnError bool
+
+	OnBlock       func(ctx context.Context, b *Block) error
+	OnTransaction func(ctx context.Context, tx *Transaction) error
+	// OnStaking receives successful Stake 2.0, delegation, vote and withdrawal contracts
+	OnStaking func(ctx context.Context, s *Staking) error
+	// OnFailedTx receives failed transactions with their result, fees and revert reason
+	OnFailedTx func(ctx context.Context, f *Failure) error
+	// OnTransfer receives successful native TRX and TRC10 transfers, including
+	// value paid out by contracts in internal transactions (Transfer.Internal set)
+	OnTransfer   func(ctx context.Context, t *Transfer) error
+	OnLog        func(ctx context.Context, l *Log) error
+	OnInternalTx func(ctx context.Context, it *InternalTx) error
+	// OnRollback is called newest first for every processed block abandoned by a
+	// reorganisation; only Number and Hash are set
+	OnRollback func(ctx context.Context, b *Block) error
+}
+
+// Subscribe registers a handler. Handlers are called in registration order
+// and only for blocks processed after they were registered.
+func (m *Monitor) Subscribe(h Handler) error {
+	if h.Name == "" {
+		return errors.New("handler name is required")
+	}
+	if h.OnBlock == nil && h.OnTransaction == nil && h.OnStaking == nil && h.OnFailedTx == nil && h.OnTransfer == nil && h.OnLog == nil && h.OnInternalTx == nil && h.OnRollback == nil {
+		return fmt.Errorf("handler %s has no callbacks", h.Name)
+	}
+	if h.Retry.Attempts < 1 {
+		h.Retry.Attempts = 3
+	}
+	if h.Retry.Backoff <= 0 {
+		h.Retry.Backoff = time.Second
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.handlers {
+		if other.Name == h.Name {
+			return fmt.Errorf("handler %s is already registered", h.Name)
+		}
+	}
+	m.handlers = append(m.handlers, &h)
+	return nil
+}
+
+// subscribers returns the registered handlers
+func (m *Monitor) subscribers() []*Handler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.handlers)
+}
+
+// deliveries records which handlers received a block, so that processing the
+// block again after a failure only retries the handlers that failed
+type deliveries struct {
+	block BlockRef
+	done  map[string]bool
+}
+
+// dispatch delivers a block to every handler that has not received it yet.
+// Callbacks run under a context that shutdown does not cancel; ctx only
+// interrupts the waits between retries.
+func (m *Monitor) dispatch(ctx context.Context, b *Block) error {
+	ref := BlockRef{Number: b.Number, Hash: b.Hash}
+	if m.delivered.block != ref || m.delivered.done == nil {
+		m.delivered = deliveries{block: ref, done: make(map[string]bool)}
+	}
+	var errs []error
+	for _, h := range m.subscribers() {
+		if m.delivered.done[h.Name] {
+			continue
+		}
+		if err := h.deliver(ctx, b); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.delivered.done[h.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// deliver calls the handler's callbacks for one block
+func (h *Handler) deliver(stop context.Context, b *Block) error {
+	ctx := context.WithoutCancel(stop)
+	if h.OnBlock != nil {
+		if err := h.call(stop, "block", b.Number, func() error { return h.OnBlock(ctx, b) }); err != nil {
+			return err
+		}
+	}
+	for _, tx := range b.Transactions {
+		if h.OnTransaction != nil && h.Filter.matchTx(tx) {
+			if err := h.call(stop, "transaction "+tx.ID, b.Number, func() error { return h.OnTransaction(ctx, tx) }); err != nil {
+				return err
+			}
+		}
+		if s := tx.Staking; h.OnStaking != nil && s != nil && tx.Success && h.Filter.matchStaking(s) {
+			if err := h.call(stop, "staking "+tx.ID, b.Number, func() error { return h.OnStaking(ctx, s) }); err != nil {
+				return err
+			}
+		}
+		if f := tx.Failure; h.OnFailedTx != nil && f != nil && h.Filter.matchFailure(f) {
+			if err := h.call(stop, "failed tx "+tx.ID, b.Number, func() error { return h.OnFailedTx(ctx, f) }); err != nil {
+				return err
+			}
+		}
+		if h.OnTransfer != nil {
+			for _, t := range tx.Transfers() {
+				if !h.Filter.matchTransfer(t) {
+					continue
+				}
+				if err := h.call(stop, "transfer "+t.Key(), b.Number, func() error { return h.OnTransfer(ctx, t) }); err != nil {
+					return err
+				}
+			}
+		}
+		if h.OnLog != nil {
+			for _, l := range tx.Logs {
+				if !h.Filter.matchLog(l) {
+					continue
+				}
+				if err := h.call(stop, fmt.Sprintf("log %s#%d", tx.ID, l.Index), b.Number, func() error { return h.OnLog(ctx, l) }); err != nil {
+					return err
+				}
+			}
+		}
+		if h.OnInternalTx != nil {
+			for _, it := range tx.Internal {
+				if !h.Filter.matchInternal(it) {
+					continue
+				}
+				if err := h.call(stop, fmt.Sprintf("internal tx %s#%d", tx.ID, it.Index), b.Number, func() error { return h.OnInternalTx(ctx, it) }); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rollback tells the handler that a processed block was abandoned
+func (h *Handler) rollback(stop context.Context, b *Block) error {
+	if h.OnRollback == nil {
+		return nil
+	}
+	ctx := context.WithoutCancel(stop)
+	return h.call(stop, "rollback", b.Number, func() error { return h.OnRollback(ctx, b) })
+}
+
+// call runs fn with the handler's retry policy. The error is returned when the
+// handler does not continue on errors, or when stop was cancelled during the
+// retries: a callback interrupted by shutdown is never skipped.
+func (h *Handler) call(stop context.Context, what string, block int64, fn func() error) error {
+	delay := h.Retry.Backoff
+	var err error
+	for attempt := 1; attempt <= h.Retry.Attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		metrics.HandlerErrors.WithLabelValues(EventSource, h.Name).Inc()
+		if attempt == h.Retry.Attempts {
+			break
+		}
+		log.Printf("[monitor] Handler %s failed on %s in block %d (attempt %d/%d): %v", h.Name, what, block, attempt, h.Retry.Attempts, err)
+		if !sleepCtx(stop, delay) {
+			return fmt.Errorf("handler %s: %s: %w (retry interrupted by shutdown)", h.Name, what, err)
+		}
+		delay = min(delay*2, maxHandlerBackoff)
+	}
+	if h.ContinueOnError {
+		log.Printf("[monitor] Handler %s skipped %s in block %d: %v", h.Name, what, block, err)
+		return nil
+	}
+	return fmt.Errorf("handler %s: %s: %w", h.Name, what, err)
+}
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/sink"
+)
+
+const (
+	alice = "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"
+	bob   = "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF"
+	carol = "TLa2f6VPqDgRE67v1736s7bJ8Ray5wYjU7"
+	dave  = "TKHuVq1oKVruCGLvqVexFs6dawKv6fQgFs"
+	usdt  = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	// transferTopic is keccak256("Transfer(address,address,uint256)")
+	transferTopic = "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+// filterFixture is one object of every kind a filter is applied to, all from
+// a USDT transfer from alice to bob that also pays carol in an internal
+// transaction (and, to cover every kind, delegates resources to dave)
+type filterFixture struct {
+	tx       *Transaction
+	log      *Log
+	transfer *Transfer
+	internal *InternalTx
+	staking  *Staking
+	failure  *Failure
+}
+
+func newFilterFixture(t *testing.T) filterFixture {
+	t.Helper()
+	topic, err := hex.DecodeString(transferTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &Transaction{ID: "tx1", Type: "TriggerSmartContract", Owner: alice, To: usdt, Success: true}
+	l := &Log{Tx: tx, Contract: usdt, Topics: [][]byte{topic}, Event: &abi.Event{
+		Contract:  usdt,
+		Name:      "Transfer",
+		Signature: "Transfer(address,address,uint256)",
+		Args: []abi.Arg{
+			{Name: "from", Type: "address", Indexed: true, Value: alice},
+			{Name: "to", Type: "address", Indexed: true, Value: bob},
+		},
+	}}
+	it := &InternalTx{Tx: tx, Caller: usdt, To: carol}
+	st := &Staking{Tx: tx, Owner: alice, Receiver: dave}
+	tx.Logs = []*Log{l}
+	tx.Internal = []*InternalTx{it}
+	tx.Staking = st
+	return filterFixture{
+		tx:       tx,
+		log:      l,
+		transfer: &Transfer{Tx: tx, Asset: AssetTRX, From: alice, To: bob},
+		internal: it,
+		staking:  st,
+		failure:  &Failure{Tx: tx},
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	fx := newFilterFixture(t)
+	// 每项依次为 tx、log、transfer、internal、staking、failure 是否匹配
+	tests := []struct {
+		name   string
+		filter Filter
+		want   [6]bool
+	}{
+		{"empty", Filter{}, [6]bool{true, true, true, true, true, true}},
+		{"contract type", Filter{ContractTypes: []string{"TriggerSmartContract"}}, [6]bool{true, true, true, true, true, true}},
+		{"other contract type", Filter{ContractTypes: []string{"TransferContract"}}, [6]bool{}},
+		{"contract", Filter{Contracts: []string{usdt}}, [6]bool{true, true, true, true, true, true}},
+		// Contracts 不适用于原生转账和质押
+		{"other contract", Filter{Contracts: []string{dave}}, [6]bool{false, false, true, false, true, false}},
+		{"event name", Filter{Signatures: []string{"Transfer"}}, [6]bool{true, true, true, true, true, true}},
+		{"signature", Filter{Signatures: []string{"Transfer(address,address,uint256)"}}, [6]bool{true, true, true, true, true, true}},
+		{"topic0", Filter{Signatures: []string{"0x" + transferTopic[:8] + "1BE2C89B69C2B068FC378DAA952BA7F163C4A11628F55A4DF523B3EF"}}, [6]bool{true, true, true, true, true, true}},
+		{"other event", Filter{Signatures: []string{"Approval"}}, [6]bool{false, false, true, true, true, true}},
+		{"log address", Filter{Addresses: []string{bob}}, [6]bool{true, true, true, false, false, false}},
+		{"internal recipient", Filter{Addresses: []string{carol}}, [6]bool{true, false, false, true, false, false}},
+		{"staking receiver", Filter{Addresses: []string{dave}}, [6]bool{true, false, false, false, true, false}},
+		{"owner", Filter{Addresses: []string{alice}}, [6]bool{true, true, true, false, true, true}},
+		{"asset", Filter{Assets: []string{AssetTRX}}, [6]bool{true, true, true, true, true, true}},
+		{"other asset", Filter{Assets: []string{"1002000"}}, [6]bool{true, true, false, true, true, true}},
+		// 多个条件必须同时满足
+		{"all criteria", Filter{Contracts: []string{usdt}, Signatures: []string{"Transfer"}, Addresses: []string{bob}}, [6]bool{true, true, true, false, false, false}},
+		{"one criterion fails", Filter{Contracts: []string{usdt}, Addresses: []string{"TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"}}, [6]bool{}},
+	}
+	for _, tt := range tests {
+		f := tt.filter
+		got := [6]bool{
+			f.matchTx(fx.tx),
+			f.matchLog(fx.log),
+			f.matchTransfer(fx.transfer),
+			f.matchInternal(fx.internal),
+			f.matchStaking(fx.staking),
+			f.matchFailure(fx.failure),
+		}
+		if got != tt.want {
+			t.Errorf("%s: tx, log, transfer, internal, staking, failure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerCallRetry(t *testing.T) {
+	calls := 0
+	h := &Handler{Name: "retry", Retry: Retry{Attempts: 3, Backoff: time.Millisecond}}
+	err := h.call(context.Background(), "block", 1, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("call = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	want := errors.New("down")
+	err = h.call(context.Background(), "block", 1, func() error { calls++; return want })
+	if !errors.Is(err, want) || calls != 3 {
+		t.Errorf("call = %v after %d calls, want the error after 3", err, calls)
+	}
+
+	h.ContinueOnError = true
+	if err := h.call(context.Background(), "block", 1, func() error { return want }); err != nil {
+		t.Errorf("ContinueOnError: call = %v", err)
+	}
+}
+
+func TestHandlerCallShutdown(t *testing.T) {
+	// 退避很长：关闭时必须立即返回，且即使 ContinueOnError 也不能跳过
+	h := &Handler{Name: "slow", Retry: Retry{Attempts: 5, Backoff: time.Hour}, ContinueOnError: true, OnBlock: func(ctx context.Context, _ *Block) error {
+		if ctx.Err() != nil {
+			t.Error("callback context cancelled by shutdown")
+		}
+		return errors.New("down")
+	}}
+	stop, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- h.deliver(stop, &Block{Number: 1}) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("interrupted callback was skipped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not interrupt the retry backoff")
+	}
+}
+
+func TestDispatchRetriesFailedHandlerOnly(t *testing.T) {
+	m := testMonitor(&fakeWallet{}, sink.NewChannel(10))
+	calls := map[string][]int64{}
+	failures := 1
+	for _, name := range []string{"ok", "flaky"} {
+		err := m.Subscribe(Handler{Name: name, Retry: Retry{Attempts: 1}, OnBlock: func(_ context.Context, b *Block) error {
+			calls[name] = append(calls[name], b.Number)
+			if name == "flaky" && failures > 0 {
+				failures--
+				return errors.New("down")
+			}
+			return nil
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.processBlock(context.Background(), fetched{block: testBlock(100, 'a', 'a')}); err == nil {
+		t.Fatal("handler failure not returned")
+	}
+	if m.NextBlock() != 100 {
+		t.Errorf("cursor moved to %d although a handler failed", m.NextBlock())
+	}
+	// 重新处理本块时只调用失败的处理器
+	if err := m.processBlock(context.Background(), fetched{block: testBlock(100, 'a', 'a')}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.processBlock(context.Background(), fetched{block: testBlock(101, 'a', 'a')}); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls["ok"]; len(got) != 2 || got[0] != 100 || got[1] != 101 {
+		t.Errorf("ok handler called for %v, want 100 101", got)
+	}
+	if got := calls["flaky"]; len(got) != 3 || got[0] != 100 || got[1] != 100 || got[2] != 101 {
+		t.Errorf("flaky handler called for %v, want 100 100 101", got)
+	}
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/amount"
+)
+
+// TokenValue is a TRC10 amount carried by an internal transaction, in the token's smallest unit
+type TokenValue struct {
+	ID     string
+	Amount amount.Amount
+}
+
+// InternalTx is a call made by a contract while executing a transaction
+type InternalTx struct {
+	Tx    *Transaction
+	Index int
+	Hash  string
+	// Caller is the contract making the call, To the called contract or paid account
+	Caller string
+	To     string
+	// Value is the TRX sent with the call
+	Value amount.Amount
+	// Tokens are the TRC10 tokens sent with the call
+	Tokens []TokenValue
+	// Note is the kind of call recorded by the node, e.g. call, create or suicide
+	Note string
+	// Rejected calls were reverted: nothing they carried was transferred
+	Rejected bool
+	Raw      *core.InternalTransaction
+}
+
+// newInternalTx decodes an internal transaction of a receipt
+func newInternalTx(tx *Transaction, index int, raw *core.InternalTransaction) *InternalTx {
+	it := &InternalTx{
+		Tx:       tx,
+		Index:    index,
+		Hash:     hex.EncodeToString(raw.Hash),
+		Caller:   logAddress(raw.CallerAddress),
+		To:       logAddress(raw.TransferToAddress),
+		Note:     string(raw.Note),
+		Rejected: raw.Rejected,
+		Raw:      raw,
+	}
+	var trx int64
+	for _, v := range raw.CallValueInfo {
+		if v.TokenId == "" {
+			trx += v.CallValue
+		} else if v.CallValue != 0 {
+			it.Tokens = append(it.Tokens, TokenValue{ID: v.TokenId, Amount: amount.FromInt64(v.CallValue, 0)})
+		}
+	}
+	it.Value = amount.FromInt64(trx, amount.TRXDecimals)
+	return it
+}
+
+// Transfers returns the TRX and TRC10 value moved by the call: none when it was
+// rejected or the transaction failed
+func (it *InternalTx) Transfers() []*Transfer {
+	if it.Rejected || !it.Tx.Success {
+		return nil
+	}
+	var out []*Transfer
+	if it.Value.Sign() > 0 {
+		out = append(out, &Transfer{Tx: it.Tx, Internal: it, Asset: AssetTRX, From: it.Caller, To: it.To, Amount: it.Value})
+	}
+	for _, t := range it.Tokens {
+		if t.Amount.Sign() > 0 {
+			out = append(out, &Transfer{Tx: it.Tx, Internal: it, Asset: t.ID, From: it.Caller, To: it.To, Amount: t.Amount})
+		}
+	}
+	return out
+}
+
+// data returns the published fields of the internal transaction. The values
+// are informational: what was moved is published as transfers (see Transfers).
+func (it *InternalTx) data() map[string]string {
+	data := map[string]string{
+		"hash":       it.Hash,
+		"note":       it.Note,
+		"rejected":   strconv.FormatBool(it.Rejected),
+		"call_value": it.Value.String(),
+	}
+	for i, t := range it.Tokens {
+		data[fmt.Sprintf("token_id%d", i)] = t.ID
+		data[fmt.Sprintf("token_amount%d", i)] = t.Amount.String()
+	}
+	return data
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/sink"
+)
+
+func addressBytes(t *testing.T, a string) []byte {
+	t.Helper()
+	b, err := address.Base58ToAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// payoutTx is a successful contract call in which usdt pays bob 5 TRX and 7
+// units of TRC10 token 1002000 through one internal transaction
+func payoutTx(t *testing.T, rejected bool) *Transaction {
+	t.Helper()
+	tx := &Transaction{
+		Block: &Block{Number: 100, Timestamp: time.UnixMilli(1_700_000_000_000)},
+		ID:    "tx1", Type: "TriggerSmartContract", Owner: alice, To: usdt, Success: true,
+	}
+	tx.Internal = []*InternalTx{newInternalTx(tx, 0, &core.InternalTransaction{
+		Hash:              []byte{0xab, 0xcd},
+		CallerAddress:     addressBytes(t, usdt),
+		TransferToAddress: addressBytes(t, bob),
+		CallValueInfo: []*core.InternalTransaction_CallValueInfo{
+			{CallValue: 5_000_000},
+			{TokenId: "1002000", CallValue: 7},
+		},
+		Note:     []byte("call"),
+		Rejected: rejected,
+	})}
+	return tx
+}
+
+func publishAll(t *testing.T, tx *Transaction) []sink.Event {
+	t.Helper()
+	ch := sink.NewChannel(10)
+	m := NewWithOptions(client.NewGrpcClient(""), 100, ch, Options{Watch: []string{bob}})
+	if err := m.publishTransaction(context.Background(), ch, tx); err != nil {
+		t.Fatal(err)
+	}
+	var out []sink.Event
+	for len(ch.C()) > 0 {
+		out = append(out, <-ch.C())
+	}
+	return out
+}
+
+func TestInternalPayoutPublishedOnce(t *testing.T) {
+	tx := payoutTx(t, false)
+	it := tx.Internal[0]
+	if it.Caller != usdt || it.To != bob || it.Value.String() != "5.000000" || len(it.Tokens) != 1 || it.Hash != "abcd" {
+		t.Fatalf("decoded %+v", it)
+	}
+
+	events := publishAll(t, tx)
+	// 每笔转移的价值只在一个事件里带 Value
+	valued := map[string]string{}
+	var internal *sink.Event
+	for i, ev := range events {
+		if ev.Value != nil {
+			if _, dup := valued[ev.Key]; dup {
+				t.Errorf("%s published twice", ev.Key)
+			}
+			valued[ev.Key] = ev.Type + " " + ev.Value.String()
+		}
+		if ev.Type == EventTypeInternalTx {
+			internal = &events[i]
+		}
+	}
+	want := map[string]string{
+		"tx1#internal#0#TRX":     EventTypeTRXTransfer + " 5.000000",
+		"tx1#internal#0#1002000": EventTypeTRC10Transfer + " 7",
+	}
+	if len(valued) != len(want) {
+		t.Errorf("events with a value: %v, want %v", valued, want)
+	}
+	for k, v := range want {
+		if valued[k] != v {
+			t.Errorf("%s = %q, want %q", k, valued[k], v)
+		}
+	}
+	if internal == nil {
+		t.Fatal("INTERNAL_TX not published")
+	}
+	if internal.Key != "tx1#internal#0" || internal.Value != nil || internal.Data["call_value"] != "5.000000" || internal.Data["token_amount0"] != "7" {
+		t.Errorf("INTERNAL_TX = %+v", *internal)
+	}
+}
+
+func TestRejectedInternalNotPublished(t *testing.T) {
+	tx := payoutTx(t, true)
+	if got := tx.Internal[0].Transfers(); len(got) != 0 {
+		t.Errorf("rejected call moved %d transfers", len(got))
+	}
+	if events := publishAll(t, tx); len(events) != 0 {
+		t.Errorf("published %+v for a rejected call", events)
+	}
+
+	// 交易失败时内部交易同样没有转移任何资产
+	tx = payoutTx(t, false)
+	tx.Success = false
+	if got := tx.Transfers(); len(got) != 0 {
+		t.Errorf("failed transaction moved %d transfers", len(got))
+	}
+}
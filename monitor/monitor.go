@@ -1,11 +1,53 @@
 package monitor
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
 	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
 	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
-	"time"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/health"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// EventSource is the source name attached to published events
+const EventSource = "monitor"
+
+// Event types published by the block monitor
+const (
+	EventTypeLog        = "LOG"
+	EventTypeInternalTx = "INTERNAL_TX"
+	// EventTypeTRXTransfer and EventTypeTRC10Transfer are native transfers
+	// (TransferContract / TransferAssetContract) to a watched address
+	EventTypeTRXTransfer   = "TRX_TRANSFER"
+	EventTypeTRC10Transfer = "TRC10_TRANSFER"
+	// Stake 2.0, vote and withdrawal system contracts
+	EventTypeFreeze             = "FREEZE_V2"
+	EventTypeUnfreeze           = "UNFREEZE_V2"
+	EventTypeDelegateResource   = "DELEGATE_RESOURCE"
+	EventTypeUndelegateResource = "UNDELEGATE_RESOURCE"
+	EventTypeVoteWitness        = "VOTE_WITNESS"
+	EventTypeWithdrawBalance    = "WITHDRAW_BALANCE"
+	EventTypeWithdrawUnfrozen   = "WITHDRAW_EXPIRE_UNFREEZE"
+	// EventTypeFailedTx is a failed transaction with its result, fees and revert reason
+	EventTypeFailedTx = "FAILED_TX"
+	// EventTypeRollback revokes an event delivered from a block abandoned by a
+	// reorganisation; Data["rollback_key"] is the key of the revoked event
+	EventTypeRollback = "ROLLBACK"
+	// EventTypeBlockRollback follows the rollbacks of one abandoned block
+	EventTypeBlockRollback = "BLOCK_ROLLBACK"
 )
 
 type TransactionEvent struct {
@@ -14,77 +56,669 @@ type TransactionEvent struct {
 	Data    string
 }
 
-func monitorTransactionEvents(c *client.GrpcClient, txID string) error {
-	// Get transaction info which includes events
-	txInfo, err := c.GetTransactionInfoByID(txID)
-	if err != nil {
-		return err
+// logAddress converts the 20-byte address found in logs to base58
+func logAddress(b []byte) string {
+	if len(b) == 20 {
+		b = append([]byte{address.TronBytePrefix}, b...)
 	}
+	return address.Address(b).String()
+}
 
-	// Check transaction status
-	if txInfo.Result != core.TransactionInfo_SUCESS {
-		return fmt.Errorf("transaction failed: %s", txInfo.Result.String())
-	}
+// monitorTransactionEvents publishes the logs and internal transactions of one
+// transaction that involve a watched address (all of them when none are watched)
+func (m *Monitor) monitorTransactionEvents(ctx context.Context, out sink.EventSink, tx *Transaction) error {
+	blockTs := tx.Block.Timestamp.UnixMilli()
 
 	// Process contract events
-	for _, event := range txInfo.Log {
-		fmt.Printf("Event Topics:\n")
-		for i, topic := range event.Topics {
-			fmt.Printf("  Topic[%d]: %x\n", i, topic)
+	for _, l := range tx.Logs {
+		// 监听不按合约过滤，按合约打标签会让序列数无限增长
+		metrics.EventsProcessed.WithLabelValues(EventSource, "log").Inc()
+		// 发出日志的合约或地址参数是关注地址时才发布
+		if !m.watches(l.Involves) {
+			continue
+		}
+		ev := sink.Event{
+			Source:         EventSource,
+			Type:           EventTypeLog,
+			Key:            fmt.Sprintf("%s#%d", tx.ID, l.Index),
+			TxID:           tx.ID,
+			BlockNumber:    tx.Block.Number,
+			BlockTimestamp: blockTs,
+			Contract:       l.Contract,
+			Confirmed:      tx.Block.Confirmed,
+			Data:           map[string]string{"data": hex.EncodeToString(l.Data)},
+		}
+		for j, topic := range l.Topics {
+			ev.Data["topic"+strconv.Itoa(j)] = hex.EncodeToString(topic)
+		}
+		// 按合约 ABI（或内置的常见事件表）解码出事件名和参数，解不出时只发布原始 topics/data
+		if l.Event != nil {
+			annotate(&ev, l.Event)
+		} else if l.DecodeError != nil {
+			ev.Data["decode_error"] = l.DecodeError.Error()
+		}
+		if err := out.Publish(ctx, ev); err != nil {
+			return err
 		}
-		fmt.Printf("Event Data: %x\n", event.Data)
-		fmt.Printf("Contract Address: %x\n", event.Address)
-
-		// Parse event data based on ABI
-		// Example: Transfer event
-		// topic[0] = keccak256("Transfer(address,address,uint256)")
-		// topic[1] = from address
-		// topic[2] = to address
-		// data = amount
 	}
 
-	// Process internal transactions
-	for _, internal := range txInfo.InternalTransactions {
-		fmt.Printf("Internal TX: %x\n", internal.Hash)
-		fmt.Printf("  From: %x\n", internal.CallerAddress)
-		fmt.Printf("  To: %x\n", internal.TransferToAddress)
-		fmt.Printf("  Amount: %d\n", internal.CallValueInfo)
+	// Process internal transactions. INTERNAL_TX 不带 value：转移的 TRX/TRC10 只以
+	// TRX_TRANSFER/TRC10_TRANSFER 发布一次；被拒绝的调用什么也没转移，不发布
+	for _, it := range tx.Internal {
+		if it.Rejected || !m.watches(func(a string) bool { return it.Caller == a || it.To == a }) {
+			continue
+		}
+		if err := out.Publish(ctx, sink.Event{
+			Source:         EventSource,
+			Type:           EventTypeInternalTx,
+			Key:            fmt.Sprintf("%s#internal#%d", tx.ID, it.Index),
+			TxID:           tx.ID,
+			BlockNumber:    tx.Block.Number,
+			BlockTimestamp: blockTs,
+			From:           it.Caller,
+			To:             it.To,
+			Confirmed:      tx.Block.Confirmed,
+			Data:           it.data(),
+		}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Monitor new blocks for events
-func MonitorBlockEvents(c *client.GrpcClient, startBlock int64) error {
-	currentBlock := startBlock
+// watches reports whether involves holds for a watched address; with no
+// watched addresses everything is watched
+func (m *Monitor) watches(involves func(address string) bool) bool {
+	if len(m.watch) == 0 {
+		return true
+	}
+	for a := range m.watch {
+		if involves(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotate adds a decoded event's name, signature and arguments to ev. Argument
+// names clashing with the raw fields are prefixed with "arg_"; from/to address
+// arguments also fill ev.From and ev.To.
+func annotate(ev *sink.Event, decoded *abi.Event) {
+	ev.Data["event"] = decoded.Name
+	ev.Data["signature"] = decoded.Signature
+	for _, a := range decoded.Args {
+		key := a.Name
+		if _, taken := ev.Data[key]; taken {
+			key = "arg_" + key
+		}
+		ev.Data[key] = a.String()
+		if a.Type != "address" {
+			continue
+		}
+		switch strings.TrimPrefix(a.Name, "_") {
+		case "from":
+			ev.From = a.String()
+		case "to":
+			ev.To = a.String()
+		}
+	}
+}
+
+// Options tune the monitor
+type Options struct {
+	// Workers is the number of blocks fetched ahead in parallel while catching up
+	Workers int
+	// BlockTime is how long to wait for the next block once caught up
+	BlockTime time.Duration
+	// MaxBackoff caps the wait after repeated node errors
+	MaxBackoff time.Duration
+
+	// Confirmations keeps the monitor this many blocks behind the head. Zero
+	// processes the head block itself, whose events are published unconfirmed.
+	Confirmations int64
+	// Solidity, when set, limits processing to the solidity node's latest block
+	// and takes precedence over Confirmations
+	Solidity api.WalletSolidityClient
+	// StateFile persists the cursor (last block number and hash); empty disables persistence
+	StateFile string
+	// ForkWindow is the number of recent blocks kept to find the common ancestor after a reorganisation
+	ForkWindow int
+	// ABI decodes logs; nil decodes only the built-in common events
+	ABI *abi.Registry
+	// Watch limits published native transfers to these recipients, and logs,
+	// internal transactions, staking contracts and failed transactions to those
+	// involving them; empty publishes all. Handlers are not affected, they use their Filter.
+	Watch []string
+	// EndBlock, when set, makes Run return once this block has been processed
+	EndBlock int64
+	// UnfreezeDelay is how long unstaked TRX stays locked before it can be
+	// withdrawn (chain parameter getUnfreezeDelayDays)
+	UnfreezeDelay time.Duration
+}
+
+// Finality names the rule deciding the newest block the monitor processes
+func (o Options) Finality() string {
+	switch {
+	case o.Solidity != nil:
+		return "solidity"
+	case o.Confirmations > 0:
+		return "depth"
+	default:
+		return "head"
+	}
+}
+
+// DefaultOptions returns the options used by New
+func DefaultOptions() Options {
+	return Options{
+		Workers:    8,
+		BlockTime:  3 * time.Second, // TRON block time
+		MaxBackoff: time.Minute,
+		ForkWindow: 64,
+		// 主网 getUnfreezeDelayDays 为 14 天
+		UnfreezeDelay: 14 * 24 * time.Hour,
+	}
+}
+
+// errNotProduced means the node has no such block yet; it is not an error
+var errNotProduced = errors.New("block not produced yet")
 
-	for {
-		// Get block
-		block, err := c.GetBlockByNum(currentBlock)
+// Monitor follows new blocks and publishes their events to a sink.
+// While behind the chain head it fetches up to Workers blocks ahead in
+// parallel and processes them strictly in order; once caught up it waits
+// one block time between polls. Only blocks up to the finalized head
+// (see Options.Finality) are processed. Each block's parent hash is checked
+// against the previous block; on a mismatch the monitor rolls back to the
+// common ancestor and publishes rollback events for the abandoned blocks.
+type Monitor struct {
+	client *client.GrpcClient
+	out    sink.EventSink
+	next   int64
+	opts   Options
+
+	mu           sync.Mutex
+	startedAt    time.Time
+	lastActivity time.Time
+	lastSuccess  time.Time
+	lastBlock    int64
+	lastBlockTs  time.Time
+	head         int64
+	finalized    int64
+	window       forkWindow
+	handlers     []*Handler
+	// delivered is only used by the goroutine processing blocks
+	delivered deliveries
+	watch     map[string]struct{}
+	lastSave  time.Time
+	errors    int64
+	failures  int
+	lastError string
+
+	throughput throughput
+}
+
+// New returns a monitor that starts at startBlock with DefaultOptions.
+// A startBlock of 0 starts at the newest processable block.
+func New(c *client.GrpcClient, startBlock int64, out sink.EventSink) *Monitor {
+	return NewWithOptions(c, startBlock, out, DefaultOptions())
+}
+
+// NewWithOptions returns a monitor that starts at startBlock
+func NewWithOptions(c *client.GrpcClient, startBlock int64, out sink.EventSink, opts Options) *Monitor {
+	def := DefaultOptions()
+	if opts.Workers < 1 {
+		opts.Workers = def.Workers
+	}
+	if opts.BlockTime <= 0 {
+		opts.BlockTime = def.BlockTime
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = def.MaxBackoff
+	}
+	if opts.ForkWindow < 1 {
+		opts.ForkWindow = def.ForkWindow
+	}
+	if opts.UnfreezeDelay <= 0 {
+		opts.UnfreezeDelay = def.UnfreezeDelay
+	}
+	if opts.ABI == nil {
+		opts.ABI = abi.NewRegistry(nil)
+	}
+	watch := make(map[string]struct{}, len(opts.Watch))
+	for _, a := range opts.Watch {
+		watch[a] = struct{}{}
+	}
+	return &Monitor{
+		watch:     watch,
+		client:    c,
+		out:       out,
+		next:      startBlock,
+		opts:      opts,
+		window:    forkWindow{size: opts.ForkWindow},
+		startedAt: time.Now(),
+		throughput: throughput{
+			logEvery: time.Minute,
+			since:    time.Now(),
+		},
+	}
+}
+
+// Status implements health.Reporter; Network and Tokens are left for the caller to fill in
+func (m *Monitor) Status() health.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lastTs := m.lastBlockTs
+	if lastTs.IsZero() {
+		lastTs = m.startedAt
+	}
+	lag := time.Since(lastTs)
+	lagBlocks := int64(lag / m.opts.BlockTime)
+	mode := "live"
+	if m.head > 0 {
+		lagBlocks = max(m.head-m.lastBlock, 0)
+		if m.finalized-m.lastBlock > 1 {
+			mode = "catch-up"
+		}
+	}
+	return health.Status{
+		Watcher: EventSource,
+		Cursor: map[string]any{
+			"next_block": m.NextBlock(),
+			"mode":       mode,
+			"finality":   m.opts.Finality(),
+			"finalized":  m.finalized,
+			"last_hash":  m.lastHashLocked(),
+		},
+		CursorBlock:         m.lastBlock,
+		ChainHead:           m.head,
+		LagSeconds:          lag.Seconds(),
+		LagBlocks:           lagBlocks,
+		StartedAt:           m.startedAt,
+		LastActivity:        m.lastActivity,
+		LastSuccess:         m.lastSuccess,
+		Errors:              map[string]int64{"grpc": m.errors},
+		ConsecutiveFailures: int64(m.failures),
+		LastError:           m.lastError,
+		Throughput:          m.throughput.rates(),
+	}
+}
+
+// Resume continues after the block saved in c instead of the start block
+func (m *Monitor) Resume(c *Cursor) {
+	atomic.StoreInt64(&m.next, c.NextBlock)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastBlock = c.LastBlock
+	refs := c.Recent
+	if len(refs) == 0 && c.LastHash != "" {
+		refs = []BlockRef{{Number: c.LastBlock, Hash: c.LastHash}}
+	}
+	m.window.restore(refs)
+}
+
+// lastHashLocked returns the hash of the last processed block; m.mu must be held
+func (m *Monitor) lastHashLocked() string {
+	last, _ := m.window.last()
+	return last.Hash
+}
+
+// record updates the status after a node call; errNotProduced only counts as activity
+func (m *Monitor) record(err error, header *core.BlockHeaderRaw) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.lastActivity = now
+	if err != nil && !errors.Is(err, errNotProduced) {
+		m.errors++
+		m.failures++
+		m.lastError = err.Error()
+		return
+	}
+	if err == nil {
+		m.failures = 0
+	}
+	if header != nil {
+		m.lastSuccess = now
+		m.lastBlock = header.Number
+		m.lastBlockTs = time.UnixMilli(header.Timestamp)
+	}
+}
+
+// backoff returns the wait after the current run of consecutive failures
+func (m *Monitor) backoff() time.Duration {
+	m.mu.Lock()
+	n := m.failures
+	m.mu.Unlock()
+	d := m.opts.BlockTime
+	for i := 1; i < n && d < m.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, m.opts.MaxBackoff)
+}
+
+// NextBlock returns the number of the next block to be processed.
+// After Run returns it is the block to resume from.
+func (m *Monitor) NextBlock() int64 {
+	return atomic.LoadInt64(&m.next)
+}
+
+// Run processes blocks until ctx is cancelled or Options.EndBlock has been
+// processed. A block that is being
+// processed when ctx is cancelled is finished before Run returns nil.
+// Run only fails when a reorganisation is deeper than Options.ForkWindow.
+func (m *Monitor) Run(ctx context.Context) error {
+	defer m.checkpoint()
+	for ctx.Err() == nil {
+		head, err := m.refreshHead(ctx)
 		if err != nil {
-			// Block might not exist yet
-			time.Sleep(3 * time.Second)
+			log.Printf("[monitor] Failed to get chain head: %v", err)
+			if !sleepCtx(ctx, m.backoff()) {
+				return nil
+			}
 			continue
 		}
 
-		// Process transactions in block
-		for _, tx := range block.Transactions {
-			txID := hex.EncodeToString(tx.Txid)
+		next := m.NextBlock()
+		if next == 0 {
+			// 未指定起始区块也没有保存的游标：从最新的可处理区块开始
+			next = head
+			atomic.StoreInt64(&m.next, next)
+			log.Printf("[monitor] Starting at block %d (finality %s)", next, m.opts.Finality())
+		}
+		if end := m.opts.EndBlock; end > 0 {
+			if next > end {
+				log.Printf("[monitor] Reached end block %d", end)
+				return nil
+			}
+			head = min(head, end)
+		}
+		if next > head {
+			// 已追上链头，按出块时间等待下一个块
+			if !sleepCtx(ctx, m.opts.BlockTime) {
+				return nil
+			}
+			continue
+		}
+		if lag := head - next + 1; lag > int64(m.opts.Workers) {
+			log.Printf("[monitor] Catching up: %d blocks behind head %d", lag, head)
+		}
 
-			// Get transaction info
-			txInfo, err := c.GetTransactionInfoByID(txID)
-			if err != nil {
-				continue
+		err = m.processRange(ctx, next, head)
+		m.checkpoint()
+		switch {
+		case err == nil:
+		case errors.Is(err, errFork):
+			log.Printf("[monitor] Block %d: %v, rolling back", m.NextBlock(), err)
+			if err := m.rollback(ctx); errors.Is(err, errForkTooDeep) {
+				return err
+			} else if err != nil {
+				log.Printf("[monitor] Rollback failed: %v", err)
+				if !sleepCtx(ctx, m.backoff()) {
+					return nil
+				}
+			}
+		case errors.Is(err, errNotProduced):
+			// 节点的链头比区块数据先到，稍后再取
+			if !sleepCtx(ctx, m.opts.BlockTime) {
+				return nil
+			}
+		default:
+			log.Printf("[monitor] Block %d: %v", m.NextBlock(), err)
+			if !sleepCtx(ctx, m.backoff()) {
+				return nil
 			}
+		}
+	}
+	return nil
+}
+
+// refreshHead asks the node for its latest block and returns the newest block
+// that may be processed under the configured finality
+func (m *Monitor) refreshHead(ctx context.Context) (int64, error) {
+	block, err := getNowBlock(m.client)
+	if err == nil && block.GetBlockHeader().GetRawData() == nil {
+		err = errors.New("node returned a head block without header")
+	}
+	if err != nil {
+		m.record(err, nil)
+		return 0, err
+	}
+	header := block.GetBlockHeader().GetRawData()
+	metrics.Lag.SetHead(EventSource, header.Number, time.UnixMilli(header.Timestamp))
+
+	finalized := header.Number - m.opts.Confirmations
+	if m.opts.Solidity != nil {
+		solid, err := getSolidNowBlock(ctx, m.opts.Solidity)
+		if err == nil && solid.GetBlockHeader().GetRawData() == nil {
+			err = errors.New("solidity node returned a block without header")
+		}
+		if err != nil {
+			m.record(err, nil)
+			return 0, fmt.Errorf("solidity node: %w", err)
+		}
+		finalized = solid.GetBlockHeader().GetRawData().Number
+	}
+
+	m.mu.Lock()
+	m.head = header.Number
+	m.finalized = finalized
+	m.mu.Unlock()
+	return finalized, nil
+}
+
+// fetched is a block with its receipts, ready to be processed
+type fetched struct {
+	block *api.BlockExtention
+	infos []*core.TransactionInfo
+	err   error
+}
+
+// fetch loads a block and the receipts of all its transactions
+func (m *Monitor) fetch(num int64) fetched {
+	block, err := getBlockByNum(m.client, num)
+	if err != nil {
+		return fetched{err: err}
+	}
+	// 未产生的区块节点返回空块（没有区块头），不是错误
+	if block.GetBlockHeader().GetRawData() == nil {
+		return fetched{err: fmt.Errorf("block %d: %w", num, errNotProduced)}
+	}
+	if len(block.Transactions) == 0 {
+		return fetched{block: block}
+	}
+	// 整个区块的回执一次取回，不再逐笔 GetTransactionInfoByID
+	infos, err := getTransactionInfoByBlockNum(m.client, num)
+	if err != nil {
+		return fetched{err: err}
+	}
+	// 在并发的取块阶段预取新合约的 ABI，按序处理时解码不再等节点
+	m.opts.ABI.Prefetch(logContracts(infos)...)
+	return fetched{block: block, infos: infos}
+}
 
-			// Check if transaction has events
-			if len(txInfo.Log) > 0 {
-				fmt.Printf("Transaction %s has %d events\n", txID, len(txInfo.Log))
-				monitorTransactionEvents(c, txID)
+// logContracts returns the distinct contracts that emitted the logs in infos
+func logContracts(infos []*core.TransactionInfo) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, info := range infos {
+		for _, lg := range info.GetLog() {
+			c := logAddress(lg.GetAddress())
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				out = append(out, c)
 			}
 		}
+	}
+	return out
+}
 
-		currentBlock++
-		time.Sleep(3 * time.Second) // TRON block time
+// processRange fetches [from, to] with up to Workers requests in flight and
+// processes the blocks strictly in order. It stops at the first block that
+// failed, is not produced yet or does not extend the processed chain;
+// NextBlock then points at that block.
+func (m *Monitor) processRange(ctx context.Context, from, to int64) error {
+	launch := func(num int64) <-chan fetched {
+		ch := make(chan fetched, 1)
+		go func() { ch <- m.fetch(num) }()
+		return ch
 	}
+
+	var queue []<-chan fetched
+	num := from
+	for ; num <= to && len(queue) < m.opts.Workers; num++ {
+		queue = append(queue, launch(num))
+	}
+	for len(queue) > 0 {
+		r := <-queue[0]
+		queue = queue[1:]
+		// 出错时丢弃后面已发出的请求（带缓冲的 channel，goroutine 不会泄漏）
+		m.record(r.err, nil)
+		if r.err != nil {
+			return r.err
+		}
+		if err := m.processBlock(ctx, r); err != nil {
+			// 发布或处理器失败计入连续失败，退避随之增长；分叉不是故障
+			if !errors.Is(err, errFork) {
+				m.record(err, nil)
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if num <= to {
+			queue = append(queue, launch(num))
+			num++
+		}
+	}
+	return nil
+}
+
+// processBlock publishes the events of one block and advances the cursor.
+// It returns errFork without publishing anything when the block's parent is
+// not the last processed block.
+func (m *Monitor) processBlock(ctx context.Context, r fetched) error {
+	header := r.block.GetBlockHeader().GetRawData()
+	m.mu.Lock()
+	last, ok := m.window.last()
+	m.mu.Unlock()
+	if parent := hex.EncodeToString(header.ParentHash); ok && last.Number == header.Number-1 && parent != last.Hash {
+		return fmt.Errorf("block %d parent %s, processed block %d is %s: %w", header.Number, parent, last.Number, last.Hash, errFork)
+	}
+
+	// 已开始的区块不随 ctx 取消而中断
+	inflight := context.WithoutCancel(ctx)
+	// 只有可能被回滚的链头区块以未确认发布
+	block := buildBlock(r, m.opts.ABI, m.opts.Finality() != "head", m.opts.UnfreezeDelay)
+	rec := &recorder{out: m.out}
+	for _, tx := range block.Transactions {
+		// 发布失败不推进游标，下一轮从本块重新发布（事件按 key 幂等）
+		if err := m.publishTransaction(inflight, rec, tx); err != nil {
+			return fmt.Errorf("block %d transaction %s: %w", block.Number, tx.ID, err)
+		}
+	}
+	// 处理器失败时不推进游标，下一轮从本块重新处理（事件按 key 幂等，已成功的处理器不再调用）。
+	// 处理器的重试等待可被关闭打断
+	if err := m.dispatch(ctx, block); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.window.push(blockRecord{
+		BlockRef: BlockRef{Number: block.Number, Hash: block.Hash},
+		events:   rec.events,
+		known:    true,
+	})
+	m.mu.Unlock()
+	atomic.StoreInt64(&m.next, header.Number+1)
+	m.throughput.add(len(r.block.Transactions))
+	metrics.BlocksProcessed.WithLabelValues(EventSource).Inc()
+	metrics.TransactionsProcessed.WithLabelValues(EventSource).Add(float64(len(r.block.Transactions)))
+	m.record(nil, header)
+	metrics.Lag.SetCursor(EventSource, "blocks", header.Number, time.UnixMilli(header.Timestamp))
+	m.throughput.maybeLog(m.NextBlock())
+
+	m.mu.Lock()
+	due := time.Since(m.lastSave) >= time.Second
+	m.mu.Unlock()
+	if due {
+		m.checkpoint()
+	}
+	return nil
+}
+
+// publishTransaction publishes the events of one transaction
+func (m *Monitor) publishTransaction(ctx context.Context, out sink.EventSink, tx *Transaction) error {
+	// 失败交易单独发布（结果、能量、手续费、revert 原因），其日志已被回滚
+	if tx.Failure != nil {
+		return m.publishFailure(ctx, out, tx.Failure)
+	}
+	for _, t := range tx.Transfers() {
+		if err := m.publishTransfer(ctx, out, t); err != nil {
+			return err
+		}
+	}
+	if tx.Staking != nil {
+		if err := m.publishStaking(ctx, out, tx.Staking); err != nil {
+			return err
+		}
+	}
+	// Check if transaction has events
+	if len(tx.Logs) > 0 || len(tx.Internal) > 0 {
+		return m.monitorTransactionEvents(ctx, out, tx)
+	}
+	return nil
+}
+
+// Replay processes a block read from an archive instead of the node: events
+// are published and handlers called exactly as for a fetched block. Blocks
+// must be replayed in chain order; the monitor needs no client for this.
+func (m *Monitor) Replay(ctx context.Context, block *api.BlockExtention, infos []*core.TransactionInfo) error {
+	if block.GetBlockHeader().GetRawData() == nil {
+		return errors.New("replayed block has no header")
+	}
+	return m.processBlock(ctx, fetched{block: block, infos: infos})
+}
+
+// checkpoint saves the cursor; failures are logged because the in-memory cursor is still valid
+func (m *Monitor) checkpoint() {
+	if m.opts.StateFile == "" {
+		return
+	}
+	m.mu.Lock()
+	c := Cursor{
+		NextBlock: m.NextBlock(),
+		LastBlock: m.lastBlock,
+		LastHash:  m.lastHashLocked(),
+		Recent:    m.window.refs(),
+		UpdatedAt: time.Now(),
+	}
+	m.lastSave = c.UpdatedAt
+	m.mu.Unlock()
+	if c.NextBlock == 0 {
+		return
+	}
+	if err := saveCursor(m.opts.StateFile, c); err != nil {
+		log.Printf("[monitor] Failed to save cursor to %s: %v", m.opts.StateFile, err)
+	}
+}
+
+// sleepCtx sleeps for d and reports false if ctx was cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Monitor new blocks for events and publish them to out.
+// It never returns unless the monitor fails; use New and Run for a stoppable monitor.
+func MonitorBlockEvents(c *client.GrpcClient, startBlock int64, out sink.EventSink) error {
+	return New(c, startBlock, out).Run(context.Background())
 }
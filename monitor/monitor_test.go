@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// eventsTx is a transaction with a log and an internal transaction involving
+// bob and another pair involving neither bob nor the transaction's sender
+func eventsTx() *Transaction {
+	tx := &Transaction{
+		Block: &Block{Number: 100, Timestamp: time.UnixMilli(1_700_000_000_000)},
+		ID:    "tx1", Type: "TriggerSmartContract", Owner: alice, To: usdt, Success: true,
+	}
+	transfer := func(index int, to string) *Log {
+		return &Log{Tx: tx, Index: index, Contract: usdt, Event: &abi.Event{
+			Name:      "Transfer",
+			Signature: "Transfer(address,address,uint256)",
+			Args: []abi.Arg{
+				{Name: "from", Type: "address", Value: carol},
+				{Name: "to", Type: "address", Value: to},
+			},
+		}}
+	}
+	tx.Logs = []*Log{transfer(0, bob), transfer(1, dave)}
+	tx.Internal = []*InternalTx{
+		{Tx: tx, Index: 0, Caller: usdt, To: bob, Value: amount.FromInt64(0, amount.TRXDecimals)},
+		{Tx: tx, Index: 1, Caller: usdt, To: dave, Value: amount.FromInt64(0, amount.TRXDecimals)},
+	}
+	return tx
+}
+
+func publishedKeys(t *testing.T, watch []string) []string {
+	t.Helper()
+	ch := sink.NewChannel(10)
+	m := NewWithOptions(client.NewGrpcClient(""), 100, ch, Options{Watch: watch})
+	if err := m.monitorTransactionEvents(context.Background(), ch, eventsTx()); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for len(ch.C()) > 0 {
+		keys = append(keys, (<-ch.C()).Key)
+	}
+	return keys
+}
+
+func TestTransactionEventsWatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		watch []string
+		want  []string
+	}{
+		{"no watch", nil, []string{"tx1#0", "tx1#1", "tx1#internal#0", "tx1#internal#1"}},
+		{"watched recipient", []string{bob}, []string{"tx1#0", "tx1#internal#0"}},
+		// 发出日志的合约和内部交易的调用方也算涉及
+		{"watched contract", []string{usdt}, []string{"tx1#0", "tx1#1", "tx1#internal#0", "tx1#internal#1"}},
+		{"unwatched", []string{"TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf"}, nil},
+	}
+	for _, tt := range tests {
+		if got := publishedKeys(t, tt.watch); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: published %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
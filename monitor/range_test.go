@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/sink"
+	"google.golang.org/grpc"
+)
+
+// GetTransactionInfoByBlockNum returns one successful receipt with a log for every transaction
+func (w *fakeWallet) GetTransactionInfoByBlockNum(_ context.Context, in *api.NumberMessage, _ ...grpc.CallOption) (*api.TransactionInfoList, error) {
+	list := &api.TransactionInfoList{}
+	for _, tx := range w.blocks[in.Num].GetTransactions() {
+		list.TransactionInfo = append(list.TransactionInfo, &core.TransactionInfo{
+			Id:          tx.Txid,
+			BlockNumber: in.Num,
+			Log:         []*core.TransactionInfo_Log{{Address: make([]byte, 20)}},
+		})
+	}
+	return list, nil
+}
+
+// gatedWallet serves blocks like fakeWallet, but GetBlockByNum2 for a block
+// with a gate waits until the gate is closed. It records the order in which
+// requests completed.
+type gatedWallet struct {
+	fakeWallet
+	gates map[int64]chan struct{}
+	fail  map[int64]error
+
+	mu   sync.Mutex
+	done []int64
+}
+
+func (w *gatedWallet) GetBlockByNum2(ctx context.Context, in *api.NumberMessage, opts ...grpc.CallOption) (*api.BlockExtention, error) {
+	if gate, ok := w.gates[in.Num]; ok {
+		<-gate
+	}
+	defer func() {
+		w.mu.Lock()
+		w.done = append(w.done, in.Num)
+		w.mu.Unlock()
+	}()
+	if err := w.fail[in.Num]; err != nil {
+		return nil, err
+	}
+	return w.fakeWallet.GetBlockByNum2(ctx, in, opts...)
+}
+
+func (w *gatedWallet) completed() []int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.done)
+}
+
+// waitCompleted waits until n requests completed
+func (w *gatedWallet) waitCompleted(t *testing.T, n int) []int64 {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		done := w.completed()
+		if len(done) >= n {
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d requests completed: %v", len(done), n, done)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func chain(from, to int64) map[int64]*api.BlockExtention {
+	blocks := make(map[int64]*api.BlockExtention)
+	for num := from; num <= to; num++ {
+		b := testBlock(num, 'a', 'a')
+		// 每块一笔带日志的交易，发布出的事件反映处理顺序
+		b.Transactions = []*api.TransactionExtention{{Txid: []byte{byte(num)}}}
+		blocks[num] = b
+	}
+	return blocks
+}
+
+// rangeMonitor returns a monitor with the given workers and a handler recording the processed blocks
+func rangeMonitor(t *testing.T, wallet *gatedWallet, workers int, onBlock func(*Block)) (*Monitor, func() []int64) {
+	t.Helper()
+	m := testMonitor(wallet, sink.NewChannel(100))
+	m.opts.Workers = workers
+	var mu sync.Mutex
+	var seen []int64
+	err := m.Subscribe(Handler{Name: "order", OnBlock: func(_ context.Context, b *Block) error {
+		mu.Lock()
+		seen = append(seen, b.Number)
+		mu.Unlock()
+		if onBlock != nil {
+			onBlock(b)
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, func() []int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(seen)
+	}
+}
+
+func TestProcessRangeInOrder(t *testing.T) {
+	wallet := &gatedWallet{fakeWallet: fakeWallet{blocks: chain(100, 105)}, gates: make(map[int64]chan struct{})}
+	for num := int64(100); num <= 103; num++ {
+		wallet.gates[num] = make(chan struct{})
+	}
+	m, seen := rangeMonitor(t, wallet, 4, nil)
+
+	errc := make(chan error, 1)
+	go func() { errc <- m.processRange(context.Background(), 100, 105) }()
+	// 前 4 个请求倒序完成
+	for i, num := range []int64{103, 102, 101, 100} {
+		close(wallet.gates[num])
+		wallet.waitCompleted(t, i+1)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	if done := wallet.completed(); !slices.Equal(done[:4], []int64{103, 102, 101, 100}) {
+		t.Errorf("fetches completed in order %v, want 103 102 101 100 first", done)
+	}
+	if got := seen(); !slices.Equal(got, []int64{100, 101, 102, 103, 104, 105}) {
+		t.Errorf("processed %v", got)
+	}
+	if m.NextBlock() != 106 {
+		t.Errorf("NextBlock = %d, want 106", m.NextBlock())
+	}
+}
+
+func TestProcessRangeStopsOnError(t *testing.T) {
+	// SDK 包装错误时不带 %w，只能按消息比较
+	want := errors.New("node unavailable")
+	wallet := &gatedWallet{
+		fakeWallet: fakeWallet{blocks: chain(100, 110)},
+		gates:      map[int64]chan struct{}{103: make(chan struct{})},
+		fail:       map[int64]error{102: want},
+	}
+	m, seen := rangeMonitor(t, wallet, 4, nil)
+
+	err := m.processRange(context.Background(), 100, 110)
+	if err == nil || !strings.Contains(err.Error(), want.Error()) {
+		t.Fatalf("processRange = %v, want %v", err, want)
+	}
+	if got := seen(); !slices.Equal(got, []int64{100, 101}) {
+		t.Errorf("processed %v, want 100 101", got)
+	}
+	if m.NextBlock() != 102 {
+		t.Errorf("NextBlock = %d, want the failed block 102", m.NextBlock())
+	}
+
+	// 出错时在途的是 102-105：103 完成后不会阻塞，也不会再发出新的请求
+	close(wallet.gates[103])
+	wallet.waitCompleted(t, 6)
+	time.Sleep(10 * time.Millisecond)
+	if done := wallet.completed(); len(done) != 6 || slices.Max(done) != 105 {
+		t.Errorf("fetched %v after the error, want 100-105 only", done)
+	}
+}
+
+func TestProcessRangeStopsOnCancel(t *testing.T) {
+	wallet := &gatedWallet{fakeWallet: fakeWallet{blocks: chain(100, 110)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, seen := rangeMonitor(t, wallet, 2, func(b *Block) {
+		if b.Number == 101 {
+			cancel()
+		}
+	})
+
+	// 取消时当前区块处理完，游标指向下一个区块，不算错误
+	if err := m.processRange(ctx, 100, 110); err != nil {
+		t.Fatalf("processRange = %v", err)
+	}
+	if got := seen(); !slices.Equal(got, []int64{100, 101}) {
+		t.Errorf("processed %v, want 100 101", got)
+	}
+	if m.NextBlock() != 102 {
+		t.Errorf("NextBlock = %d, want 102", m.NextBlock())
+	}
+}
+
+type failingSink struct{ err error }
+
+func (s failingSink) Publish(context.Context, sink.Event) error { return s.err }
+func (s failingSink) Close() error                              { return nil }
+
+func TestProcessRangePublishError(t *testing.T) {
+	want := errors.New("sink down")
+	wallet := &gatedWallet{fakeWallet: fakeWallet{blocks: chain(100, 103)}}
+	m := testMonitor(wallet, failingSink{want})
+
+	// 发布失败不推进游标，下一轮从同一区块重新发布
+	if err := m.processRange(context.Background(), 100, 103); !errors.Is(err, want) {
+		t.Fatalf("processRange = %v, want %v", err, want)
+	}
+	if m.NextBlock() != 100 {
+		t.Errorf("NextBlock = %d, want 100", m.NextBlock())
+	}
+}
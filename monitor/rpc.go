@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/metrics"
+)
+
+// Instrumented wrappers around the full node calls used by the monitor
+
+func getNowBlock(c *client.GrpcClient) (*api.BlockExtention, error) {
+	start := time.Now()
+	block, err := c.GetNowBlock()
+	metrics.ObserveGRPC("GetNowBlock", start, err)
+	return block, err
+}
+
+// rpcTimeout bounds calls made without the SDK client (solidity node, chain parameters)
+const rpcTimeout = 10 * time.Second
+
+func getSolidNowBlock(ctx context.Context, c api.WalletSolidityClient) (*api.BlockExtention, error) {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+	start := time.Now()
+	block, err := c.GetNowBlock2(ctx, &api.EmptyMessage{})
+	metrics.ObserveGRPC("WalletSolidity/GetNowBlock", start, err)
+	return block, err
+}
+
+func getBlockByNum(c *client.GrpcClient, num int64) (*api.BlockExtention, error) {
+	start := time.Now()
+	block, err := c.GetBlockByNum(num)
+	metrics.ObserveGRPC("GetBlockByNum", start, err)
+	return block, err
+}
+
+// getTransactionInfoByBlockNum returns the receipts of every transaction in a block in one call
+// (GetBlockInfoByNum in the SDK wraps the GetTransactionInfoByBlockNum RPC)
+func getTransactionInfoByBlockNum(c *client.GrpcClient, num int64) ([]*core.TransactionInfo, error) {
+	start := time.Now()
+	infos, err := c.GetBlockInfoByNum(num)
+	metrics.ObserveGRPC("GetTransactionInfoByBlockNum", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return infos.GetTransactionInfo(), nil
+}
+
+// UnfreezeDelay reads the chain parameter getUnfreezeDelayDays: how long unstaked TRX stays locked.
+// The SDK has no wrapper for GetChainParameters, so the wallet client is called directly.
+func UnfreezeDelay(c *client.GrpcClient) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	start := time.Now()
+	params, err := c.Client.GetChainParameters(ctx, &api.EmptyMessage{})
+	metrics.ObserveGRPC("GetChainParameters", start, err)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range params.GetChainParameter() {
+		if p.GetKey() == "getUnfreezeDelayDays" {
+			return time.Duration(p.GetValue()) * 24 * time.Hour, nil
+		}
+	}
+	return 0, errors.New("chain parameter getUnfreezeDelayDays not found")
+}
+
+// abiTimeout bounds one GetContract call; a slow node must not hold up the
+// fetch workers for long, the ABI is simply retried later
+const abiTimeout = 3 * time.Second
+
+// ContractABIFetcher returns an abi.Fetcher reading contract ABIs from the full node
+func ContractABIFetcher(c *client.GrpcClient) abi.Fetcher {
+	return func(contract string) (*core.SmartContract_ABI, error) {
+		addr, err := address.Base58ToAddress(contract)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), abiTimeout)
+		defer cancel()
+		start := time.Now()
+		sc, err := c.Client.GetContract(ctx, client.GetMessageBytes(addr))
+		metrics.ObserveGRPC("GetContract", start, err)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil {
+			return nil, fmt.Errorf("contract %s: no smart contract", contract)
+		}
+		return sc.GetAbi(), nil
+	}
+}
@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// Vote is one witness vote of a VoteWitnessContract
+type Vote struct {
+	Witness string
+	Count   int64
+}
+
+// Staking is a Stake 2.0 resource, delegation, vote or withdrawal system contract
+type Staking struct {
+	Tx *Transaction
+	// Type is the published event type, e.g. EventTypeDelegateResource
+	Type string
+	// Owner signed the contract; Receiver is the account resources were
+	// delegated to or reclaimed from
+	Owner    string
+	Receiver string
+	// Resource is BANDWIDTH or ENERGY (TRON_POWER for votes-only stakes); empty for votes and withdrawals
+	Resource string
+	// Amount is the TRX staked, unstaked, delegated, reclaimed or withdrawn.
+	// Withdrawals take it from the receipt and are zero when the node returned none.
+	Amount amount.Amount
+	// Lock and LockPeriod (in blocks) are set for locked delegations
+	Lock       bool
+	LockPeriod int64
+	// WithdrawableAt is when unstaked TRX can be withdrawn (UnfreezeBalanceV2 only)
+	WithdrawableAt time.Time
+	Votes          []Vote
+}
+
+// decodeStaking reads a Stake 2.0, vote or withdrawal contract parameter; nil for other contract types
+func decodeStaking(c *core.Transaction_Contract) (*Staking, error) {
+	switch c.GetType() {
+	case core.Transaction_Contract_FreezeBalanceV2Contract:
+		var p core.FreezeBalanceV2Contract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{
+			Type:     EventTypeFreeze,
+			Owner:    logAddress(p.OwnerAddress),
+			Resource: p.Resource.String(),
+			Amount:   amount.FromInt64(p.FrozenBalance, amount.TRXDecimals),
+		}, nil
+	case core.Transaction_Contract_UnfreezeBalanceV2Contract:
+		var p core.UnfreezeBalanceV2Contract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{
+			Type:     EventTypeUnfreeze,
+			Owner:    logAddress(p.OwnerAddress),
+			Resource: p.Resource.String(),
+			Amount:   amount.FromInt64(p.UnfreezeBalance, amount.TRXDecimals),
+		}, nil
+	case core.Transaction_Contract_DelegateResourceContract:
+		var p core.DelegateResourceContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{
+			Type:       EventTypeDelegateResource,
+			Owner:      logAddress(p.OwnerAddress),
+			Receiver:   logAddress(p.ReceiverAddress),
+			Resource:   p.Resource.String(),
+			Amount:     amount.FromInt64(p.Balance, amount.TRXDecimals),
+			Lock:       p.Lock,
+			LockPeriod: p.LockPeriod,
+		}, nil
+	case core.Transaction_Contract_UnDelegateResourceContract:
+		var p core.UnDelegateResourceContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{
+			Type:     EventTypeUndelegateResource,
+			Owner:    logAddress(p.OwnerAddress),
+			Receiver: logAddress(p.ReceiverAddress),
+			Resource: p.Resource.String(),
+			Amount:   amount.FromInt64(p.Balance, amount.TRXDecimals),
+		}, nil
+	case core.Transaction_Contract_VoteWitnessContract:
+		var p core.VoteWitnessContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		s := &Staking{Type: EventTypeVoteWitness, Owner: logAddress(p.OwnerAddress), Amount: amount.FromInt64(0, amount.TRXDecimals)}
+		for _, v := range p.Votes {
+			s.Votes = append(s.Votes, Vote{Witness: logAddress(v.VoteAddress), Count: v.VoteCount})
+		}
+		return s, nil
+	case core.Transaction_Contract_WithdrawBalanceContract:
+		var p core.WithdrawBalanceContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{Type: EventTypeWithdrawBalance, Owner: logAddress(p.OwnerAddress)}, nil
+	case core.Transaction_Contract_WithdrawExpireUnfreezeContract:
+		var p core.WithdrawExpireUnfreezeContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Staking{Type: EventTypeWithdrawUnfrozen, Owner: logAddress(p.OwnerAddress)}, nil
+	}
+	return nil, nil
+}
+
+// settle fills in what only the block and receipt know: withdrawn amounts and
+// when unstaked TRX becomes withdrawable
+func (s *Staking) settle(info *core.TransactionInfo, unfreezeDelay time.Duration) {
+	switch s.Type {
+	case EventTypeWithdrawBalance:
+		s.Amount = amount.FromInt64(info.GetWithdrawAmount(), amount.TRXDecimals)
+	case EventTypeWithdrawUnfrozen:
+		s.Amount = amount.FromInt64(info.GetWithdrawExpireAmount(), amount.TRXDecimals)
+	case EventTypeUnfreeze:
+		s.WithdrawableAt = s.Tx.Block.Timestamp.Add(unfreezeDelay)
+	}
+}
+
+// Involves reports whether address is the owner, the receiver or a voted witness
+func (s *Staking) Involves(address string) bool {
+	if s.Owner == address || s.Receiver == address {
+		return true
+	}
+	for _, v := range s.Votes {
+		if v.Witness == address {
+			return true
+		}
+	}
+	return false
+}
+
+// publishStaking publishes a staking contract involving a watched address
+// (any contract when none are watched)
+func (m *Monitor) publishStaking(ctx context.Context, out sink.EventSink, s *Staking) error {
+	if !m.watches(s.Involves) {
+		return nil
+	}
+
+	tx := s.Tx
+	data := map[string]string{"contract_type": tx.Type}
+	if s.Resource != "" {
+		data["resource"] = s.Resource
+	}
+	if s.Lock {
+		data["lock"] = "true"
+		data["lock_period"] = strconv.FormatInt(s.LockPeriod, 10)
+	}
+	if !s.WithdrawableAt.IsZero() {
+		data["withdrawable_at"] = s.WithdrawableAt.UTC().Format(time.RFC3339)
+	}
+	for i, v := range s.Votes {
+		data[fmt.Sprintf("witness%d", i)] = v.Witness
+		data[fmt.Sprintf("votes%d", i)] = strconv.FormatInt(v.Count, 10)
+	}
+	metrics.EventsProcessed.WithLabelValues(EventSource, s.Type).Inc()
+	value := s.Amount
+	key := tx.ID + "#staking"
+	log.Printf("[monitor] %s: owner=%s receiver=%s resource=%s amount=%s tx=%s key=%s",
+		s.Type, s.Owner, s.Receiver, s.Resource, value, tx.ID, key)
+	return out.Publish(ctx, sink.Event{
+		Source:         EventSource,
+		Type:           s.Type,
+		Key:            key,
+		TxID:           tx.ID,
+		BlockNumber:    tx.Block.Number,
+		BlockTimestamp: tx.Block.Timestamp.UnixMilli(),
+		From:           s.Owner,
+		To:             s.Receiver,
+		Value:          &value,
+		Confirmed:      tx.Block.Confirmed,
+		Data:           data,
+	})
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func contract(t *testing.T, typ core.Transaction_Contract_ContractType, p proto.Message) *core.Transaction_Contract {
+	t.Helper()
+	param, err := anypb.New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &core.Transaction_Contract{Type: typ, Parameter: param}
+}
+
+func TestDecodeStaking(t *testing.T) {
+	owner, receiver := addressBytes(t, alice), addressBytes(t, bob)
+	tests := []struct {
+		name     string
+		contract *core.Transaction_Contract
+		want     Staking
+		amount   string
+	}{
+		{"freeze", contract(t, core.Transaction_Contract_FreezeBalanceV2Contract, &core.FreezeBalanceV2Contract{
+			OwnerAddress: owner, FrozenBalance: 100_000_000, Resource: core.ResourceCode_ENERGY,
+		}), Staking{Type: EventTypeFreeze, Owner: alice, Resource: "ENERGY"}, "100.000000"},
+		// 未设置 resource 即 BANDWIDTH
+		{"unfreeze", contract(t, core.Transaction_Contract_UnfreezeBalanceV2Contract, &core.UnfreezeBalanceV2Contract{
+			OwnerAddress: owner, UnfreezeBalance: 2_500_000,
+		}), Staking{Type: EventTypeUnfreeze, Owner: alice, Resource: "BANDWIDTH"}, "2.500000"},
+		{"delegate", contract(t, core.Transaction_Contract_DelegateResourceContract, &core.DelegateResourceContract{
+			OwnerAddress: owner, ReceiverAddress: receiver, Resource: core.ResourceCode_ENERGY, Balance: 50_000_000, Lock: true, LockPeriod: 86400,
+		}), Staking{Type: EventTypeDelegateResource, Owner: alice, Receiver: bob, Resource: "ENERGY", Lock: true, LockPeriod: 86400}, "50.000000"},
+		{"undelegate", contract(t, core.Transaction_Contract_UnDelegateResourceContract, &core.UnDelegateResourceContract{
+			OwnerAddress: owner, ReceiverAddress: receiver, Resource: core.ResourceCode_BANDWIDTH, Balance: 1_000_000,
+		}), Staking{Type: EventTypeUndelegateResource, Owner: alice, Receiver: bob, Resource: "BANDWIDTH"}, "1.000000"},
+		{"vote", contract(t, core.Transaction_Contract_VoteWitnessContract, &core.VoteWitnessContract{
+			OwnerAddress: owner, Votes: []*core.VoteWitnessContract_Vote{
+				{VoteAddress: addressBytes(t, carol), VoteCount: 10},
+				{VoteAddress: addressBytes(t, dave), VoteCount: 3},
+			},
+		}), Staking{Type: EventTypeVoteWitness, Owner: alice, Votes: []Vote{{carol, 10}, {dave, 3}}}, "0.000000"},
+		// 提取的金额在 settle 时从收据读取
+		{"withdraw balance", contract(t, core.Transaction_Contract_WithdrawBalanceContract, &core.WithdrawBalanceContract{
+			OwnerAddress: owner,
+		}), Staking{Type: EventTypeWithdrawBalance, Owner: alice}, "0"},
+		{"withdraw unfrozen", contract(t, core.Transaction_Contract_WithdrawExpireUnfreezeContract, &core.WithdrawExpireUnfreezeContract{
+			OwnerAddress: owner,
+		}), Staking{Type: EventTypeWithdrawUnfrozen, Owner: alice}, "0"},
+	}
+	for _, tt := range tests {
+		s, err := decodeStaking(tt.contract)
+		if err != nil || s == nil {
+			t.Errorf("%s: decodeStaking = %v, %v", tt.name, s, err)
+			continue
+		}
+		if s.Type != tt.want.Type || s.Owner != tt.want.Owner || s.Receiver != tt.want.Receiver || s.Resource != tt.want.Resource ||
+			s.Lock != tt.want.Lock || s.LockPeriod != tt.want.LockPeriod {
+			t.Errorf("%s: decodeStaking = %+v, want %+v", tt.name, *s, tt.want)
+		}
+		if s.Amount.String() != tt.amount {
+			t.Errorf("%s: amount %q, want %q", tt.name, s.Amount.String(), tt.amount)
+		}
+		if len(s.Votes) != len(tt.want.Votes) {
+			t.Errorf("%s: votes %v, want %v", tt.name, s.Votes, tt.want.Votes)
+		}
+		for i := range tt.want.Votes {
+			if i < len(s.Votes) && s.Votes[i] != tt.want.Votes[i] {
+				t.Errorf("%s: vote %d = %v, want %v", tt.name, i, s.Votes[i], tt.want.Votes[i])
+			}
+		}
+	}
+
+	// 其他合约类型不是质押；参数与类型不符是错误
+	if s, err := decodeStaking(contract(t, core.Transaction_Contract_TransferContract, &core.TransferContract{OwnerAddress: owner})); s != nil || err != nil {
+		t.Errorf("TransferContract: decodeStaking = %v, %v", s, err)
+	}
+	if s, err := decodeStaking(contract(t, core.Transaction_Contract_DelegateResourceContract, &core.TransferContract{OwnerAddress: owner})); err == nil {
+		t.Errorf("mismatched parameter: decodeStaking = %+v", s)
+	}
+}
+
+func TestStakingSettle(t *testing.T) {
+	block := &Block{Number: 100, Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	info := &core.TransactionInfo{WithdrawAmount: 12_345_678, WithdrawExpireAmount: 2_500_000}
+	tests := []struct {
+		typ    string
+		amount string
+	}{
+		{EventTypeWithdrawBalance, "12.345678"},
+		{EventTypeWithdrawUnfrozen, "2.500000"},
+	}
+	for _, tt := range tests {
+		s := &Staking{Tx: &Transaction{Block: block}, Type: tt.typ}
+		s.settle(info, 14*24*time.Hour)
+		if s.Amount.String() != tt.amount {
+			t.Errorf("%s: amount %s, want %s", tt.typ, s.Amount, tt.amount)
+		}
+		// 没有收据时金额为 0
+		s.settle(nil, 0)
+		if !s.Amount.IsZero() || s.Amount.String() != "0.000000" {
+			t.Errorf("%s without a receipt: amount %s", tt.typ, s.Amount)
+		}
+	}
+
+	s := &Staking{Tx: &Transaction{Block: block}, Type: EventTypeUnfreeze}
+	s.settle(info, 14*24*time.Hour)
+	if want := block.Timestamp.Add(14 * 24 * time.Hour); !s.WithdrawableAt.Equal(want) {
+		t.Errorf("WithdrawableAt = %v, want %v", s.WithdrawableAt, want)
+	}
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourname/tron-demo/health"
+)
+
+// throughput measures blocks and transactions processed per second and logs
+// the rates once per logEvery
+type throughput struct {
+	logEvery time.Duration
+
+	mu     sync.Mutex
+	since  time.Time
+	blocks int64
+	txs    int64
+	last   *health.Throughput
+}
+
+// add counts one processed block with txs transactions
+func (t *throughput) add(txs int) {
+	t.mu.Lock()
+	t.blocks++
+	t.txs += int64(txs)
+	t.mu.Unlock()
+}
+
+// maybeLog closes the interval and logs the rates once logEvery has passed
+func (t *throughput) maybeLog(next int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	elapsed := time.Since(t.since)
+	if elapsed < t.logEvery {
+		return
+	}
+	t.last = &health.Throughput{
+		BlocksPerSecond:       float64(t.blocks) / elapsed.Seconds(),
+		TransactionsPerSecond: float64(t.txs) / elapsed.Seconds(),
+	}
+	log.Printf("[monitor] %d blocks, %d txs in %v (%.2f blocks/s, %.1f tx/s), next block %d",
+		t.blocks, t.txs, elapsed.Round(time.Second), t.last.BlocksPerSecond, t.last.TransactionsPerSecond, next)
+	t.since = time.Now()
+	t.blocks, t.txs = 0, 0
+}
+
+// rates returns the rates of the last completed interval, nil before the first one
+func (t *throughput) rates() *health.Throughput {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/metrics"
+	"github.com/yourname/tron-demo/sink"
+)
+
+// AssetTRX is Transfer.Asset for native TRX transfers
+const AssetTRX = "TRX"
+
+// Transfer is a native TRX (TransferContract) or TRC10 (TransferAssetContract)
+// transfer, or TRX/TRC10 value sent by a contract in an internal transaction
+type Transfer struct {
+	Tx *Transaction
+	// Internal is the internal transaction that moved the value; nil for the transaction's own contract
+	Internal *InternalTx
+	// Asset is AssetTRX or the TRC10 token id
+	Asset string
+	From  string
+	To    string
+	// Amount is in TRX for TRX transfers and in the token's smallest unit for TRC10
+	// (the node does not return the token precision with the transaction)
+	Amount amount.Amount
+}
+
+// decodeTransfer reads a TransferContract or TransferAssetContract parameter
+func decodeTransfer(c *core.Transaction_Contract) (*Transfer, error) {
+	switch c.GetType() {
+	case core.Transaction_Contract_TransferContract:
+		var p core.TransferContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Transfer{
+			Asset:  AssetTRX,
+			From:   logAddress(p.OwnerAddress),
+			To:     logAddress(p.ToAddress),
+			Amount: amount.FromInt64(p.Amount, amount.TRXDecimals),
+		}, nil
+	case core.Transaction_Contract_TransferAssetContract:
+		var p core.TransferAssetContract
+		if err := c.GetParameter().UnmarshalTo(&p); err != nil {
+			return nil, err
+		}
+		return &Transfer{
+			Asset:  string(p.AssetName),
+			From:   logAddress(p.OwnerAddress),
+			To:     logAddress(p.ToAddress),
+			Amount: amount.FromInt64(p.Amount, 0),
+		}, nil
+	}
+	return nil, nil
+}
+
+// publishTransfer publishes a native transfer to a watched address (any
+// address when none are watched), the same rule gridwatcher applies to token transfers
+func (m *Monitor) publishTransfer(ctx context.Context, out sink.EventSink, t *Transfer) error {
+	if len(m.watch) > 0 {
+		if _, ok := m.watch[t.To]; !ok {
+			return nil
+		}
+	}
+	eventType := EventTypeTRXTransfer
+	data := map[string]string{"contract_type": t.Tx.Type}
+	if t.Asset != AssetTRX {
+		eventType = EventTypeTRC10Transfer
+		data["token_id"] = t.Asset
+	}
+	if t.Internal != nil {
+		// 合约内部转出（比如合约向我们的地址付款）
+		data["internal_hash"] = t.Internal.Hash
+		data["note"] = t.Internal.Note
+	}
+	// TRC10 代币 id 不计入标签，否则序列数随链上代币无限增长
+	label := "trx"
+	if t.Asset != AssetTRX {
+		label = "trc10"
+	}
+	metrics.EventsProcessed.WithLabelValues(EventSource, label).Inc()
+	value := t.Amount
+	key := t.Key()
+	log.Printf("[monitor] %s hit: to=%s from=%s value=%s asset=%s tx=%s confirmed=%v key=%s",
+		eventType, t.To, t.From, value, t.Asset, t.Tx.ID, t.Tx.Block.Confirmed, key)
+	return out.Publish(ctx, sink.Event{
+		Source:         EventSource,
+		Type:           eventType,
+		Key:            key,
+		TxID:           t.Tx.ID,
+		BlockNumber:    t.Tx.Block.Number,
+		BlockTimestamp: t.Tx.Block.Timestamp.UnixMilli(),
+		From:           t.From,
+		To:             t.To,
+		Value:          &value,
+		Unscaled:       t.Asset != AssetTRX, // TRC10 的精度不在交易里，按最小单位发布
+		Confirmed:      t.Tx.Block.Confirmed,
+		Data:           data,
+	})
+}
+
+// Key is the idempotency key: txid#transfer for the transaction's own contract
+// (a transaction carries one system contract) and txid#internal#<index>#<asset>
+// for value sent by an internal transaction
+func (t *Transfer) Key() string {
+	if t.Internal != nil {
+		return fmt.Sprintf("%s#internal#%d#%s", t.Tx.ID, t.Internal.Index, t.Asset)
+	}
+	return t.Tx.ID + "#transfer"
+}
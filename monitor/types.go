@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/api"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/abi"
+	"github.com/yourname/tron-demo/metrics"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Block is a processed block as seen by handlers
+type Block struct {
+	Number     int64
+	Hash       string
+	ParentHash string
+	Timestamp  time.Time
+	// Confirmed is false when the block may still be reverted (finality head)
+	Confirmed    bool
+	Transactions []*Transaction
+	// Raw is the node's block, with the header and transactions
+	Raw *api.BlockExtention
+}
+
+// Transaction is one transaction of a block with its receipt
+type Transaction struct {
+	Block *Block
+	ID    string
+	// Type is the system contract type, e.g. TransferContract or TriggerSmartContract
+	Type string
+	// Owner is the account that signed the transaction; To is the recipient or
+	// the called contract when the contract type has one
+	Owner string
+	To    string
+	// Success is false for transactions whose receipt reports a failure
+	Success bool
+	// Failure is set when Success is false
+	Failure *Failure
+	// Transfer is set for native TRX and TRC10 transfers
+	Transfer *Transfer
+	// Staking is set for Stake 2.0, vote and withdrawal contracts
+	Staking  *Staking
+	Logs     []*Log
+	Internal []*InternalTx
+	// Raw and Info are the node's transaction and receipt; Info is nil when the node returned none
+	Raw  *core.Transaction
+	Info *core.TransactionInfo
+}
+
+// Log is a contract event log
+type Log struct {
+	Tx       *Transaction
+	Index    int
+	Contract string
+	Topics   [][]byte
+	Data     []byte
+	// Event is the decoded log; nil when neither the contract ABI nor the built-in table knows it
+	Event *abi.Event
+	// DecodeError is set when the log matched a known event but could not be decoded
+	DecodeError error
+}
+
+// Transfers returns the TRX and TRC10 value moved by a successful transaction:
+// its own transfer contract followed by value sent in internal transactions
+func (t *Transaction) Transfers() []*Transfer {
+	if !t.Success {
+		return nil
+	}
+	var out []*Transfer
+	if t.Transfer != nil {
+		out = append(out, t.Transfer)
+	}
+	for _, it := range t.Internal {
+		out = append(out, it.Transfers()...)
+	}
+	return out
+}
+
+// Involves reports whether address is the owner or recipient of the transaction
+// or appears in its staking contract, logs or internal transactions
+func (t *Transaction) Involves(address string) bool {
+	if t.Owner == address || t.To == address {
+		return true
+	}
+	if t.Staking != nil && t.Staking.Involves(address) {
+		return true
+	}
+	for _, l := range t.Logs {
+		if l.Involves(address) {
+			return true
+		}
+	}
+	for _, it := range t.Internal {
+		if it.Caller == address || it.To == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Involves reports whether address is the emitting contract or an address argument of the log
+func (l *Log) Involves(address string) bool {
+	if l.Contract == address {
+		return true
+	}
+	if l.Event == nil {
+		return false
+	}
+	for _, a := range l.Event.Args {
+		if a.Type == "address" && a.String() == address {
+			return true
+		}
+	}
+	return false
+}
+
+// buildBlock assembles the typed view of a fetched block, decoding its logs with
+// reg; unfreezeDelay dates when unstaked TRX becomes withdrawable
+func buildBlock(r fetched, reg *abi.Registry, confirmed bool, unfreezeDelay time.Duration) *Block {
+	header := r.block.GetBlockHeader().GetRawData()
+	b := &Block{
+		Number:     header.Number,
+		Hash:       hex.EncodeToString(r.block.Blockid),
+		ParentHash: hex.EncodeToString(header.ParentHash),
+		Timestamp:  time.UnixMilli(header.Timestamp),
+		Confirmed:  confirmed,
+		Raw:        r.block,
+	}
+
+	infos := make(map[string]*core.TransactionInfo, len(r.infos))
+	for _, info := range r.infos {
+		infos[hex.EncodeToString(info.Id)] = info
+	}
+	for _, ext := range r.block.Transactions {
+		tx := &Transaction{Block: b, ID: hex.EncodeToString(ext.Txid), Raw: ext.Transaction, Success: true}
+		if contracts := ext.GetTransaction().GetRawData().GetContract(); len(contracts) > 0 {
+			tx.Type = contracts[0].GetType().String()
+			tx.Owner, tx.To = contractParties(contracts[0])
+			t, err := decodeTransfer(contracts[0])
+			if err != nil {
+				metrics.DecodeErrors.WithLabelValues(EventSource, tx.Type).Inc()
+			} else if t != nil {
+				t.Tx = tx
+				tx.Transfer = t
+			}
+			s, err := decodeStaking(contracts[0])
+			if err != nil {
+				metrics.DecodeErrors.WithLabelValues(EventSource, tx.Type).Inc()
+			} else if s != nil {
+				s.Tx = tx
+				tx.Staking = s
+			}
+		}
+		if rets := ext.GetTransaction().GetRet(); len(rets) > 0 && rets[0].GetContractRet() > core.Transaction_Result_SUCCESS {
+			tx.Success = false
+		}
+		if info, ok := infos[tx.ID]; ok {
+			tx.Info = info
+			if info.Result != core.TransactionInfo_SUCESS {
+				tx.Success = false
+			}
+			for i, lg := range info.Log {
+				l := &Log{Tx: tx, Index: i, Contract: logAddress(lg.Address), Topics: lg.Topics, Data: lg.Data}
+				ev, err := reg.Decode(l.Contract, lg.Topics, lg.Data)
+				switch {
+				case err == nil:
+					l.Event = ev
+				case errors.Is(err, abi.ErrUnknownEvent):
+				default:
+					metrics.DecodeErrors.WithLabelValues(EventSource, "log").Inc()
+					l.DecodeError = err
+				}
+				tx.Logs = append(tx.Logs, l)
+			}
+			for i, internal := range info.InternalTransactions {
+				tx.Internal = append(tx.Internal, newInternalTx(tx, i, internal))
+			}
+		}
+		if tx.Staking != nil {
+			tx.Staking.settle(tx.Info, unfreezeDelay)
+		}
+		if !tx.Success {
+			tx.Failure = newFailure(tx, reg)
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	return b
+}
+
+// contractParties reads the owner and recipient (or called contract) of a
+// system contract. Every contract type has an owner_address; the recipient
+// field depends on the type.
+func contractParties(c *core.Transaction_Contract) (owner, to string) {
+	msg, err := c.GetParameter().UnmarshalNew()
+	if err != nil {
+		return "", ""
+	}
+	fields := msg.ProtoReflect()
+	get := func(name protoreflect.Name) string {
+		fd := fields.Descriptor().Fields().ByName(name)
+		if fd == nil || fd.Kind() != protoreflect.BytesKind {
+			return ""
+		}
+		b := fields.Get(fd).Bytes()
+		if len(b) == 0 {
+			return ""
+		}
+		return logAddress(b)
+	}
+	owner = get("owner_address")
+	for _, name := range []protoreflect.Name{"to_address", "receiver_address", "contract_address"} {
+		if to = get(name); to != "" {
+			break
+		}
+	}
+	return owner, to
+}
@@ -0,0 +1,215 @@
+package network
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Names of the built-in network profiles
+const (
+	Mainnet = "mainnet"
+	Nile    = "nile"
+	Shasta  = "shasta"
+)
+
+// Token is a well-known TRC20 contract on a network
+type Token struct {
+	Symbol   string
+	Contract string
+	Decimals int32
+}
+
+// Profile bundles the endpoints, well-known contracts and chain parameters of one TRON network
+type Profile struct {
+	Name         string
+	GRPCEndpoint string
+	// SolidityEndpoint serves the WalletSolidity gRPC service (confirmed blocks only)
+	SolidityEndpoint string
+	TronGridURL      string
+	Tokens           []Token
+
+	// BlockTime is the target block interval
+	BlockTime time.Duration
+	// SolidifyDepth is the number of blocks after which a block is considered irreversible
+	SolidifyDepth int64
+	// Testnet marks profiles whose assets have no real value
+	Testnet bool
+}
+
+var profiles = map[string]*Profile{
+	Mainnet: {
+		Name:             Mainnet,
+		GRPCEndpoint:     "grpc.trongrid.io:50051",
+		SolidityEndpoint: "grpc.trongrid.io:50052",
+		TronGridURL:      "https://api.trongrid.io",
+		Tokens: []Token{
+			{Symbol: "USDT", Contract: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", Decimals: 6},
+			{Symbol: "USDC", Contract: "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8", Decimals: 6},
+			{Symbol: "WTRX", Contract: "TNUC9Qb1rRpS5CbWLmNMxXBjyFoydXjWFR", Decimals: 6},
+		},
+		BlockTime:     3 * time.Second,
+		SolidifyDepth: 19,
+	},
+	Nile: {
+		Name:             Nile,
+		GRPCEndpoint:     "grpc.nile.trongrid.io:50051",
+		SolidityEndpoint: "grpc.nile.trongrid.io:50061",
+		TronGridURL:      "https://nile.trongrid.io",
+		Tokens: []Token{
+			{Symbol: "USDT", Contract: "TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf", Decimals: 6},
+		},
+		BlockTime:     3 * time.Second,
+		SolidifyDepth: 19,
+		Testnet:       true,
+	},
+	Shasta: {
+		Name:             Shasta,
+		GRPCEndpoint:     "grpc.shasta.trongrid.io:50051",
+		SolidityEndpoint: "grpc.shasta.trongrid.io:50052",
+		TronGridURL:      "https://api.shasta.trongrid.io",
+		Tokens: []Token{
+			{Symbol: "USDT", Contract: "TG3XXyExBkPp9nzdajDZsozEu4BkaSJozs", Decimals: 6},
+		},
+		BlockTime:     3 * time.Second,
+		SolidifyDepth: 19,
+		Testnet:       true,
+	},
+}
+
+// Get returns the profile with the given name
+func Get(name string) (*Profile, error) {
+	p, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown network %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names returns the names of all built-in profiles, sorted
+func Names() []string {
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Token looks up a well-known token by symbol
+func (p *Profile) Token(symbol string) (Token, bool) {
+	for _, t := range p.Tokens {
+		if strings.EqualFold(t.Symbol, symbol) {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+// TokenByContract looks up a well-known token by contract address
+func (p *Profile) TokenByContract(contract string) (Token, bool) {
+	for _, t := range p.Tokens {
+		if t.Contract == contract {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+// Decimals returns the decimals of a well-known token; unknown contracts
+// are reported in raw base units (0 decimals)
+func (p *Profile) Decimals(contract string) int32 {
+	d, _ := p.TokenDecimals(contract)
+	return d
+}
+
+// TokenDecimals returns the decimals of a well-known token and whether the
+// contract is one; amounts of other contracts can only be kept in base units
+func (p *Profile) TokenDecimals(contract string) (int32, bool) {
+	if t, ok := p.TokenByContract(contract); ok {
+		return t.Decimals, true
+	}
+	return 0, false
+}
+
+// ownerOf returns the profile other than p that lists contract as a well-known token
+func (p *Profile) ownerOf(contract string) (*Profile, Token, bool) {
+	for _, other := range profiles {
+		if other.Name == p.Name {
+			continue
+		}
+		if t, ok := other.TokenByContract(contract); ok {
+			return other, t, true
+		}
+	}
+	return nil, Token{}, false
+}
+
+// ValidateContract rejects contracts that are well-known tokens of another network,
+// e.g. the Nile USDT contract configured while running against mainnet.
+func (p *Profile) ValidateContract(contract string) error {
+	if other, t, ok := p.ownerOf(contract); ok {
+		return fmt.Errorf("contract %s is %s on %s, not a %s contract", contract, t.Symbol, other.Name, p.Name)
+	}
+	return nil
+}
+
+// ValidateToken accepts only the tokens p lists (built in or added with
+// WithTokens). An arbitrary contract cannot be told apart from a testnet
+// token, so crediting it must be an explicit decision.
+func (p *Profile) ValidateToken(contract string) error {
+	if err := p.ValidateContract(contract); err != nil {
+		return err
+	}
+	if _, ok := p.TokenByContract(contract); !ok {
+		return fmt.Errorf("contract %s is not a known %s token", contract, p.Name)
+	}
+	return nil
+}
+
+// ValidateEndpoint rejects a gRPC endpoint or TronGrid URL of another network,
+// e.g. the mainnet node left in a config file while running with -network nile
+func (p *Profile) ValidateEndpoint(endpoint string) error {
+	e := normalizeEndpoint(endpoint)
+	for _, other := range profiles {
+		if other.Name == p.Name {
+			continue
+		}
+		for _, theirs := range []string{other.GRPCEndpoint, other.SolidityEndpoint, other.TronGridURL} {
+			if e == normalizeEndpoint(theirs) {
+				return fmt.Errorf("endpoint %s belongs to %s, not %s", endpoint, other.Name, p.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeEndpoint(e string) string {
+	return strings.TrimRight(strings.ToLower(strings.TrimSpace(e)), "/")
+}
+
+// ValidateAddress rejects watch addresses that are actually token contracts of another network
+func (p *Profile) ValidateAddress(addr string) error {
+	if other, t, ok := p.ownerOf(addr); ok {
+		return fmt.Errorf("address %s is the %s %s contract, not an account on %s", addr, other.Name, t.Symbol, p.Name)
+	}
+	return nil
+}
+
+// WithTokens returns a copy of p that also lists the given tokens, e.g. tokens
+// declared in the configuration; p itself is not modified
+func (p *Profile) WithTokens(tokens ...Token) *Profile {
+	c := *p
+	c.Tokens = append(slices.Clone(p.Tokens), tokens...)
+	return &c
+}
+
+// DefaultTokens returns the contracts watched when none are configured
+func (p *Profile) DefaultTokens() []string {
+	if t, ok := p.Token("USDT"); ok {
+		return []string{t.Contract}
+	}
+	return nil
+}
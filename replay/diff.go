@@ -0,0 +1,143 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/yourname/tron-demo/sink"
+)
+
+// Collector is a sink keeping published events in memory, used for dry runs
+type Collector struct {
+	mu     sync.Mutex
+	events []sink.Event
+}
+
+// Publish implements sink.EventSink
+func (c *Collector) Publish(_ context.Context, ev sink.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, ev)
+	return nil
+}
+
+// Close implements sink.EventSink
+func (c *Collector) Close() error { return nil }
+
+// Events returns the collected events
+func (c *Collector) Events() []sink.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.events)
+}
+
+// Change is an event the replay published differently than was recorded
+type Change struct {
+	Stored, Replayed sink.Event
+	// Fields names what differs: type, contract, from, to, value or data
+	Fields []string
+}
+
+// Report compares recorded events with the events of a replay, by key
+type Report struct {
+	// Missing were recorded but not published by the replay
+	Missing []sink.Event
+	// Extra were published by the replay but never recorded
+	Extra   []sink.Event
+	Changed []Change
+	// Unchanged counts events published identically
+	Unchanged int
+}
+
+// Clean reports whether the replay reproduced the recorded events exactly
+func (r *Report) Clean() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Changed) == 0
+}
+
+// Diff compares recorded events with replayed ones. Delivery details that do
+// not affect a credit (confirmation state, timestamps) are ignored.
+func Diff(stored, replayed []sink.Event) *Report {
+	r := &Report{}
+	byKey := make(map[string]sink.Event, len(stored))
+	for _, ev := range stored {
+		byKey[ev.Key] = ev
+	}
+	seen := make(map[string]bool, len(replayed))
+	for _, ev := range replayed {
+		if seen[ev.Key] {
+			continue
+		}
+		seen[ev.Key] = true
+		old, ok := byKey[ev.Key]
+		if !ok {
+			r.Extra = append(r.Extra, ev)
+			continue
+		}
+		if fields := compare(old, ev); len(fields) > 0 {
+			r.Changed = append(r.Changed, Change{Stored: old, Replayed: ev, Fields: fields})
+		} else {
+			r.Unchanged++
+		}
+	}
+	for _, ev := range stored {
+		if !seen[ev.Key] {
+			r.Missing = append(r.Missing, ev)
+		}
+	}
+	return r
+}
+
+// compare names the fields in which two events with the same key differ
+func compare(a, b sink.Event) []string {
+	var fields []string
+	if a.Type != b.Type {
+		fields = append(fields, "type")
+	}
+	if a.Contract != b.Contract {
+		fields = append(fields, "contract")
+	}
+	if a.From != b.From {
+		fields = append(fields, "from")
+	}
+	if a.To != b.To {
+		fields = append(fields, "to")
+	}
+	switch {
+	case (a.Value == nil) != (b.Value == nil):
+		fields = append(fields, "value")
+	case a.Value != nil && a.Value.Cmp(*b.Value) != 0:
+		fields = append(fields, "value")
+	}
+	if !maps.Equal(a.Data, b.Data) {
+		fields = append(fields, "data")
+	}
+	return fields
+}
+
+// Print writes the report, one line per difference
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "=== 重放对比 ===\n")
+	fmt.Fprintf(w, "一致: %d, 缺少: %d, 多出: %d, 变化: %d\n", r.Unchanged, len(r.Missing), len(r.Extra), len(r.Changed))
+	for _, ev := range r.Missing {
+		fmt.Fprintf(w, "- %s %s block=%d to=%s value=%s\n", ev.Type, ev.Key, ev.BlockNumber, ev.To, value(ev))
+	}
+	for _, ev := range r.Extra {
+		fmt.Fprintf(w, "+ %s %s block=%d to=%s value=%s\n", ev.Type, ev.Key, ev.BlockNumber, ev.To, value(ev))
+	}
+	for _, c := range r.Changed {
+		fmt.Fprintf(w, "~ %s %s block=%d %v: %s to=%s value=%s -> %s to=%s value=%s\n",
+			c.Replayed.Type, c.Replayed.Key, c.Replayed.BlockNumber, c.Fields,
+			c.Stored.Type, c.Stored.To, value(c.Stored), c.Replayed.Type, c.Replayed.To, value(c.Replayed))
+	}
+}
+
+func value(ev sink.Event) string {
+	if ev.Value == nil {
+		return "-"
+	}
+	return ev.Value.String()
+}
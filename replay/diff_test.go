@@ -0,0 +1,114 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/sink"
+)
+
+func deposit(key, to, value string) sink.Event {
+	v := amount.MustParse(value, 6)
+	return sink.Event{Type: "DEPOSIT", Key: key, To: to, Value: &v, Data: map[string]string{"symbol": "USDT"}}
+}
+
+func keys(events []sink.Event) []string {
+	var out []string
+	for _, ev := range events {
+		out = append(out, ev.Key)
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	stored := []sink.Event{
+		deposit("a#0", "TA", "1"),
+		deposit("b#0", "TB", "2"),
+		deposit("c#0", "TC", "3"),
+		deposit("d#0", "TD", "4"),
+	}
+	flagged := deposit("c#0", "TC", "3")
+	flagged.Type = "FLAGGED_TRANSFER"
+	// 确认状态、时间戳和精度不影响入账，不算变化
+	confirmed := deposit("a#0", "TA", "1")
+	confirmed.Confirmed = true
+	confirmed.BlockTimestamp = 123
+	rescaled := amount.MustParse("2", 18)
+	sameValue := deposit("b#0", "TB", "2")
+	sameValue.Value = &rescaled
+
+	replayed := []sink.Event{
+		confirmed,
+		sameValue,
+		flagged,
+		deposit("e#0", "TE", "5"),
+		deposit("e#0", "TE", "5"),
+	}
+	r := Diff(stored, replayed)
+	if r.Clean() {
+		t.Fatal("report is clean")
+	}
+	if r.Unchanged != 2 {
+		t.Errorf("Unchanged = %d, want 2", r.Unchanged)
+	}
+	if got := keys(r.Missing); !slices.Equal(got, []string{"d#0"}) {
+		t.Errorf("Missing = %v", got)
+	}
+	if got := keys(r.Extra); !slices.Equal(got, []string{"e#0"}) {
+		t.Errorf("Extra = %v", got)
+	}
+	if len(r.Changed) != 1 || r.Changed[0].Stored.Key != "c#0" || !slices.Equal(r.Changed[0].Fields, []string{"type"}) {
+		t.Errorf("Changed = %+v", r.Changed)
+	}
+
+	var out bytes.Buffer
+	r.Print(&out)
+	for _, line := range []string{"一致: 2, 缺少: 1, 多出: 1, 变化: 1", "- DEPOSIT d#0", "+ DEPOSIT e#0", "~ FLAGGED_TRANSFER c#0"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := deposit("a#0", "TA", "1")
+	noValue := base
+	noValue.Value = nil
+	otherData := base
+	otherData.Data = map[string]string{"symbol": "USDC"}
+	moved := deposit("a#0", "TB", "1.5")
+	moved.From = "TX"
+	moved.Contract = "TC"
+
+	tests := []struct {
+		name string
+		b    sink.Event
+		want []string
+	}{
+		{"same", base, nil},
+		{"value removed", noValue, []string{"value"}},
+		{"data", otherData, []string{"data"}},
+		{"everything", moved, []string{"contract", "from", "to", "value"}},
+	}
+	for _, tt := range tests {
+		if got := compare(base, tt.b); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: compare = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var c Collector
+	for _, k := range []string{"a", "b"} {
+		if err := c.Publish(context.Background(), sink.Event{Key: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := keys(c.Events()); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Events = %v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package risk
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+// Reason explains why a transfer was flagged
+type Reason string
+
+const (
+	// ZeroValue is a transfer of 0 tokens; it moves nothing and only exists to show up in history
+	ZeroValue Reason = "zero_value"
+	// Dust is a non-zero transfer below the dust threshold
+	Dust Reason = "dust"
+	// FakeTransferFrom is a zero-amount transferFrom sent by someone other than the token owner.
+	// Tokens that allow transferFrom(victim, x, 0) without allowance let anyone forge
+	// "outgoing" transfers from the victim to a lookalike address.
+	FakeTransferFrom Reason = "fake_transfer_from"
+	// Lookalike is a counterparty whose address shares prefix and suffix with a known
+	// counterparty or a watched address without being that address
+	Lookalike Reason = "lookalike_address"
+)
+
+// Transfer is the part of a token transfer the detector looks at
+type Transfer struct {
+	From string
+	To   string
+	// Caller is the account that signed the transaction, if known. For a plain
+	// transfer it equals From; for transferFrom it is the spender.
+	Caller string
+	Value  amount.Amount
+	// Unscaled marks a Value in base units because the token's decimals are
+	// unknown; such transfers cannot be compared with the dust threshold
+	Unscaled bool
+}
+
+// Options tune the detector
+type Options struct {
+	// DustThreshold flags non-zero transfers strictly below it, in whole tokens;
+	// zero disables the dust check
+	DustThreshold amount.Amount
+	// PrefixLen and SuffixLen are how many leading/trailing characters of a base58
+	// address must match a known address to count as a lookalike. Wallets usually
+	// show the first and last 4-6 characters, which is exactly what vanity
+	// generators target. Both 0 disables the lookalike check.
+	PrefixLen int
+	SuffixLen int
+}
+
+// DefaultOptions flags transfers below 1 token and lookalikes matching 4+4 characters
+func DefaultOptions() Options {
+	return Options{
+		DustThreshold: amount.FromInt64(1, 0),
+		PrefixLen:     4,
+		SuffixLen:     4,
+	}
+}
+
+// Detector flags zero-value, dust, fake transferFrom and lookalike-address transfers.
+// It remembers counterparties of the watched addresses so that later transfers
+// from addresses mimicking them can be recognised. Safe for concurrent use.
+type Detector struct {
+	opts Options
+
+	mu    sync.RWMutex
+	own   map[string]struct{}
+	known map[string]struct{}
+}
+
+// NewDetector returns a detector for the given watched addresses
+func NewDetector(opts Options, own []string) *Detector {
+	d := &Detector{
+		opts:  opts,
+		own:   make(map[string]struct{}, len(own)),
+		known: make(map[string]struct{}),
+	}
+	for _, a := range own {
+		d.own[a] = struct{}{}
+	}
+	return d
+}
+
+// Remember records addr as a legitimate counterparty
+func (d *Detector) Remember(addr string) {
+	if addr == "" {
+		return
+	}
+	d.mu.Lock()
+	d.known[addr] = struct{}{}
+	d.mu.Unlock()
+}
+
+// Known returns the remembered counterparties, sorted
+func (d *Detector) Known() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]string, 0, len(d.known))
+	for a := range d.known {
+		out = append(out, a)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Learn remembers the counterparty of a clean transfer involving a watched address
+// and reports whether a new one was added. reasons is what Assess returned for t:
+// flagged transfers are never learned, otherwise a poisoned "outgoing" transfer
+// would whitelist the attacker.
+func (d *Detector) Learn(t Transfer, reasons []Reason) bool {
+	if len(reasons) > 0 {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var peer string
+	if _, ok := d.own[t.From]; ok {
+		peer = t.To
+	} else if _, ok := d.own[t.To]; ok {
+		peer = t.From
+	}
+	if peer == "" {
+		return false
+	}
+	if _, ok := d.known[peer]; ok {
+		return false
+	}
+	d.known[peer] = struct{}{}
+	return true
+}
+
+// Assess returns the reasons a transfer looks like dust spam or address poisoning; nil if clean
+func (d *Detector) Assess(t Transfer) []Reason {
+	var reasons []Reason
+	switch {
+	case t.Value.IsZero():
+		reasons = append(reasons, ZeroValue)
+		if t.Caller != "" && t.Caller != t.From {
+			reasons = append(reasons, FakeTransferFrom)
+		}
+	case t.Value.Sign() > 0 && !t.Unscaled && d.opts.DustThreshold.Sign() > 0 && t.Value.Cmp(d.opts.DustThreshold) < 0:
+		reasons = append(reasons, Dust)
+	}
+
+	// 对方地址：转入看 from，转出看 to
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	peer := t.From
+	if _, ok := d.own[t.From]; ok {
+		peer = t.To
+	}
+	if d.lookalike(peer) {
+		reasons = append(reasons, Lookalike)
+	}
+	return reasons
+}
+
+// lookalike reports whether addr mimics, but is not, a watched or known address
+func (d *Detector) lookalike(addr string) bool {
+	if d.opts.PrefixLen == 0 && d.opts.SuffixLen == 0 || addr == "" {
+		return false
+	}
+	if _, ok := d.own[addr]; ok {
+		return false
+	}
+	if _, ok := d.known[addr]; ok {
+		return false
+	}
+	for a := range d.own {
+		if d.similar(addr, a) {
+			return true
+		}
+	}
+	for a := range d.known {
+		if d.similar(addr, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Detector) similar(a, b string) bool {
+	p, s := d.opts.PrefixLen, d.opts.SuffixLen
+	if len(a) < p+s || len(b) < p+s {
+		return false
+	}
+	return a[:p] == b[:p] && a[len(a)-s:] == b[len(b)-s:]
+}
+
+// Join formats reasons as a comma separated tag, e.g. "zero_value,lookalike_address"
+func Join(reasons []Reason) string {
+	parts := make([]string, len(reasons))
+	for i, r := range reasons {
+		parts[i] = string(r)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,106 @@
+package risk
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+const (
+	own     = "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF"
+	peer    = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	mimic   = "TR7NxxxxxxxxxxxxxxxxxxxxxxxxxxLj6t" // 首尾 4 位与 peer 相同
+	mimicMe = "TNPdyyyyyyyyyyyyyyyyyyyyyyyyyyeHAF"
+	other   = "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"
+)
+
+func usdt(s string) amount.Amount { return amount.MustParse(s, 6) }
+
+func TestAssess(t *testing.T) {
+	d := NewDetector(DefaultOptions(), []string{own})
+	d.Remember(peer)
+
+	tests := []struct {
+		name string
+		t    Transfer
+		want []Reason
+	}{
+		{"clean deposit", Transfer{From: peer, To: own, Value: usdt("10")}, nil},
+		{"clean from unknown", Transfer{From: other, To: own, Value: usdt("10")}, nil},
+		{"zero", Transfer{From: other, To: own, Value: usdt("0")}, []Reason{ZeroValue}},
+		{"zero by owner", Transfer{From: other, To: own, Caller: other, Value: usdt("0")}, []Reason{ZeroValue}},
+		{"fake transferFrom", Transfer{From: own, To: mimic, Caller: other, Value: usdt("0")}, []Reason{ZeroValue, FakeTransferFrom, Lookalike}},
+		{"dust", Transfer{From: other, To: own, Value: usdt("0.5")}, []Reason{Dust}},
+		{"threshold is not dust", Transfer{From: other, To: own, Value: usdt("1")}, nil},
+		{"unscaled skips dust", Transfer{From: other, To: own, Value: amount.FromInt64(5, 0), Unscaled: true}, nil},
+		{"unscaled zero", Transfer{From: other, To: own, Value: amount.FromInt64(0, 0), Unscaled: true}, []Reason{ZeroValue}},
+		{"lookalike of known", Transfer{From: mimic, To: own, Value: usdt("10")}, []Reason{Lookalike}},
+		{"lookalike of own", Transfer{From: mimicMe, To: own, Value: usdt("10")}, []Reason{Lookalike}},
+		{"lookalike dust", Transfer{From: mimic, To: own, Value: usdt("0.000001")}, []Reason{Dust, Lookalike}},
+		{"outgoing to lookalike", Transfer{From: own, To: mimic, Value: usdt("10")}, []Reason{Lookalike}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Assess(tt.t); !slices.Equal(got, tt.want) {
+				t.Errorf("Assess = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssessDisabled(t *testing.T) {
+	d := NewDetector(Options{}, []string{own})
+	d.Remember(peer)
+	if got := d.Assess(Transfer{From: mimic, To: own, Value: usdt("0.1")}); got != nil {
+		t.Errorf("Assess with checks disabled = %v", got)
+	}
+	if got := d.Assess(Transfer{From: mimic, To: own, Value: usdt("0")}); !slices.Equal(got, []Reason{ZeroValue}) {
+		t.Errorf("zero value with checks disabled = %v", got)
+	}
+}
+
+func TestLearn(t *testing.T) {
+	d := NewDetector(DefaultOptions(), []string{own})
+
+	in := Transfer{From: peer, To: own, Value: usdt("10")}
+	if !d.Learn(in, d.Assess(in)) {
+		t.Fatal("clean counterparty not learned")
+	}
+	if d.Learn(in, d.Assess(in)) {
+		t.Error("known counterparty learned twice")
+	}
+	if got := d.Known(); !slices.Equal(got, []string{peer}) {
+		t.Errorf("Known = %v", got)
+	}
+
+	// 学到的对手方之后，仿冒它的地址被标记，且不会被学习
+	fake := Transfer{From: mimic, To: own, Value: usdt("10")}
+	reasons := d.Assess(fake)
+	if !slices.Equal(reasons, []Reason{Lookalike}) {
+		t.Fatalf("Assess lookalike = %v", reasons)
+	}
+	if d.Learn(fake, reasons) {
+		t.Error("flagged counterparty learned")
+	}
+
+	if d.Learn(Transfer{From: other, To: peer, Value: usdt("10")}, nil) {
+		t.Error("transfer without a watched address learned")
+	}
+	out := Transfer{From: own, To: other, Value: usdt("10")}
+	if !d.Learn(out, d.Assess(out)) {
+		t.Error("recipient of an outgoing transfer not learned")
+	}
+	if got := d.Known(); !slices.Equal(got, []string{other, peer}) {
+		t.Errorf("Known = %v", got)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if got := Join([]Reason{ZeroValue, Lookalike}); got != "zero_value,lookalike_address" {
+		t.Errorf("Join = %q", got)
+	}
+	if got := Join(nil); got != "" {
+		t.Errorf("Join(nil) = %q", got)
+	}
+}
@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Publisher is the subset of a message-queue client the broker sink needs.
+// *nats.Conn satisfies it directly; Kafka producers can be wrapped with a
+// small adapter that uses the subject as the topic.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// brokerSink publishes JSON-encoded events to "<prefix>.<source>.<type>"
+type brokerSink struct {
+	pub    Publisher
+	prefix string
+}
+
+// NewBroker returns a sink that publishes events through p.
+// The subject is built from prefix, the event source and the lower-cased event type,
+// e.g. "tron.gridwatcher.deposit".
+func NewBroker(p Publisher, prefix string) EventSink {
+	return &brokerSink{pub: p, prefix: prefix}
+}
+
+// Subject returns the subject an event is published on
+func Subject(prefix string, ev Event) string {
+	parts := make([]string, 0, 3)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, ev.Source, strings.ToLower(ev.Type))
+	return strings.Join(parts, ".")
+}
+
+func (s *brokerSink) Publish(_ context.Context, ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.pub.Publish(Subject(s.prefix, ev), data)
+}
+
+func (s *brokerSink) Close() error {
+	return nil
+}
+
+// Message is a payload delivered by MemoryBroker
+type Message struct {
+	Subject string
+	Data    []byte
+}
+
+// MemoryBroker is an in-process Publisher with NATS-style subject matching.
+// It is meant for tests and single-binary deployments.
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs []*subscription
+}
+
+type subscription struct {
+	pattern string
+	ch      chan Message
+}
+
+// NewMemoryBroker returns an empty in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Subscribe returns a channel receiving messages whose subject matches pattern.
+// "*" matches exactly one token and a trailing ">" matches one or more tokens.
+// Messages are dropped for subscribers whose buffer is full.
+func (b *MemoryBroker) Subscribe(pattern string, buffer int) <-chan Message {
+	sub := &subscription{pattern: pattern, ch: make(chan Message, buffer)}
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+	return sub.ch
+}
+
+// Publish implements Publisher
+func (b *MemoryBroker) Publish(subject string, data []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if !matchSubject(sub.pattern, subject) {
+			continue
+		}
+		select {
+		case sub.ch <- Message{Subject: subject, Data: data}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close closes every subscription channel
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		close(sub.ch)
+	}
+	b.subs = nil
+}
+
+func matchSubject(pattern, subject string) bool {
+	p := strings.Split(pattern, ".")
+	s := strings.Split(subject, ".")
+	for i, tok := range p {
+		if tok == ">" {
+			return len(s) > i
+		}
+		if i >= len(s) {
+			return false
+		}
+		if tok != "*" && tok != s[i] {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
@@ -0,0 +1,133 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+func TestSubject(t *testing.T) {
+	ev := Event{Source: "gridwatcher", Type: "DEPOSIT"}
+	if got := Subject("tron", ev); got != "tron.gridwatcher.deposit" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := Subject("", ev); got != "gridwatcher.deposit" {
+		t.Errorf("Subject without prefix = %q", got)
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"tron.gridwatcher.deposit", "tron.gridwatcher.deposit", true},
+		{"tron.*.deposit", "tron.monitor.deposit", true},
+		{"tron.*", "tron.monitor.deposit", false},
+		{"tron.>", "tron.monitor.deposit", true},
+		{"tron.>", "tron", false},
+		{"tron.monitor.>", "tron.gridwatcher.deposit", false},
+		{"tron.monitor.deposit", "tron.monitor", false},
+	}
+	for _, tt := range tests {
+		if got := matchSubject(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("matchSubject(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
+
+// 用内存 broker 端到端验证：订阅、按主题过滤、消息体为 JSON 事件
+func TestBrokerSink(t *testing.T) {
+	b := NewMemoryBroker()
+	deposits := b.Subscribe("tron.*.deposit", 10)
+	all := b.Subscribe("tron.>", 10)
+	s := NewBroker(b, "tron")
+
+	value := amount.MustParse("12.5", 6)
+	events := []Event{
+		{Source: "gridwatcher", Type: "DEPOSIT", Key: "tx1#0", Value: &value, Confirmed: true},
+		{Source: "monitor", Type: "LOG", Key: "tx2#1"},
+	}
+	for _, ev := range events {
+		if err := s.Publish(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	var got []Message
+	for m := range deposits {
+		got = append(got, m)
+	}
+	if len(got) != 1 || got[0].Subject != "tron.gridwatcher.deposit" {
+		t.Fatalf("deposit subscriber got %+v", got)
+	}
+	var ev Event
+	if err := json.Unmarshal(got[0].Data, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Key != "tx1#0" || ev.Value == nil || ev.Value.Cmp(value) != 0 || !ev.Confirmed {
+		t.Errorf("decoded event = %+v", ev)
+	}
+
+	n := 0
+	for range all {
+		n++
+	}
+	if n != len(events) {
+		t.Errorf("wildcard subscriber got %d messages, want %d", n, len(events))
+	}
+}
+
+func TestBrokerDropsWhenFull(t *testing.T) {
+	b := NewMemoryBroker()
+	ch := b.Subscribe(">", 1)
+	for range 3 {
+		if err := b.Publish("a.b", []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Close()
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("got %d messages, want 1", n)
+	}
+}
+
+type failingPublisher struct{ err error }
+
+func (p failingPublisher) Publish(string, []byte) error { return p.err }
+
+func TestBrokerSinkError(t *testing.T) {
+	want := errors.New("broker down")
+	s := NewBroker(failingPublisher{want}, "tron")
+	if err := s.Publish(context.Background(), Event{Source: "monitor", Type: "LOG"}); !errors.Is(err, want) {
+		t.Errorf("Publish = %v, want %v", err, want)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	want := errors.New("down")
+	ch := NewChannel(1)
+	m := Multi(NewBroker(failingPublisher{want}, ""), ch)
+	err := m.Publish(context.Background(), Event{Key: "k"})
+	if !errors.Is(err, want) {
+		t.Errorf("Publish = %v, want %v", err, want)
+	}
+	// 一个下游失败不影响其他下游
+	if ev := <-ch.C(); ev.Key != "k" {
+		t.Errorf("channel got %+v", ev)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
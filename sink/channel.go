@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to a closed sink
+var ErrClosed = errors.New("sink closed")
+
+// ChannelSink delivers events to an in-process Go channel
+type ChannelSink struct {
+	ch   chan Event
+	done chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	senders sync.WaitGroup
+}
+
+// NewChannel returns a channel sink with the given buffer size.
+// Publish blocks when the buffer is full until the consumer catches up, ctx is
+// done or the sink is closed.
+func NewChannel(buffer int) *ChannelSink {
+	return &ChannelSink{ch: make(chan Event, buffer), done: make(chan struct{})}
+}
+
+// C returns the channel events are delivered on. It is closed by Close.
+func (s *ChannelSink) C() <-chan Event {
+	return s.ch
+}
+
+func (s *ChannelSink) Publish(ctx context.Context, ev Event) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.senders.Add(1)
+	s.mu.Unlock()
+	defer s.senders.Done()
+
+	// 不持锁等待发送：消费者停止后 Close 通过 done 唤醒阻塞的 Publish
+	select {
+	case s.ch <- ev:
+		return nil
+	case <-s.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close wakes up blocked publishers and closes the channel once none is sending
+func (s *ChannelSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	s.senders.Wait()
+	close(s.ch)
+	return nil
+}
@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannelDeliver(t *testing.T) {
+	s := NewChannel(2)
+	for _, k := range []string{"a", "b"} {
+		if err := s.Publish(context.Background(), Event{Key: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for ev := range s.C() {
+		keys = append(keys, ev.Key)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("got %v", keys)
+	}
+	if err := s.Publish(context.Background(), Event{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+// 消费者停止后缓冲区满，Close 不能被阻塞中的 Publish 卡住
+func TestChannelCloseUnblocksPublish(t *testing.T) {
+	s := NewChannel(1)
+	if err := s.Publish(context.Background(), Event{Key: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	published := make(chan error, 1)
+	go func() { published <- s.Publish(context.Background(), Event{Key: "b"}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close deadlocked with a blocked Publish")
+	}
+	if err := <-published; !errors.Is(err, ErrClosed) {
+		t.Errorf("blocked Publish = %v, want ErrClosed", err)
+	}
+}
+
+func TestChannelPublishContext(t *testing.T) {
+	s := NewChannel(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Publish(ctx, Event{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish = %v, want deadline exceeded", err)
+	}
+	s.Close()
+}
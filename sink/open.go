@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Open builds a sink from a comma separated spec such as "stdout,file:events.jsonl".
+// Supported entries are "stdout", "file:<path>" and "discard".
+// Channel and broker sinks are in-process and must be constructed in code.
+func Open(spec string) (EventSink, error) {
+	var sinks []EventSink
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kind, arg, _ := strings.Cut(item, ":")
+		switch kind {
+		case "stdout":
+			sinks = append(sinks, NewStdout())
+		case "discard":
+			sinks = append(sinks, Discard())
+		case "file":
+			if arg == "" {
+				return nil, fmt.Errorf("sink %q: missing file path", item)
+			}
+			f, err := NewFile(arg)
+			if err != nil {
+				Multi(sinks...).Close()
+				return nil, err
+			}
+			sinks = append(sinks, f)
+		default:
+			Multi(sinks...).Close()
+			return nil, fmt.Errorf("unknown sink %q", item)
+		}
+	}
+	if len(sinks) == 0 {
+		return Discard(), nil
+	}
+	return Multi(sinks...), nil
+}
@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+// Event is the common envelope published by gridwatcher and monitor.
+// Key is the idempotency key (txid + separator + event index) and is stable
+// across restarts, so consumers can de-duplicate on it.
+type Event struct {
+	Source         string         `json:"source"`
+	Type           string         `json:"type"`
+	Key            string         `json:"key"`
+	TxID           string         `json:"tx_id"`
+	BlockNumber    int64          `json:"block_number"`
+	BlockTimestamp int64          `json:"block_timestamp"`
+	Contract       string         `json:"contract,omitempty"`
+	From           string         `json:"from,omitempty"`
+	To             string         `json:"to,omitempty"`
+	Value          *amount.Amount `json:"value,omitempty"`
+	// Unscaled marks a Value in base units because the token's decimals are unknown
+	Unscaled  bool              `json:"unscaled,omitempty"`
+	Confirmed bool              `json:"confirmed"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// MarshalJSON adds raw_value (the integer amount in base units) and decimals
+// next to value, so consumers need not infer the scale from the decimal string
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	v := struct {
+		event
+		RawValue string `json:"raw_value,omitempty"`
+		Decimals *int32 `json:"decimals,omitempty"`
+	}{event: event(e)}
+	if e.Value != nil {
+		d := e.Value.Decimals()
+		v.RawValue, v.Decimals = e.Value.Raw().String(), &d
+	}
+	return json.Marshal(v)
+}
+
+// EventSink receives events from a watcher.
+// Publish must be safe for concurrent use.
+type EventSink interface {
+	Publish(ctx context.Context, ev Event) error
+	Close() error
+}
+
+// multiSink fans one event out to several sinks
+type multiSink struct {
+	sinks []EventSink
+}
+
+// Multi returns a sink that publishes every event to all of the given sinks.
+// A failure in one sink does not stop delivery to the others; all errors are joined.
+func Multi(sinks ...EventSink) EventSink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return &multiSink{sinks: sinks}
+}
+
+func (m *multiSink) Publish(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *multiSink) Close() error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Discard returns a sink that drops every event
+func Discard() EventSink {
+	return discardSink{}
+}
+
+type discardSink struct{}
+
+func (discardSink) Publish(context.Context, Event) error { return nil }
+func (discardSink) Close() error                         { return nil }
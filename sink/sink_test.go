@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+func TestEventJSON(t *testing.T) {
+	value := amount.MustParse("1", 6)
+	raw := amount.FromInt64(123, 0)
+	tests := []struct {
+		name string
+		ev   Event
+		want map[string]any
+	}{
+		{
+			name: "scaled",
+			ev:   Event{Key: "a#0", Value: &value},
+			want: map[string]any{"value": "1.000000", "raw_value": "1000000", "decimals": 6.0},
+		},
+		{
+			name: "unscaled",
+			ev:   Event{Key: "b#0", Value: &raw, Unscaled: true},
+			want: map[string]any{"value": "123", "raw_value": "123", "decimals": 0.0, "unscaled": true},
+		},
+		{
+			name: "no value",
+			ev:   Event{Key: "c#0"},
+			want: map[string]any{"value": nil, "raw_value": nil, "decimals": nil, "unscaled": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got["key"] != tt.ev.Key {
+				t.Errorf("key = %v in %s", got["key"], data)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v in %s", k, got[k], v, data)
+				}
+			}
+
+			var back Event
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			if (back.Value == nil) != (tt.ev.Value == nil) ||
+				back.Value != nil && (back.Value.Cmp(*tt.ev.Value) != 0 || back.Value.Decimals() != tt.ev.Value.Decimals()) ||
+				back.Unscaled != tt.ev.Unscaled {
+				t.Errorf("round trip of %s = %+v", data, back)
+			}
+		})
+	}
+}
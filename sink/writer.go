@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// writerSink writes events as JSON lines to an io.Writer
+type writerSink struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewWriter returns a sink that writes one JSON object per line to w.
+// If w is an io.Closer it is closed by Close.
+func NewWriter(w io.Writer) EventSink {
+	s := &writerSink{enc: json.NewEncoder(w)}
+	if c, ok := w.(io.Closer); ok {
+		s.closer = c
+	}
+	return s
+}
+
+// NewStdout returns a JSON-lines sink on standard output
+func NewStdout() EventSink {
+	return &writerSink{enc: json.NewEncoder(os.Stdout)}
+}
+
+// NewFile returns a JSON-lines sink appending to the file at path
+func NewFile(path string) (EventSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open event file %s: %w", path, err)
+	}
+	return &writerSink{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+func (s *writerSink) Publish(_ context.Context, ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(ev)
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	if f, ok := s.closer.(*os.File); ok {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	return s.closer.Close()
+}
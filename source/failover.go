@@ -0,0 +1,166 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourname/tron-demo/metrics"
+)
+
+// Failover serves requests from one source at a time and switches to the next
+// after threshold consecutive failures, or right away on a fatal error (IsFatal). It goes back to the first (preferred)
+// source once retryPrimary has passed. Since the cursor is a block number held
+// by the caller, a switch never skips or repeats blocks.
+type Failover struct {
+	sources      []TransferSource
+	threshold    int
+	retryPrimary time.Duration
+
+	mu         sync.Mutex
+	active     int
+	failures   int
+	switchedAt time.Time
+	switches   int64
+}
+
+// NewFailover returns a failover over sources in order of preference
+func NewFailover(threshold int, retryPrimary time.Duration, sources ...TransferSource) *Failover {
+	if threshold < 1 {
+		threshold = 1
+	}
+	f := &Failover{sources: sources, threshold: threshold, retryPrimary: retryPrimary}
+	f.setActiveMetric()
+	return f
+}
+
+// Name implements TransferSource
+func (f *Failover) Name() string {
+	return "failover"
+}
+
+// Active returns the name of the source currently serving requests
+func (f *Failover) Active() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sources[f.active].Name()
+}
+
+// Switches returns how many times the active source changed
+func (f *Failover) Switches() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.switches
+}
+
+// Head implements TransferSource
+func (f *Failover) Head(ctx context.Context) (int64, error) {
+	var head int64
+	err := f.do(ctx, func(s TransferSource) error {
+		var err error
+		head, err = s.Head(ctx)
+		return err
+	})
+	return head, err
+}
+
+// Transfers implements TransferSource
+func (f *Failover) Transfers(ctx context.Context, contracts []string, from, to int64) ([]Transfer, error) {
+	var out []Transfer
+	err := f.do(ctx, func(s TransferSource) error {
+		var err error
+		out, err = s.Transfers(ctx, contracts, from, to)
+		return err
+	})
+	return out, err
+}
+
+// do runs fn on the active source. A failure that trips the threshold switches
+// sources and tries the next one right away; otherwise the error is returned
+// so the caller can back off.
+func (f *Failover) do(ctx context.Context, fn func(TransferSource) error) error {
+	f.restorePrimary()
+	var errs []error
+	for range f.sources {
+		f.mu.Lock()
+		s := f.sources[f.active]
+		f.mu.Unlock()
+
+		err := fn(s)
+		if err == nil || errors.Is(err, ErrBeyondHead) {
+			f.succeeded(s)
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		if !f.failed(s, IsFatal(err)) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Failover) succeeded(s TransferSource) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sources[f.active] == s {
+		f.failures = 0
+	}
+}
+
+// failed records a failure of s and reports whether the active source was switched.
+// A fatal failure switches without waiting for the threshold.
+func (f *Failover) failed(s TransferSource, fatal bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sources[f.active] != s || len(f.sources) < 2 {
+		return false
+	}
+	f.failures++
+	if f.failures < f.threshold && !fatal {
+		return false
+	}
+	f.switchTo((f.active + 1) % len(f.sources))
+	if fatal {
+		log.Printf("[failover] %s failed with a fatal error, switched to %s", s.Name(), f.sources[f.active].Name())
+	} else {
+		log.Printf("[failover] %s failed %d times in a row, switched to %s", s.Name(), f.threshold, f.sources[f.active].Name())
+	}
+	return true
+}
+
+// restorePrimary goes back to the preferred source after retryPrimary
+func (f *Failover) restorePrimary() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active == 0 || f.retryPrimary <= 0 || time.Since(f.switchedAt) < f.retryPrimary {
+		return
+	}
+	log.Printf("[failover] Retrying preferred source %s", f.sources[0].Name())
+	f.switchTo(0)
+}
+
+// switchTo must be called with f.mu held
+func (f *Failover) switchTo(i int) {
+	f.active = i
+	f.failures = 0
+	f.switchedAt = time.Now()
+	f.switches++
+	metrics.SourceSwitches.Inc()
+	f.setActiveMetric()
+}
+
+func (f *Failover) setActiveMetric() {
+	for i, s := range f.sources {
+		v := 0.0
+		if i == f.active {
+			v = 1
+		}
+		metrics.SourceActive.WithLabelValues(s.Name()).Set(v)
+	}
+}
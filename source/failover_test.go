@@ -0,0 +1,138 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSource returns err from every call, or head when err is nil
+type fakeSource struct {
+	name  string
+	head  int64
+	err   error
+	calls int
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) Head(context.Context) (int64, error) {
+	s.calls++
+	return s.head, s.err
+}
+
+func (s *fakeSource) Transfers(context.Context, []string, int64, int64) ([]Transfer, error) {
+	s.calls++
+	return nil, s.err
+}
+
+type fatalError struct{}
+
+func (fatalError) Error() string { return "unauthorized" }
+func (fatalError) Fatal() bool   { return true }
+
+func TestFailoverSwitchesAfterThreshold(t *testing.T) {
+	primary := &fakeSource{name: "primary", err: errors.New("timeout")}
+	backup := &fakeSource{name: "backup", head: 42}
+	f := NewFailover(2, 0, primary, backup)
+
+	// 第一次失败未达到阈值，错误交给调用方退避
+	if _, err := f.Head(context.Background()); err == nil {
+		t.Fatal("first failure not returned")
+	}
+	if f.Active() != "primary" {
+		t.Fatalf("switched after one failure to %s", f.Active())
+	}
+	// 第二次失败切换并立即用备用源重试
+	head, err := f.Head(context.Background())
+	if err != nil || head != 42 {
+		t.Fatalf("Head = %d, %v", head, err)
+	}
+	if f.Active() != "backup" || f.Switches() != 1 {
+		t.Errorf("active %s after %d switches", f.Active(), f.Switches())
+	}
+	if primary.calls != 2 || backup.calls != 1 {
+		t.Errorf("calls: primary %d, backup %d", primary.calls, backup.calls)
+	}
+}
+
+func TestFailoverSuccessResetsFailures(t *testing.T) {
+	primary := &fakeSource{name: "primary", err: errors.New("timeout")}
+	f := NewFailover(2, 0, primary, &fakeSource{name: "backup"})
+	f.Head(context.Background())
+	primary.err = nil
+	if _, err := f.Head(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	primary.err = errors.New("timeout")
+	f.Head(context.Background())
+	if f.Active() != "primary" {
+		t.Errorf("switched to %s although the failures were not consecutive", f.Active())
+	}
+}
+
+func TestFailoverBeyondHeadIsNotAFailure(t *testing.T) {
+	primary := &fakeSource{name: "primary", err: fmt.Errorf("block 9: %w", ErrBeyondHead)}
+	f := NewFailover(1, 0, primary, &fakeSource{name: "backup"})
+	if _, err := f.Transfers(context.Background(), nil, 1, 9); !errors.Is(err, ErrBeyondHead) {
+		t.Fatalf("Transfers = %v", err)
+	}
+	if f.Active() != "primary" {
+		t.Errorf("switched to %s on ErrBeyondHead", f.Active())
+	}
+}
+
+func TestFailoverFatalSwitchesAtOnce(t *testing.T) {
+	primary := &fakeSource{name: "primary", err: fmt.Errorf("http 401: %w", fatalError{})}
+	backup := &fakeSource{name: "backup", head: 7}
+	f := NewFailover(5, 0, primary, backup)
+	if head, err := f.Head(context.Background()); err != nil || head != 7 {
+		t.Fatalf("Head = %d, %v", head, err)
+	}
+	if f.Active() != "backup" {
+		t.Errorf("active = %s", f.Active())
+	}
+}
+
+func TestFailoverRestoresPrimary(t *testing.T) {
+	primary := &fakeSource{name: "primary", err: errors.New("timeout")}
+	f := NewFailover(1, time.Millisecond, primary, &fakeSource{name: "backup"})
+	f.Head(context.Background())
+	if f.Active() != "backup" {
+		t.Fatalf("active = %s", f.Active())
+	}
+	primary.err = nil
+	time.Sleep(2 * time.Millisecond)
+	f.Head(context.Background())
+	if f.Active() != "primary" || f.Switches() != 2 {
+		t.Errorf("active %s after %d switches", f.Active(), f.Switches())
+	}
+}
+
+func TestIsFatal(t *testing.T) {
+	retryable := errors.New("timeout")
+	fatal := fmt.Errorf("trongrid: %w", fatalError{})
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", retryable, false},
+		{"fatal method", fatal, true},
+		{"grpc unavailable", status.Error(codes.Unavailable, "down"), false},
+		{"grpc unauthenticated", fmt.Errorf("node: %w", status.Error(codes.Unauthenticated, "key")), true},
+		{"all sources fatal", errors.Join(fatal, status.Error(codes.PermissionDenied, "denied")), true},
+		{"one source retryable", errors.Join(fatal, retryable), false},
+	}
+	for _, tt := range tests {
+		if got := IsFatal(tt.err); got != tt.want {
+			t.Errorf("%s: IsFatal = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package source
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fbsobreira/gotron-sdk/pkg/address"
+	"github.com/fbsobreira/gotron-sdk/pkg/client"
+	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
+	"github.com/yourname/tron-demo/metrics"
+)
+
+// Node reads transfers from a full node over gRPC by scanning the receipts
+// (GetTransactionInfoByBlockNum) of every block in the range.
+type Node struct {
+	client *client.GrpcClient
+	// confirmations is how far behind the node's head a block must be to count as confirmed
+	confirmations int64
+}
+
+// NewNode returns a node source; confirmations is usually the network's solidify depth
+func NewNode(c *client.GrpcClient, confirmations int64) *Node {
+	return &Node{client: c, confirmations: confirmations}
+}
+
+// Name implements TransferSource
+func (n *Node) Name() string {
+	return "node"
+}
+
+// Head implements TransferSource
+func (n *Node) Head(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	block, err := n.client.GetNowBlock()
+	metrics.ObserveGRPC("GetNowBlock", start, err)
+	if err != nil {
+		return 0, err
+	}
+	if block.GetBlockHeader().GetRawData() == nil {
+		return 0, fmt.Errorf("node returned a block without header")
+	}
+	return block.GetBlockHeader().GetRawData().GetNumber() - n.confirmations, nil
+}
+
+// Transfers implements TransferSource
+func (n *Node) Transfers(ctx context.Context, contracts []string, from, to int64) ([]Transfer, error) {
+	// 未产生的区块查询结果为空，必须先确认范围内的区块都已确认，否则会漏掉转账
+	head, err := n.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if to > head {
+		return nil, fmt.Errorf("block %d > head %d: %w", to, head, ErrBeyondHead)
+	}
+
+	want := make(map[string]struct{}, len(contracts))
+	for _, c := range contracts {
+		want[c] = struct{}{}
+	}
+
+	var out []Transfer
+	for num := from; num <= to; num++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		infos, err := n.client.GetBlockInfoByNum(num)
+		metrics.ObserveGRPC("GetTransactionInfoByBlockNum", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", num, err)
+		}
+		for _, info := range infos.GetTransactionInfo() {
+			// 失败交易的日志已被回滚，不会出现在回执里，这里再保险
+			if info.GetResult() != core.TransactionInfo_SUCESS {
+				continue
+			}
+			txID := hex.EncodeToString(info.GetId())
+			for i, lg := range info.GetLog() {
+				tr, ok := decodeTransferLog(lg)
+				if !ok {
+					continue
+				}
+				if _, ok := want[tr.Contract]; !ok {
+					continue
+				}
+				tr.TxID = txID
+				tr.LogIndex = int64(i)
+				tr.BlockNumber = num
+				tr.BlockTimestamp = info.GetBlockTimeStamp()
+				out = append(out, tr)
+			}
+		}
+	}
+	return out, nil
+}
+
+// decodeTransferLog decodes a TRC20 Transfer(address indexed, address indexed, uint256) log.
+// TRC721 transfers share topic0 but index the token id as well, so they are rejected by the topic count.
+func decodeTransferLog(lg *core.TransactionInfo_Log) (Transfer, bool) {
+	topics := lg.GetTopics()
+	if len(topics) != 3 || hex.EncodeToString(topics[0]) != TransferTopic {
+		return Transfer{}, false
+	}
+	if len(topics[1]) != 32 || len(topics[2]) != 32 || len(lg.GetData()) != 32 {
+		return Transfer{}, false
+	}
+	return Transfer{
+		Contract: logAddress(lg.GetAddress()),
+		From:     logAddress(topics[1][12:]),
+		To:       logAddress(topics[2][12:]),
+		Value:    new(big.Int).SetBytes(lg.GetData()),
+	}, true
+}
+
+// logAddress converts the 20-byte address found in logs to base58
+func logAddress(b []byte) string {
+	if len(b) == 20 {
+		b = append([]byte{address.TronBytePrefix}, b...)
+	}
+	return address.Address(b).String()
+}
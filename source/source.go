@@ -0,0 +1,81 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrBeyondHead is returned when a range reaches past the blocks a source can
+// serve as confirmed. It is not a failure: the caller waits and asks again.
+var ErrBeyondHead = errors.New("range is beyond the confirmed head")
+
+// IsFatal reports whether err will not go away by retrying the same source,
+// such as a rejected API key. Errors opt in with a Fatal() bool method; gRPC
+// Unauthenticated and PermissionDenied errors are fatal as well. A joined
+// error is fatal only when every error in it is.
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		for _, e := range errs {
+			if !IsFatal(e) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	var f interface{ Fatal() bool }
+	if errors.As(err, &f) {
+		return f.Fatal()
+	}
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	}
+	return false
+}
+
+// TransferTopic is keccak256("Transfer(address,address,uint256)"), topic0 of TRC20 Transfer logs
+const TransferTopic = "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// Transfer is a TRC20 Transfer log, identical no matter which source delivered it
+type Transfer struct {
+	TxID string
+	// LogIndex is the position of the log in the transaction's receipt; TronGrid
+	// reports the same position as event_index, so keys match across sources
+	LogIndex       int64
+	BlockNumber    int64
+	BlockTimestamp int64
+	Contract       string
+	From           string
+	To             string
+	Value          *big.Int
+}
+
+// Key is the idempotency key of the transfer: txid#log_index
+func (t Transfer) Key() string {
+	return fmt.Sprintf("%s#%d", t.TxID, t.LogIndex)
+}
+
+// TransferSource delivers confirmed TRC20 transfers by block range.
+//
+// The cursor is a block number owned by the caller. Every source answers the
+// same question for the same range, so the caller may switch sources between
+// calls without skipping or re-ordering blocks.
+type TransferSource interface {
+	// Name identifies the source in logs, metrics and status
+	Name() string
+	// Head returns the highest block whose transfers the source can serve as confirmed
+	Head(ctx context.Context) (int64, error)
+	// Transfers returns the Transfer logs of the given contracts in blocks [from, to],
+	// ordered by block number. It returns ErrBeyondHead instead
+	// of a partial result when to is past the source's confirmed head.
+	Transfers(ctx context.Context, contracts []string, from, to int64) ([]Transfer, error)
+}
@@ -0,0 +1,165 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Mismatch is a transfer both sources returned with different contents
+type Mismatch struct {
+	Key string
+	A   Transfer
+	B   Transfer
+}
+
+// Report is the result of comparing two sources over the same block range
+type Report struct {
+	From    int64
+	To      int64
+	SourceA string
+	SourceB string
+	// Matched counts transfers that are identical in both sources
+	Matched int
+	// OnlyA and OnlyB are transfers missing from the other source
+	OnlyA []Transfer
+	OnlyB []Transfer
+	// Mismatched are transfers with the same key but different contents
+	Mismatched []Mismatch
+	// UndecodedA and UndecodedB count events a source skipped because it could
+	// not decode them; such transfers usually also show up as OnlyB or OnlyA
+	UndecodedA int64
+	UndecodedB int64
+}
+
+// DecodeErrorCounter is implemented by sources that skip events they cannot
+// decode. DecodeErrors returns the running total of skipped events.
+type DecodeErrorCounter interface {
+	DecodeErrors() int64
+}
+
+// OK reports whether both sources agree and decoded everything
+func (r *Report) OK() bool {
+	return r.Discrepancies() == 0
+}
+
+// Discrepancies returns the number of differences and undecodable events found
+func (r *Report) Discrepancies() int {
+	return len(r.OnlyA) + len(r.OnlyB) + len(r.Mismatched) + int(r.UndecodedA+r.UndecodedB)
+}
+
+// Verify fetches [from, to] from both sources and compares them
+func Verify(ctx context.Context, a, b TransferSource, contracts []string, from, to int64) (*Report, error) {
+	ta, undecodedA, err := fetchCounted(ctx, a, contracts, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.Name(), err)
+	}
+	tb, undecodedB, err := fetchCounted(ctx, b, contracts, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	r := Diff(ta, tb)
+	r.From, r.To, r.SourceA, r.SourceB = from, to, a.Name(), b.Name()
+	r.UndecodedA, r.UndecodedB = undecodedA, undecodedB
+	return r, nil
+}
+
+// fetchCounted returns the transfers of s and how many events it skipped as undecodable
+func fetchCounted(ctx context.Context, s TransferSource, contracts []string, from, to int64) ([]Transfer, int64, error) {
+	c, counts := s.(DecodeErrorCounter)
+	var before int64
+	if counts {
+		before = c.DecodeErrors()
+	}
+	out, err := s.Transfers(ctx, contracts, from, to)
+	if err != nil || !counts {
+		return out, 0, err
+	}
+	return out, c.DecodeErrors() - before, nil
+}
+
+// Diff compares two transfer lists by idempotency key. OnlyA, OnlyB and
+// Mismatched are sorted by block, transaction and log index.
+func Diff(a, b []Transfer) *Report {
+	r := &Report{}
+	byKey := make(map[string]Transfer, len(b))
+	for _, t := range b {
+		byKey[t.Key()] = t
+	}
+	for _, ta := range a {
+		key := ta.Key()
+		tb, ok := byKey[key]
+		if !ok {
+			r.OnlyA = append(r.OnlyA, ta)
+			continue
+		}
+		delete(byKey, key)
+		if equal(ta, tb) {
+			r.Matched++
+		} else {
+			r.Mismatched = append(r.Mismatched, Mismatch{Key: key, A: ta, B: tb})
+		}
+	}
+	for _, tb := range byKey {
+		r.OnlyB = append(r.OnlyB, tb)
+	}
+	sort.Slice(r.OnlyA, func(i, j int) bool { return less(r.OnlyA[i], r.OnlyA[j]) })
+	sort.Slice(r.OnlyB, func(i, j int) bool { return less(r.OnlyB[i], r.OnlyB[j]) })
+	sort.Slice(r.Mismatched, func(i, j int) bool { return less(r.Mismatched[i].A, r.Mismatched[j].A) })
+	return r
+}
+
+// less orders transfers by block, transaction and log index
+func less(a, b Transfer) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber < b.BlockNumber
+	}
+	if a.TxID != b.TxID {
+		return a.TxID < b.TxID
+	}
+	return a.LogIndex < b.LogIndex
+}
+
+func equal(a, b Transfer) bool {
+	return a.BlockNumber == b.BlockNumber && a.Contract == b.Contract &&
+		a.From == b.From && a.To == b.To && a.Value.Cmp(b.Value) == 0
+}
+
+// WriteText writes a human readable diff report
+func (r *Report) WriteText(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("blocks %d-%d: %s vs %s\n", r.From, r.To, r.SourceA, r.SourceB)
+	ew.printf("matched: %d, only in %s: %d, only in %s: %d, mismatched: %d\n",
+		r.Matched, r.SourceA, len(r.OnlyA), r.SourceB, len(r.OnlyB), len(r.Mismatched))
+	if r.UndecodedA > 0 || r.UndecodedB > 0 {
+		ew.printf("undecodable events: %s: %d, %s: %d\n", r.SourceA, r.UndecodedA, r.SourceB, r.UndecodedB)
+	}
+	for _, t := range r.OnlyA {
+		ew.printf("- only in %s: %s\n", r.SourceA, describe(t))
+	}
+	for _, t := range r.OnlyB {
+		ew.printf("+ only in %s: %s\n", r.SourceB, describe(t))
+	}
+	for _, m := range r.Mismatched {
+		ew.printf("~ %s\n    %s: %s\n    %s: %s\n", m.Key, r.SourceA, describe(m.A), r.SourceB, describe(m.B))
+	}
+	return ew.err
+}
+
+func describe(t Transfer) string {
+	return fmt.Sprintf("%s block=%d contract=%s from=%s to=%s value=%s", t.Key(), t.BlockNumber, t.Contract, t.From, t.To, t.Value)
+}
+
+// errWriter keeps the first write error so the report can be written without checking every line
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
@@ -0,0 +1,117 @@
+package source
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func transfer(block int64, tx string, index int64, value int64) Transfer {
+	return Transfer{
+		TxID:        tx,
+		LogIndex:    index,
+		BlockNumber: block,
+		Contract:    "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+		From:        "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8",
+		To:          "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF",
+		Value:       big.NewInt(value),
+	}
+}
+
+func keys(ts []Transfer) string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Key()
+	}
+	return strings.Join(out, " ")
+}
+
+func TestDiff(t *testing.T) {
+	// 故意打乱顺序，log index 10 与 2 按数值排序而不是按字符串
+	a := []Transfer{
+		transfer(12, "cc", 0, 1),
+		transfer(10, "aa", 10, 1),
+		transfer(11, "bb", 0, 5),
+		transfer(10, "aa", 2, 1),
+		transfer(9, "zz", 0, 1),
+		transfer(11, "ab", 0, 6),
+	}
+	b := []Transfer{
+		transfer(9, "zz", 0, 1),
+		transfer(13, "dd", 1, 1),
+		transfer(11, "bb", 0, 7),
+		transfer(13, "dd", 0, 1),
+		transfer(11, "ab", 0, 8),
+		transfer(8, "yy", 0, 1),
+	}
+	r := Diff(a, b)
+	if r.Matched != 1 {
+		t.Errorf("Matched = %d, want 1", r.Matched)
+	}
+	if got, want := keys(r.OnlyA), "aa#2 aa#10 cc#0"; got != want {
+		t.Errorf("OnlyA = %s, want %s", got, want)
+	}
+	if got, want := keys(r.OnlyB), "yy#0 dd#0 dd#1"; got != want {
+		t.Errorf("OnlyB = %s, want %s", got, want)
+	}
+	if len(r.Mismatched) != 2 || r.Mismatched[0].Key != "ab#0" || r.Mismatched[1].Key != "bb#0" {
+		t.Errorf("Mismatched = %+v", r.Mismatched)
+	} else if r.Mismatched[1].A.Value.Int64() != 5 || r.Mismatched[1].B.Value.Int64() != 7 {
+		t.Errorf("Mismatched[1] = %+v", r.Mismatched[1])
+	}
+	if r.OK() || r.Discrepancies() != 8 {
+		t.Errorf("OK = %v, Discrepancies = %d", r.OK(), r.Discrepancies())
+	}
+
+	if r := Diff(a, a); !r.OK() || r.Matched != len(a) {
+		t.Errorf("identical lists: OK = %v, Matched = %d", r.OK(), r.Matched)
+	}
+}
+
+// listSource returns fixed transfers and counts undecoded events on every call
+type listSource struct {
+	name      string
+	transfers []Transfer
+	undecoded int64
+	total     int64
+}
+
+func (s *listSource) Name() string                        { return s.name }
+func (s *listSource) Head(context.Context) (int64, error) { return 0, nil }
+func (s *listSource) DecodeErrors() int64                 { return s.total }
+
+func (s *listSource) Transfers(context.Context, []string, int64, int64) ([]Transfer, error) {
+	s.total += s.undecoded
+	return s.transfers, nil
+}
+
+func TestVerifyUndecodable(t *testing.T) {
+	ts := []Transfer{transfer(10, "aa", 0, 1)}
+	// 之前运行累计的解析失败不算入本次报告
+	grid := &listSource{name: "trongrid", transfers: ts, undecoded: 2, total: 5}
+	node := &fakeSource{name: "node"}
+	r, err := Verify(context.Background(), grid, &listSource{name: "node", transfers: ts}, nil, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.UndecodedA != 2 || r.UndecodedB != 0 || r.Matched != 1 {
+		t.Errorf("UndecodedA = %d, UndecodedB = %d, Matched = %d", r.UndecodedA, r.UndecodedB, r.Matched)
+	}
+	if r.OK() || r.Discrepancies() != 2 {
+		t.Errorf("OK = %v, Discrepancies = %d; undecodable events must fail the check", r.OK(), r.Discrepancies())
+	}
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "undecodable events: trongrid: 2, node: 0") {
+		t.Errorf("report:\n%s", b.String())
+	}
+
+	// 不实现 DecodeErrorCounter 的数据源计为 0
+	r, err = Verify(context.Background(), node, node, nil, 10, 10)
+	if err != nil || !r.OK() {
+		t.Errorf("Verify = %+v, %v", r, err)
+	}
+}
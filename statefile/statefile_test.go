@@ -0,0 +1,48 @@
+package statefile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, v := range []map[string]int{{"next_block": 1}, {"next_block": 2}} {
+		if err := WriteJSON(path, v); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]int
+		if err := json.Unmarshal(data, &got); err != nil || got["next_block"] != v["next_block"] {
+			t.Errorf("file = %s, %v; want %v", data, err, v)
+		}
+	}
+	// 临时文件在改名或失败后都会被清理
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory holds %v, %v; want only state.json", entries, err)
+	}
+}
+
+func TestWriteJSONErrors(t *testing.T) {
+	if err := WriteJSON("", map[string]int{}); err != nil {
+		t.Errorf("empty path: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := WriteJSON(path, func() {}); err == nil {
+		t.Error("unmarshalable value accepted")
+	}
+	if err := WriteJSON(filepath.Join(dir, "missing", "state.json"), 1); err == nil {
+		t.Error("missing directory accepted")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("failed write left %s: %v", path, err)
+	}
+}
@@ -0,0 +1,161 @@
+package trongrid
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourname/tron-demo/amount"
+)
+
+// 质押相关操作类型（对应系统合约）
+const (
+	StakingFreeze           = "FreezeBalanceV2Contract"
+	StakingUnfreeze         = "UnfreezeBalanceV2Contract"
+	StakingDelegate         = "DelegateResourceContract"
+	StakingUndelegate       = "UnDelegateResourceContract"
+	StakingVote             = "VoteWitnessContract"
+	StakingWithdrawBalance  = "WithdrawBalanceContract"
+	StakingWithdrawUnfrozen = "WithdrawExpireUnfreezeContract"
+)
+
+// DefaultUnfreezeDelay 主网 getUnfreezeDelayDays：解质押后 14 天才能提取
+const DefaultUnfreezeDelay = 14 * 24 * time.Hour
+
+// StakingOperation 表示一笔 Stake 2.0 质押/解质押、资源代理、投票或提取交易
+type StakingOperation struct {
+	TxID string
+	// Type 合约类型，取值见 Staking* 常量
+	Type string
+	// Owner 签名者；Receiver 为资源代理/回收的对象账户
+	Owner    string
+	Receiver string
+	// Resource BANDWIDTH / ENERGY（TronGrid 省略 BANDWIDTH），投票和提取为空
+	Resource string
+	// Amount 质押、解质押、代理、回收或提取的 TRX
+	Amount amount.Amount
+	// Lock、LockPeriod（区块数）为锁定代理
+	Lock       bool
+	LockPeriod int64
+	// WithdrawableAt 解质押的 TRX 可以提取的时间，仅 UnfreezeBalanceV2
+	WithdrawableAt time.Time
+	Votes          []Vote
+	BlockNumber    int
+	Timestamp      time.Time
+	Status         string
+}
+
+// GetStakingOperations 获取账户的质押、资源代理、投票和提取记录。
+// unfreezeDelay 为 0 时按 DefaultUnfreezeDelay 计算可提取时间。
+func GetStakingOperations(config *Config, unfreezeDelay time.Duration) ([]StakingOperation, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if unfreezeDelay <= 0 {
+		unfreezeDelay = DefaultUnfreezeDelay
+	}
+
+	var ops []StakingOperation
+	err := fetchTransactions(config, func(tx TransactionData) {
+		if op := parseStaking(tx, unfreezeDelay); op != nil {
+			ops = append(ops, *op)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("总共获取到 %d 笔质押相关交易\n", len(ops))
+	return ops, nil
+}
+
+// parseStaking 解析质押相关交易，其他合约类型返回 nil
+func parseStaking(tx TransactionData, unfreezeDelay time.Duration) *StakingOperation {
+	if len(tx.RawData.Contract) == 0 {
+		return nil
+	}
+	contract := tx.RawData.Contract[0]
+	v := contract.Parameter.Value
+
+	op := &StakingOperation{
+		TxID:        tx.TxID,
+		Type:        contract.Type,
+		Owner:       hexToBase58Check(v.OwnerAddress),
+		BlockNumber: tx.BlockNumber,
+		Timestamp:   time.UnixMilli(tx.BlockTimestamp),
+		Status:      "UNKNOWN",
+	}
+	if len(tx.Ret) > 0 {
+		op.Status = tx.Ret[0].ContractRet
+	}
+
+	resource := v.Resource
+	if resource == "" {
+		resource = "BANDWIDTH"
+	}
+	var sun int64
+	switch contract.Type {
+	case StakingFreeze:
+		op.Resource = resource
+		sun = v.FrozenBalance
+	case StakingUnfreeze:
+		op.Resource = resource
+		sun = v.UnfreezeBalance
+		op.WithdrawableAt = op.Timestamp.Add(unfreezeDelay)
+	case StakingDelegate:
+		op.Resource = resource
+		op.Receiver = hexToBase58Check(v.ReceiverAddress)
+		sun = v.Balance
+		op.Lock = v.Lock
+		op.LockPeriod = v.LockPeriod
+	case StakingUndelegate:
+		op.Resource = resource
+		op.Receiver = hexToBase58Check(v.ReceiverAddress)
+		sun = v.Balance
+	case StakingVote:
+		for _, vote := range v.Votes {
+			op.Votes = append(op.Votes, Vote{VoteAddress: hexToBase58Check(vote.VoteAddress), VoteCount: vote.VoteCount})
+		}
+	case StakingWithdrawBalance:
+		sun = tx.WithdrawAmount
+	case StakingWithdrawUnfrozen:
+		sun = tx.WithdrawExpireAmount
+	default:
+		return nil
+	}
+	op.Amount = amount.FromInt64(sun, amount.TRXDecimals)
+	fmt.Printf("[TxID %s] %s: Owner=%s, Receiver=%s, Resource=%s, Amount=%s\n",
+		tx.TxID, contract.Type, op.Owner, op.Receiver, op.Resource, op.Amount)
+	return op
+}
+
+// PrintStakingOperations 打印质押相关记录
+func PrintStakingOperations(ops []StakingOperation) {
+	if len(ops) == 0 {
+		fmt.Println("没有找到任何质押相关交易")
+		return
+	}
+	for i, op := range ops {
+		fmt.Printf("[%d] TxID: %s\n", i+1, op.TxID)
+		fmt.Printf("     Type:     %s\n", op.Type)
+		fmt.Printf("     Owner:    %s\n", op.Owner)
+		if op.Receiver != "" {
+			fmt.Printf("     Receiver: %s\n", op.Receiver)
+		}
+		if op.Resource != "" {
+			fmt.Printf("     Resource: %s\n", op.Resource)
+		}
+		fmt.Printf("     Amount:   %s TRX\n", op.Amount)
+		if op.Lock {
+			fmt.Printf("     Lock:     %d blocks\n", op.LockPeriod)
+		}
+		if !op.WithdrawableAt.IsZero() {
+			fmt.Printf("     Withdrawable: %s\n", op.WithdrawableAt.Format("2006-01-02 15:04:05"))
+		}
+		for _, vote := range op.Votes {
+			fmt.Printf("     Vote:     %s x %d\n", vote.VoteAddress, vote.VoteCount)
+		}
+		fmt.Printf("     Block:    %d\n", op.BlockNumber)
+		fmt.Printf("     Time:     %s\n", op.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Printf("     Status:   %s\n", op.Status)
+		fmt.Printf("----------------------------------\n\n")
+	}
+}
@@ -0,0 +1,111 @@
+package trongrid
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const (
+	alice = "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8"
+	bob   = "TNPdqto8HiuMzoG7Vv9wyyYhWzCojLeHAF"
+	carol = "TLa2f6VPqDgRE67v1736s7bJ8Ray5wYjU7"
+)
+
+// stakingTx builds a TronGrid /v1/accounts/{address}/transactions entry with
+// the given contract type and parameter value
+func stakingTx(t *testing.T, typ, value string, extra string) TransactionData {
+	t.Helper()
+	raw := `{
+		"ret": [{"contractRet": "SUCCESS", "fee": 0}],
+		"txID": "tx1",
+		"blockNumber": 100,
+		"block_timestamp": 1767323045000,
+		"raw_data": {"contract": [{"type": "` + typ + `", "parameter": {"type_url": "type.googleapis.com/protocol.` + typ + `", "value": ` + value + `}}]}` +
+		extra + `
+	}`
+	var tx TransactionData
+	if err := json.Unmarshal([]byte(raw), &tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestParseStaking(t *testing.T) {
+	const delay = 14 * 24 * time.Hour
+	timestamp := time.UnixMilli(1767323045000)
+	tests := []struct {
+		name  string
+		tx    TransactionData
+		want  StakingOperation
+		votes []Vote
+	}{
+		{"freeze", stakingTx(t, StakingFreeze,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "frozen_balance": 100000000, "resource": "ENERGY"}`, ""),
+			StakingOperation{Owner: alice, Resource: "ENERGY"}, nil},
+		// TronGrid 省略 BANDWIDTH
+		{"unfreeze", stakingTx(t, StakingUnfreeze,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "unfreeze_balance": 2500000}`, ""),
+			StakingOperation{Owner: alice, Resource: "BANDWIDTH", WithdrawableAt: timestamp.Add(delay)}, nil},
+		{"delegate", stakingTx(t, StakingDelegate,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "receiver_address": "4188403ac26730e33164eeac403291b48f300b782f", "balance": 50000000, "resource": "ENERGY", "lock": true, "lock_period": 86400}`, ""),
+			StakingOperation{Owner: alice, Receiver: bob, Resource: "ENERGY", Lock: true, LockPeriod: 86400}, nil},
+		{"undelegate", stakingTx(t, StakingUndelegate,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "receiver_address": "4188403ac26730e33164eeac403291b48f300b782f", "balance": 1000000}`, ""),
+			StakingOperation{Owner: alice, Receiver: bob, Resource: "BANDWIDTH"}, nil},
+		{"vote", stakingTx(t, StakingVote,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "votes": [{"vote_address": "4174472e7d35395a6b5add427eecb7f4b62ad2b071", "vote_count": 10}, {"vote_address": "4188403ac26730e33164eeac403291b48f300b782f", "vote_count": 3}]}`, ""),
+			StakingOperation{Owner: alice}, []Vote{{carol, 10}, {bob, 3}}},
+		// 提取金额来自交易的 withdraw_amount / withdraw_expire_amount
+		{"withdraw balance", stakingTx(t, StakingWithdrawBalance,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe"}`, `, "withdraw_amount": 12345678`),
+			StakingOperation{Owner: alice}, nil},
+		{"withdraw unfrozen", stakingTx(t, StakingWithdrawUnfrozen,
+			`{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe"}`, `, "withdraw_expire_amount": 2500000`),
+			StakingOperation{Owner: alice}, nil},
+	}
+	amounts := map[string]string{
+		"freeze":            "100.000000",
+		"unfreeze":          "2.500000",
+		"delegate":          "50.000000",
+		"undelegate":        "1.000000",
+		"vote":              "0.000000",
+		"withdraw balance":  "12.345678",
+		"withdraw unfrozen": "2.500000",
+	}
+	for _, tt := range tests {
+		op := parseStaking(tt.tx, delay)
+		if op == nil {
+			t.Errorf("%s: not parsed", tt.name)
+			continue
+		}
+		w := tt.want
+		if op.TxID != "tx1" || op.Type != tt.tx.RawData.Contract[0].Type || op.BlockNumber != 100 || !op.Timestamp.Equal(timestamp) || op.Status != "SUCCESS" {
+			t.Errorf("%s: operation = %+v", tt.name, *op)
+		}
+		if op.Owner != w.Owner || op.Receiver != w.Receiver || op.Resource != w.Resource || op.Lock != w.Lock || op.LockPeriod != w.LockPeriod || !op.WithdrawableAt.Equal(w.WithdrawableAt) {
+			t.Errorf("%s: operation = %+v, want %+v", tt.name, *op, w)
+		}
+		if got := op.Amount.String(); got != amounts[tt.name] {
+			t.Errorf("%s: amount %s, want %s", tt.name, got, amounts[tt.name])
+		}
+		if len(op.Votes) != len(tt.votes) {
+			t.Errorf("%s: votes %v, want %v", tt.name, op.Votes, tt.votes)
+			continue
+		}
+		for i := range tt.votes {
+			if op.Votes[i] != tt.votes[i] {
+				t.Errorf("%s: vote %d = %v, want %v", tt.name, i, op.Votes[i], tt.votes[i])
+			}
+		}
+	}
+
+	// 其他合约类型和没有合约的交易不是质押
+	transfer := stakingTx(t, "TransferContract", `{"owner_address": "413487b63d30b5b2c87fb7ffa8bcfade38eaac1abe", "amount": 1}`, "")
+	if op := parseStaking(transfer, delay); op != nil {
+		t.Errorf("TransferContract parsed as %+v", *op)
+	}
+	if op := parseStaking(TransactionData{TxID: "empty"}, delay); op != nil {
+		t.Errorf("transaction without contracts parsed as %+v", *op)
+	}
+}
@@ -8,8 +8,12 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/yourname/tron-demo/amount"
+	"github.com/yourname/tron-demo/risk"
 )
 
 // TransactionData 表示交易数据的结构
@@ -27,6 +31,9 @@ type TransactionData struct {
 	EnergyUsageTotal     int           `json:"energy_usage_total"`
 	RawData              RawData       `json:"raw_data,omitempty"`
 	InternalTransactions []interface{} `json:"internal_transactions"`
+	// 提取奖励 / 提取已解质押 TRX 的金额（sun），接口未返回时为 0
+	WithdrawAmount       int64 `json:"withdraw_amount"`
+	WithdrawExpireAmount int64 `json:"withdraw_expire_amount"`
 }
 
 // Ret 表示交易返回结果
@@ -45,6 +52,18 @@ type Value struct {
 	Data            string                 `json:"data"`   // 合约调用 data
 	Other           map[string]interface{} `json:"-"`      // 备用
 	Amount          json.Number            `json:"amount"` // 备用字段
+	// Stake 2.0 / 投票合约参数
+	FrozenBalance   int64  `json:"frozen_balance"`
+	UnfreezeBalance int64  `json:"unfreeze_balance"`
+	Lock            bool   `json:"lock"`
+	LockPeriod      int64  `json:"lock_period"`
+	Votes           []Vote `json:"votes"`
+}
+
+// Vote 表示 VoteWitnessContract 中的一条投票
+type Vote struct {
+	VoteAddress string `json:"vote_address"`
+	VoteCount   int64  `json:"vote_count"`
 }
 
 // Parameter 表示合约参数
@@ -94,10 +113,14 @@ type TRC20Transfer struct {
 	FromAddress     string
 	ToAddress       string
 	ContractAddress string
-	Amount          *big.Int
+	Amount          amount.Amount
 	BlockNumber     int
 	Timestamp       time.Time
 	Status          string
+	// Caller 交易签名者；transferFrom 时与 FromAddress 不同
+	Caller string
+	// Risk 粉尘/地址投毒检测结果，为空表示未命中
+	Risk []risk.Reason
 	// 以下字段用于计算手续费
 	EnergyFee        int
 	EnergyUsageTotal int
@@ -112,6 +135,13 @@ type Config struct {
 	Address  string
 	PageSize int
 	APIKey   string // 可选的API密钥
+	// TokenDecimals TRC20合约地址 -> 精度，未列出的合约按最小单位（0位小数）处理
+	TokenDecimals map[string]int32
+	// Risk 粉尘/地址投毒检测参数；Counterparties 为已知的正常对手方地址
+	Risk           risk.Options
+	Counterparties []string
+	// IncludeFlagged 为 true 时保留被标记的转账（带 Risk 标签），默认从结果中剔除
+	IncludeFlagged bool
 }
 
 // DefaultConfig 返回默认配置
@@ -120,6 +150,10 @@ func DefaultConfig() *Config {
 		BaseURL:  "https://api.trongrid.io/v1/accounts/%s/transactions",
 		Address:  "TUk2k7gSZGs9xquWH6XMGa8BWWvq2m6hbd",
 		PageSize: 50, // 增加页面大小以获取更多数据
+		TokenDecimals: map[string]int32{
+			"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t": 6, // USDT
+		},
+		Risk: risk.DefaultOptions(),
 	}
 }
 
@@ -130,6 +164,27 @@ func GetTRC20Transactions(config *Config) ([]TRC20Transfer, error) {
 	}
 
 	var transfers []TRC20Transfer
+	err := fetchTransactions(config, func(tx TransactionData) {
+		transfer, err := parseTransaction(tx, config.TokenDecimals)
+		if err != nil {
+			fmt.Printf("[TxID %s] 解析交易失败: %v\n", tx.TxID, err)
+			return
+		}
+		if transfer != nil {
+			transfers = append(transfers, *transfer)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("总共获取到 %d 笔TRC20转账交易\n", len(transfers))
+	transfers = ScreenTransfers(transfers, config)
+	return transfers, nil
+}
+
+// fetchTransactions 按 fingerprint 翻页获取账户的全部已确认交易，逐笔交给 handle
+func fetchTransactions(config *Config, handle func(TransactionData)) error {
 	fingerprint := ""
 	pageCount := 0
 
@@ -144,7 +199,7 @@ func GetTRC20Transactions(config *Config) ([]TRC20Transfer, error) {
 
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+			return fmt.Errorf("创建HTTP请求失败: %w", err)
 		}
 		req.Header.Set("accept", "application/json")
 		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; TronDemo/1.0)")
@@ -158,18 +213,18 @@ func GetTRC20Transactions(config *Config) ([]TRC20Transfer, error) {
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("HTTP请求失败: %w", err)
+			return fmt.Errorf("HTTP请求失败: %w", err)
 		}
 		defer resp.Body.Close()
 
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("读取响应体失败: %w", err)
+			return fmt.Errorf("读取响应体失败: %w", err)
 		}
 
 		var result JSONResponse
 		if err := json.Unmarshal(body, &result); err != nil {
-			return nil, fmt.Errorf("解析JSON失败: %w", err)
+			return fmt.Errorf("解析JSON失败: %w", err)
 		}
 
 		// 打印API响应信息
@@ -184,21 +239,10 @@ func GetTRC20Transactions(config *Config) ([]TRC20Transfer, error) {
 		fmt.Printf("第 %d 页获取到 %d 笔交易\n", pageCount, len(result.Data))
 
 		// 处理交易数据
-		pageTransfers := 0
 		for _, tx := range result.Data {
-			transfer, err := parseTransaction(tx)
-			if err != nil {
-				fmt.Printf("[TxID %s] 解析交易失败: %v\n", tx.TxID, err)
-				continue
-			}
-			if transfer != nil {
-				transfers = append(transfers, *transfer)
-				pageTransfers++
-			}
+			handle(tx)
 		}
 
-		fmt.Printf("第 %d 页解析出 %d 笔TRC20转账\n", pageCount, pageTransfers)
-
 		// 检查是否有下一页（fingerprint为空表示没有下一页）
 		if result.Meta.Fingerprint == "" {
 			fmt.Printf("第 %d 页的fingerprint为空，已到达最后一页\n", pageCount)
@@ -212,13 +256,52 @@ func GetTRC20Transactions(config *Config) ([]TRC20Transfer, error) {
 		// 添加延迟避免请求过于频繁
 		time.Sleep(1 * time.Second)
 	}
+	return nil
+}
 
-	fmt.Printf("总共获取到 %d 笔TRC20转账交易\n", len(transfers))
-	return transfers, nil
+// ScreenTransfers 为每笔转账做粉尘/地址投毒检测并写入 Risk。
+// 按时间从旧到新处理，先评估再学习对手方，这样之前付过款的地址才能识别出后来的仿冒地址。
+// 除非 config.IncludeFlagged，被标记的转账会从结果中剔除。
+func ScreenTransfers(transfers []TRC20Transfer, config *Config) []TRC20Transfer {
+	detector := risk.NewDetector(config.Risk, []string{config.Address})
+	for _, a := range config.Counterparties {
+		detector.Remember(a)
+	}
+
+	// TronGrid 返回的是倒序（最新在前）
+	order := make([]int, len(transfers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return transfers[order[a]].Timestamp.Before(transfers[order[b]].Timestamp)
+	})
+	for _, i := range order {
+		t := &transfers[i]
+		// 精度未知的代币（未列在 TokenDecimals 的 TRC20、TRC10）以最小单位计，不做粉尘比较
+		_, known := config.TokenDecimals[t.ContractAddress]
+		rt := risk.Transfer{From: t.FromAddress, To: t.ToAddress, Caller: t.Caller, Value: t.Amount,
+			Unscaled: t.ContractAddress != "TRX" && !known}
+		t.Risk = detector.Assess(rt)
+		detector.Learn(rt, t.Risk)
+	}
+
+	if config.IncludeFlagged {
+		return transfers
+	}
+	kept := transfers[:0]
+	for _, t := range transfers {
+		if len(t.Risk) > 0 {
+			fmt.Printf("[TxID %s] 已剔除可疑转账: %s\n", t.TxID, risk.Join(t.Risk))
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
 }
 
 // parseTransaction 解析单个交易
-func parseTransaction(tx TransactionData) (*TRC20Transfer, error) {
+func parseTransaction(tx TransactionData, decimals map[string]int32) (*TRC20Transfer, error) {
 	if len(tx.RawData.Contract) == 0 {
 		fmt.Printf("[TxID %s] 跳过：无合约数据\n", tx.TxID)
 		return nil, nil
@@ -230,7 +313,7 @@ func parseTransaction(tx TransactionData) (*TRC20Transfer, error) {
 	// 根据不同的合约类型进行解析
 	switch contract.Type {
 	case "TriggerSmartContract":
-		return parseTriggerSmartContract(tx, contract)
+		return parseTriggerSmartContract(tx, contract, decimals)
 	case "TransferContract":
 		//return parseTransferContract(tx, contract)
 		return nil, nil
@@ -244,7 +327,7 @@ func parseTransaction(tx TransactionData) (*TRC20Transfer, error) {
 }
 
 // parseTriggerSmartContract 解析智能合约触发交易
-func parseTriggerSmartContract(tx TransactionData, contract Contract) (*TRC20Transfer, error) {
+func parseTriggerSmartContract(tx TransactionData, contract Contract, decimals map[string]int32) (*TRC20Transfer, error) {
 	v := contract.Parameter.Value
 
 	// 检查是否有合约调用数据
@@ -269,22 +352,31 @@ func parseTriggerSmartContract(tx TransactionData, contract Contract) (*TRC20Tra
 	// 检查前4字节是否为transfer方法签名
 	methodSig := contractData[:8]
 	// a9059cbb，即 transfer(address,uint256) 方法的签名
+	// 23b872dd，即 transferFrom(address,address,uint256)，地址投毒常用 0 金额的 transferFrom 伪造转出记录
 	// 095ea7b3，这是 approve(address,uint256) 的方法签名
 	// approve 是 TRC20（和 ERC20）标准中非常重要的一个方法，它不是用来转账，而是授权第三方账户可以代表你转账指定数量的代币。
-	if methodSig != "a9059cbb" {
-		fmt.Printf("[TxID %s] 跳过：不是TRC20 transfer方法 (期望: a9059cbb, 实际: %s)\n", tx.TxID, methodSig)
+	caller := hexToBase58Check(v.OwnerAddress)
+	var fromAddress, toAddress string
+	var rawAmount *big.Int
+	var err error
+	switch methodSig {
+	case "a9059cbb":
+		fmt.Printf("[TxID %s] 发现TRC20 transfer交易\n", tx.TxID)
+		fromAddress = caller
+		toAddress, rawAmount, err = parseTRC20Data(contractData)
+	case "23b872dd":
+		fmt.Printf("[TxID %s] 发现TRC20 transferFrom交易\n", tx.TxID)
+		fromAddress, toAddress, rawAmount, err = parseTRC20TransferFromData(contractData)
+	default:
+		fmt.Printf("[TxID %s] 跳过：不是TRC20 transfer/transferFrom方法 (实际: %s)\n", tx.TxID, methodSig)
 		return nil, nil
 	}
-
-	fmt.Printf("[TxID %s] 发现TRC20 transfer交易\n", tx.TxID)
-
-	toAddress, amount, err := parseTRC20Data(contractData)
 	if err != nil {
 		return nil, fmt.Errorf("TRC20数据解析失败: %w", err)
 	}
 
-	fromAddress := hexToBase58Check(v.OwnerAddress)
 	contractAddress := hexToBase58Check(v.ContractAddress)
+	value := amount.New(rawAmount, decimals[contractAddress])
 	timestamp := time.UnixMilli(tx.RawData.Timestamp)
 
 	status := "UNKNOWN"
@@ -293,17 +385,18 @@ func parseTriggerSmartContract(tx TransactionData, contract Contract) (*TRC20Tra
 	}
 
 	fmt.Printf("[TxID %s] 解析成功: From=%s, To=%s, Contract=%s, Amount=%s\n",
-		tx.TxID, fromAddress, toAddress, contractAddress, amount.String())
+		tx.TxID, fromAddress, toAddress, contractAddress, value)
 
 	return &TRC20Transfer{
 		TxID:             tx.TxID,
 		FromAddress:      fromAddress,
 		ToAddress:        toAddress,
 		ContractAddress:  contractAddress,
-		Amount:           amount,
+		Amount:           value,
 		BlockNumber:      tx.BlockNumber,
 		Timestamp:        timestamp,
 		Status:           status,
+		Caller:           caller,
 		EnergyFee:        tx.EnergyFee,
 		EnergyUsageTotal: tx.EnergyUsageTotal,
 		NetUsage:         tx.NetUsage,
@@ -321,16 +414,10 @@ func parseTransferContract(tx TransactionData, contract Contract) (*TRC20Transfe
 	fromAddress := hexToBase58Check(v.OwnerAddress)
 	toAddress := hexToBase58Check(v.ReceiverAddress)
 
-	// TRX转账使用Amount字段
-	var amount *big.Int
-	if v.Amount != "" {
-		if amountInt, err := v.Amount.Int64(); err == nil {
-			amount = big.NewInt(amountInt)
-		} else {
-			amount = big.NewInt(0)
-		}
-	} else {
-		amount = big.NewInt(0)
+	// TRX转账使用Amount字段（单位 sun）
+	value, err := parseContractAmount(v.Amount, amount.TRXDecimals)
+	if err != nil {
+		return nil, err
 	}
 
 	blockNumber := tx.BlockNumber
@@ -342,14 +429,14 @@ func parseTransferContract(tx TransactionData, contract Contract) (*TRC20Transfe
 	}
 
 	fmt.Printf("[TxID %s] TRX转账: From=%s, To=%s, Amount=%s\n",
-		tx.TxID, fromAddress, toAddress, amount.String())
+		tx.TxID, fromAddress, toAddress, value)
 
 	return &TRC20Transfer{
 		TxID:             tx.TxID,
 		FromAddress:      fromAddress,
 		ToAddress:        toAddress,
 		ContractAddress:  "TRX", // TRX原生转账
-		Amount:           amount,
+		Amount:           value,
 		BlockNumber:      blockNumber,
 		Timestamp:        timestamp,
 		Status:           status,
@@ -370,16 +457,10 @@ func parseTransferAssetContract(tx TransactionData, contract Contract) (*TRC20Tr
 	fromAddress := hexToBase58Check(v.OwnerAddress)
 	toAddress := hexToBase58Check(v.ReceiverAddress)
 
-	// TRC10代币转账使用Amount字段
-	var amount *big.Int
-	if v.Amount != "" {
-		if amountInt, err := v.Amount.Int64(); err == nil {
-			amount = big.NewInt(amountInt)
-		} else {
-			amount = big.NewInt(0)
-		}
-	} else {
-		amount = big.NewInt(0)
+	// TRC10代币转账使用Amount字段（精度取决于代币，这里按最小单位处理）
+	value, err := parseContractAmount(v.Amount, 0)
+	if err != nil {
+		return nil, err
 	}
 
 	blockNumber := tx.BlockNumber
@@ -391,14 +472,14 @@ func parseTransferAssetContract(tx TransactionData, contract Contract) (*TRC20Tr
 	}
 
 	fmt.Printf("[TxID %s] TRC10转账: From=%s, To=%s, Amount=%s\n",
-		tx.TxID, fromAddress, toAddress, amount.String())
+		tx.TxID, fromAddress, toAddress, value)
 
 	return &TRC20Transfer{
 		TxID:             tx.TxID,
 		FromAddress:      fromAddress,
 		ToAddress:        toAddress,
 		ContractAddress:  "TRC10", // TRC10代币转账
-		Amount:           amount,
+		Amount:           value,
 		BlockNumber:      blockNumber,
 		Timestamp:        timestamp,
 		Status:           status,
@@ -410,6 +491,18 @@ func parseTransferAssetContract(tx TransactionData, contract Contract) (*TRC20Tr
 	}, nil
 }
 
+// parseContractAmount 解析合约参数中的整数金额，缺失时为 0
+func parseContractAmount(n json.Number, decimals int32) (amount.Amount, error) {
+	if n == "" {
+		return amount.FromInt64(0, decimals), nil
+	}
+	raw, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok {
+		return amount.Amount{}, fmt.Errorf("金额格式无效: %s", n)
+	}
+	return amount.New(raw, decimals), nil
+}
+
 // parseTRC20Data 解析TRC20转账数据
 func parseTRC20Data(data string) (string, *big.Int, error) {
 	data = strings.TrimPrefix(data, "0x")
@@ -436,6 +529,26 @@ func parseTRC20Data(data string) (string, *big.Int, error) {
 	return toAddress, amount, nil
 }
 
+// parseTRC20TransferFromData 解析 transferFrom(address,address,uint256) 的调用数据
+func parseTRC20TransferFromData(data string) (string, string, *big.Int, error) {
+	data = strings.TrimPrefix(data, "0x")
+	if len(data) < 8+64*3 { // 方法签名+from+to+amount
+		return "", "", nil, fmt.Errorf("数据长度太短: %d", len(data))
+	}
+
+	raw, err := hex.DecodeString(data[:8+64*3])
+	if err != nil {
+		return "", "", nil, fmt.Errorf("解码十六进制数据失败: %w", err)
+	}
+
+	// 每个参数 32 字节，地址取后 20 字节
+	fromAddress := hexToBase58Check(hex.EncodeToString(raw[16:36]))
+	toAddress := hexToBase58Check(hex.EncodeToString(raw[48:68]))
+	value := new(big.Int).SetBytes(raw[68:100])
+
+	return fromAddress, toAddress, value, nil
+}
+
 // hexToBase58Check 将十六进制地址转换为Base58Check格式
 func hexToBase58Check(hexAddr string) string {
 	hexAddr = strings.TrimPrefix(hexAddr, "0x")
@@ -533,10 +646,13 @@ func PrintTransfers(transfers []TRC20Transfer) {
 		fmt.Printf("     To:       %s\n", transfer.ToAddress)
 		fmt.Printf("     Type:     %s\n", getTransferType(transfer.ContractAddress))
 		fmt.Printf("     Contract: %s\n", transfer.ContractAddress)
-		fmt.Printf("     Amount:   %s\n", transfer.Amount.String())
+		fmt.Printf("     Amount:   %s\n", transfer.Amount)
 		fmt.Printf("     Block:    %d\n", transfer.BlockNumber)
 		fmt.Printf("     Time:     %s\n", transfer.Timestamp.Format("2006-01-02 15:04:05"))
 		fmt.Printf("     Status:   %s\n", transfer.Status)
+		if len(transfer.Risk) > 0 {
+			fmt.Printf("     Risk:     %s\n", risk.Join(transfer.Risk))
+		}
 		fmt.Printf("     EnergyFee:      %d (sun)\n", transfer.EnergyFee)
 		fmt.Printf("     EnergyUsageTotal:      %d\n", transfer.EnergyUsageTotal)
 		fmt.Printf("     NetUsage:      %d \n", transfer.NetUsage)
@@ -558,15 +674,18 @@ func getTransferType(contractAddress string) string {
 	}
 }
 
-func SumInOut(transfers []TRC20Transfer) (int64, int64) {
-	sumIn := int64(0)
-	sumOut := int64(0)
+// SumInOut 汇总某个合约上的转入、转出金额（精度按该合约的金额精度）
+func SumInOut(transfers []TRC20Transfer, contractAddress string) (amount.Amount, amount.Amount) {
+	var sumIn, sumOut amount.Amount
 	for _, transfer := range transfers {
+		if transfer.ContractAddress != contractAddress {
+			continue
+		}
 		if transfer.FromAddress == "TZAw4M78JonPirHnA1r5dfx5L954nay3DQ" {
-			sumIn += transfer.Amount.Int64()
+			sumIn = sumIn.Add(transfer.Amount)
 		}
 		if transfer.ToAddress == "TZAw4M78JonPirHnA1r5dfx5L954nay3DQ" {
-			sumOut += transfer.Amount.Int64()
+			sumOut = sumOut.Add(transfer.Amount)
 		}
 	}
 	return sumIn, sumOut